@@ -0,0 +1,87 @@
+package static
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// alias is a directory served at a path prefix.
+type alias struct {
+	prefix string
+	fsys   fs.FS
+
+	// Real path of the OS directory whose symlinks must not escape it, if
+	// any.
+	root string
+}
+
+// rel returns the path in the alias directory of the named file, and whether
+// the file is in it.
+func (a alias) rel(name string) (string, bool) {
+	switch {
+	case name == a.prefix:
+		return ".", true
+	case strings.HasPrefix(name, a.prefix+"/"):
+		return name[len(a.prefix)+1:], true
+	}
+	return "", false
+}
+
+// newAliases returns the aliases of the directories by URL path prefix,
+// longest prefix first.
+func newAliases(fsys fs.FS, dirs map[string]string, followSymlinks bool) ([]alias, error) {
+	aliases := make([]alias, 0, len(dirs))
+	for prefix, dir := range dirs {
+		a := alias{prefix: fsPath(prefix)}
+		if a.prefix == "." {
+			return nil, errors.New("alias of the root")
+		}
+		var err error
+		if a.fsys, err = rootFS(fsys, dir); err != nil {
+			return nil, fmt.Errorf("alias %s: %v", prefix, err)
+		}
+		if fsys == nil && !followSymlinks && !archiveRoot(nil, dir) {
+			a.root = realPath(dir)
+		}
+		aliases = append(aliases, a)
+	}
+	sort.Slice(aliases, func(i, j int) bool {
+		return len(aliases[i].prefix) > len(aliases[j].prefix)
+	})
+	return aliases, nil
+}
+
+// aliasFS serves the files of the aliases under their prefix, and the other
+// files from the base filesystem.
+type aliasFS struct {
+	base    fs.FS
+	aliases []alias
+}
+
+// resolve returns the filesystem of the named file and its name in it.
+func (a aliasFS) resolve(name string) (fs.FS, string) {
+	for _, al := range a.aliases {
+		if rel, ok := al.rel(name); ok {
+			return al.fsys, rel
+		}
+	}
+	return a.base, name
+}
+
+func (a aliasFS) Open(name string) (fs.File, error) {
+	fsys, name := a.resolve(name)
+	return fsys.Open(name)
+}
+
+func (a aliasFS) Stat(name string) (fs.FileInfo, error) {
+	fsys, name := a.resolve(name)
+	return fs.Stat(fsys, name)
+}
+
+func (a aliasFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fsys, name := a.resolve(name)
+	return fs.ReadDir(fsys, name)
+}
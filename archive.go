@@ -0,0 +1,263 @@
+package static
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type (
+	// zipFS is the file system of a ZIP archive whose stored, uncompressed,
+	// files are seekable.
+	zipFS struct {
+		*zip.Reader
+		ra     io.ReaderAt
+		stored map[string]*zip.File
+	}
+
+	// tarFS is the file system of a tar archive, by file name.
+	tarFS map[string]*tarEntry
+
+	// tarEntry is a file or directory of a tar archive.
+	tarEntry struct {
+		fi      fs.FileInfo
+		data    *io.SectionReader // Content of files.
+		entries []fs.DirEntry     // Entries of directories, sorted by name.
+	}
+
+	// sectionFile is an open file whose content is a section of an archive.
+	sectionFile struct {
+		*io.SectionReader
+		fi fs.FileInfo
+	}
+
+	// tarDir is an open directory of a tar archive.
+	tarDir struct {
+		name   string
+		e      *tarEntry
+		offset int
+	}
+
+	// dirEntry is the directory entry of a FileInfo.
+	dirEntry struct {
+		fs.FileInfo
+	}
+)
+
+// isArchive reports whether the file name has the extension of a supported
+// archive: ".zip", ".tar", ".tar.gz" or ".tgz".
+func isArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveRoot reports whether root is an archive file, in fsys or the OS
+// filesystem if fsys is nil.
+func archiveRoot(fsys fs.FS, root string) bool {
+	if !isArchive(root) {
+		return false
+	}
+	var fi fs.FileInfo
+	var err error
+	if fsys == nil {
+		fi, err = os.Stat(root)
+	} else {
+		fi, err = fs.Stat(fsys, fsPath(filepath.ToSlash(root)))
+	}
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// openArchive returns the file system of the archive file root, in fsys or
+// the OS filesystem if fsys is nil. Archives of the OS filesystem are read in
+// place, others and gzipped tar archives are loaded into memory.
+func openArchive(fsys fs.FS, root string) (fs.FS, error) {
+	var ra io.ReaderAt
+	var size int64
+	if fsys == nil {
+		f, err := os.Open(root)
+		if err != nil {
+			return nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		ra, size = f, fi.Size()
+	} else {
+		b, err := fs.ReadFile(fsys, fsPath(filepath.ToSlash(root)))
+		if err != nil {
+			return nil, err
+		}
+		ra, size = bytes.NewReader(b), int64(len(b))
+	}
+
+	name := strings.ToLower(root)
+	if strings.HasSuffix(name, ".zip") {
+		return newZipFS(ra, size)
+	}
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		zr, err := gzip.NewReader(io.NewSectionReader(ra, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, err
+		}
+		ra, size = bytes.NewReader(b), int64(len(b))
+	}
+	return newTarFS(ra, size)
+}
+
+func newZipFS(ra io.ReaderAt, size int64) (*zipFS, error) {
+	r, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	z := &zipFS{Reader: r, ra: ra, stored: map[string]*zip.File{}}
+	for _, f := range r.File {
+		if f.Method == zip.Store && f.Mode().IsRegular() {
+			z.stored[path.Clean(f.Name)] = f
+		}
+	}
+	return z, nil
+}
+
+// Open opens the named file, seekable if it is stored.
+func (z *zipFS) Open(name string) (fs.File, error) {
+	f, ok := z.stored[name]
+	if !ok || !fs.ValidPath(name) {
+		return z.Reader.Open(name)
+	}
+	off, err := f.DataOffset()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &sectionFile{io.NewSectionReader(z.ra, off, int64(f.UncompressedSize64)), f.FileInfo()}, nil
+}
+
+func newTarFS(ra io.ReaderAt, size int64) (tarFS, error) {
+	t := tarFS{".": {fi: dirInfo(".")}}
+	cr := &countingReader{r: io.NewSectionReader(ra, 0, size)}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+		if name == "" || !fs.ValidPath(name) {
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			t.add(name, &tarEntry{fi: hdr.FileInfo(), data: io.NewSectionReader(ra, cr.n, hdr.Size)})
+		case tar.TypeDir:
+			t.add(name, &tarEntry{fi: hdr.FileInfo()})
+		}
+	}
+	for name, e := range t {
+		if name != "." {
+			parent := t[path.Dir(name)]
+			parent.entries = append(parent.entries, dirEntry{e.fi})
+		}
+	}
+	for _, e := range t {
+		sort.Slice(e.entries, func(i, j int) bool { return e.entries[i].Name() < e.entries[j].Name() })
+	}
+	return t, nil
+}
+
+// add adds the entry of the named file and its missing parent directories.
+func (t tarFS) add(name string, e *tarEntry) {
+	if old, ok := t[name]; ok && old.fi.IsDir() && e.fi.IsDir() {
+		old.fi = e.fi
+		return
+	}
+	t[name] = e
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := t[dir]; ok {
+			break
+		}
+		t[dir] = &tarEntry{fi: dirInfo(dir)}
+	}
+}
+
+// dirInfo returns the FileInfo of an implicit directory of an archive.
+func dirInfo(name string) fs.FileInfo {
+	return (&tar.Header{Name: name + "/", Typeflag: tar.TypeDir, Mode: 0755}).FileInfo()
+}
+
+func (t tarFS) Open(name string) (fs.File, error) {
+	e, ok := t[name]
+	if !ok || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if e.fi.IsDir() {
+		return &tarDir{name: name, e: e}, nil
+	}
+	return &sectionFile{io.NewSectionReader(e.data, 0, e.data.Size()), e.fi}, nil
+}
+
+func (f *sectionFile) Stat() (fs.FileInfo, error) { return f.fi, nil }
+func (f *sectionFile) Close() error               { return nil }
+
+func (d *tarDir) Stat() (fs.FileInfo, error) { return d.e.fi, nil }
+func (d *tarDir) Close() error               { return nil }
+
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.e.entries[d.offset:]
+	if n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if n < len(entries) {
+			entries = entries[:n]
+		}
+	}
+	d.offset += len(entries)
+	return append([]fs.DirEntry(nil), entries...), nil
+}
+
+func (e dirEntry) Type() fs.FileMode          { return e.Mode().Type() }
+func (e dirEntry) Info() (fs.FileInfo, error) { return e.FileInfo, nil }
+
+// countingReader counts the bytes read and skipped from a seekable reader.
+type countingReader struct {
+	r *io.SectionReader
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countingReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.r.Seek(offset, whence)
+	r.n = n
+	return n, err
+}
@@ -0,0 +1,116 @@
+package static
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+var archiveFiles = []struct {
+	name, data string
+}{
+	{"index.html", "<h1>Archive</h1>"},
+	{"css/site.css", "body { color: red }"},
+	{"js/vendor/lib.js", "var lib = {}"},
+}
+
+func writeZip(t *testing.T, p string) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for i, f := range archiveFiles {
+		method := zip.Deflate
+		if i%2 == 0 {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: method, Modified: time.Unix(1e9, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.data))
+	}
+	zw.Close()
+	if err := os.WriteFile(p, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTar(t *testing.T, p string, gz bool) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "css/", Typeflag: tar.TypeDir, Mode: 0755})
+	for _, f := range archiveFiles {
+		tw.WriteHeader(&tar.Header{Name: "./" + f.name, Mode: 0644, Size: int64(len(f.data)), ModTime: time.Unix(1e9, 0)})
+		tw.Write([]byte(f.data))
+	}
+	tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"})
+	tw.Close()
+	b := buf.Bytes()
+	if gz {
+		zbuf := new(bytes.Buffer)
+		zw := gzip.NewWriter(zbuf)
+		zw.Write(b)
+		zw.Close()
+		b = zbuf.Bytes()
+	}
+	if err := os.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "assets.zip"))
+	writeTar(t, filepath.Join(dir, "assets.tar"), false)
+	writeTar(t, filepath.Join(dir, "assets.tgz"), true)
+
+	assert := assert.New(t)
+	for _, name := range []string{"assets.zip", "assets.tar", "assets.tgz"} {
+		fsys, err := openArchive(nil, filepath.Join(dir, name))
+		if !assert.NoError(err, name) {
+			continue
+		}
+		assert.NoError(fstest.TestFS(fsys, "index.html", "css/site.css", "js/vendor/lib.js"), name)
+
+		for _, mw := range []route.MiddlewareFunc{
+			New(Root(filepath.Join(dir, name)), Browse(true)),
+			New(Filesystem(os.DirFS(dir)), Root(name), Browse(true)),
+		} {
+			mux := route.NewServeMux()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), name) {
+				assert.Equal("<h1>Archive</h1>", rec.Body.String(), name)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/css/site.css", nil)
+			req.Header.Set(headerRange, "bytes=0-3")
+			rec = httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), name) {
+				assert.Equal(http.StatusPartialContent, rec.Code, name)
+				assert.Equal("body", rec.Body.String(), name)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/js/", nil)
+			req.Header.Set(route.HeaderAccept, route.MIMEApplicationJSON)
+			rec = httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), name) {
+				assert.Contains(rec.Body.String(), `"name":"vendor"`, name)
+			}
+
+			req = httptest.NewRequest(http.MethodGet, "/link", nil)
+			rec = httptest.NewRecorder()
+			assert.Error(mw(mux.NewContext(req, rec), route.NotFoundHandler), name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package static
+
+import (
+	"io/fs"
+	"os"
+)
+
+type (
+	// BackendFS is the interface of the storage backends from where the
+	// static content is served, e.g. s3backend or httpbackend. Names are
+	// slash-separated and unrooted, as in io/fs, and errors of missing files
+	// wrap fs.ErrNotExist.
+	//
+	// The FileInfo of files may provide their entity tag with an
+	// `ETag() string` method. Files opened for reading must implement
+	// io.Seeker, and directories fs.ReadDirFile to be listed in batches.
+	BackendFS interface {
+		// Open opens the named file or directory for reading.
+		Open(name string) (fs.File, error)
+
+		// Stat returns the FileInfo of the named file or directory, without
+		// reading its content.
+		Stat(name string) (fs.FileInfo, error)
+
+		// ReadDir returns the entries of the named directory.
+		ReadDir(name string) ([]fs.DirEntry, error)
+	}
+
+	// backendFS is the backend of a filesystem implementing only some of
+	// the methods of BackendFS.
+	backendFS struct {
+		fs.FS
+	}
+)
+
+// DirBackend returns the backend of the OS directory, which serves Root
+// directories by default.
+func DirBackend(dir string) BackendFS {
+	return asBackend(os.DirFS(dir))
+}
+
+// asBackend returns fsys as a backend, implementing Stat and ReadDir with
+// fs.Stat and fs.ReadDir if it doesn't.
+func asBackend(fsys fs.FS) BackendFS {
+	if b, ok := fsys.(BackendFS); ok {
+		return b
+	}
+	return backendFS{fsys}
+}
+
+func (b backendFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(b.FS, name)
+}
+
+func (b backendFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(b.FS, name)
+}
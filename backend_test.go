@@ -0,0 +1,69 @@
+package static
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// listBackend is a backend whose directories can only be listed with
+// ReadDir.
+type listBackend struct {
+	fstest.MapFS
+	stats int
+}
+
+// dirFile is an open directory without a ReadDir method.
+type dirFile struct {
+	fs.File
+}
+
+func (b *listBackend) Open(name string) (fs.File, error) {
+	f, err := b.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		return dirFile{f}, nil
+	}
+	return f, nil
+}
+
+func (b *listBackend) Stat(name string) (fs.FileInfo, error) {
+	b.stats++
+	return b.MapFS.Stat(name)
+}
+
+func TestBackendFS(t *testing.T) {
+	backend := &listBackend{MapFS: fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"dir/b.txt": {Data: []byte("b")},
+	}}
+	var _ BackendFS = backend
+	mw := New(Backend(backend), Browse(true))
+
+	assert := assert.New(t)
+	assert.Equal("a", serve(t, mw, http.MethodGet, "/a.txt").Body.String())
+	assert.NotZero(backend.stats)
+	var entries []DirEntry
+	rec := serve(t, mw, http.MethodGet, "/dir/", route.HeaderAccept, route.MIMEApplicationJSON)
+	if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &entries)) && assert.Len(entries, 1) {
+		assert.Equal("b.txt", entries[0].Name)
+	}
+
+	// Filesystems without Stat and ReadDir are adapted.
+	fsys := asBackend(struct{ fs.FS }{fstest.MapFS{"dir/c.txt": {}}})
+	fi, err := fsys.Stat("dir/c.txt")
+	if assert.NoError(err) {
+		assert.Equal("c.txt", fi.Name())
+	}
+	list, err := fsys.ReadDir("dir")
+	if assert.NoError(err) {
+		assert.Len(list, 1)
+	}
+}
@@ -0,0 +1,863 @@
+package static
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goroute/route"
+)
+
+const html = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <meta http-equiv="X-UA-Compatible" content="ie=edge">
+  <title>{{ .Name }}</title>
+  <style>
+    body {
+			font-family: Menlo, Consolas, monospace;
+			padding: 48px;
+		}
+		header {
+			padding: 4px 16px;
+			font-size: 24px;
+		}
+		header a {
+			color: inherit;
+			text-decoration: none;
+		}
+    ul {
+			list-style-type: none;
+			margin: 0;
+    	padding: 20px 0 0 0;
+			display: flex;
+			flex-wrap: wrap;
+    }
+    li {
+			width: 300px;
+			padding: 16px;
+		}
+		li a {
+			display: block;
+			overflow: hidden;
+			white-space: nowrap;
+			text-overflow: ellipsis;
+			text-decoration: none;
+			transition: opacity 0.25s;
+		}
+		li span {
+			color: #707070;
+			font-size: 12px;
+		}
+		li span + span::before {
+			content: "· ";
+		}
+		.icon {
+			vertical-align: -2px;
+		}
+		.thumbnail {
+			display: block;
+			width: 64px;
+			height: 64px;
+			margin-bottom: 4px;
+			object-fit: cover;
+		}
+		li a:hover {
+			opacity: 0.50;
+		}
+		.dir {
+			color: #E91E63;
+		}
+		.file {
+			color: #673AB7;
+		}
+		nav {
+			padding: 4px 16px;
+			font-size: 12px;
+			color: #707070;
+		}
+		nav a {
+			margin-left: 8px;
+			color: #707070;
+		}
+		nav .download {
+			float: right;
+		}
+		.pages {
+			padding-top: 16px;
+		}
+		nav form {
+			display: inline;
+			margin-left: 16px;
+		}
+		.upload {
+			padding: 16px 16px 0 16px;
+			font-size: 12px;
+		}
+		.readme {
+			margin: 32px 16px 0 16px;
+			padding-top: 16px;
+			border-top: 1px solid #e0e0e0;
+			font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+		}
+  </style>
+</head>
+<body>
+	<header>
+		{{ range $i, $crumb := .Breadcrumbs }}{{ if gt $i 1 }}/{{ end }}<a href="{{ $crumb.URL }}">{{ $crumb.Name }}</a>{{ end }}
+	</header>
+	<nav>
+		Sort by
+		<a href="{{ .SortURL "name" }}">Name{{ .SortArrow "name" }}</a>
+		<a href="{{ .SortURL "size" }}">Size{{ .SortArrow "size" }}</a>
+		<a href="{{ .SortURL "type" }}">Type{{ .SortArrow "type" }}</a>
+		<a href="{{ .SortURL "mtime" }}">Modified{{ .SortArrow "mtime" }}</a>
+		<a class="download" href="?archive=zip">Download all</a>
+		<form>
+			<input id="filter" type="search" name="q" value="{{ .Query }}" placeholder="Filter" autocomplete="off">
+		</form>
+	</nav>
+	{{ if .Truncated }}
+	<nav class="truncated">Showing the first {{ .Total }} of {{ .Count }} entries</nav>
+	{{ end }}
+	{{ if .Upload }}
+	<form class="upload" method="post" enctype="multipart/form-data">
+		<input type="file" name="file" multiple required>
+		<button type="submit">Upload</button>
+	</form>
+	{{ end }}
+	<ul>
+		{{ if ne .Name "/" }}
+		<li>
+			<a class="dir" href="../">../</a>
+		</li>
+		{{ end }}
+		{{ range .Files }}
+		<li data-name="{{ .Name }}">
+		{{ if .Dir }}
+			{{ $name := print .Name "/" }}
+			<a class="dir" href="{{ $name }}">{{ .Icon }} {{ $name }}</a>
+			{{ else }}
+			{{ if $.Thumbnails }}{{ with .Thumbnail }}<img class="thumbnail" src="{{ . }}" alt="" loading="lazy">{{ end }}{{ end }}
+			<a class="file {{ .Kind }}" href="{{ .Name }}">{{ .Icon }} {{ .Name }}</a>
+			<span>{{ .Type }}</span>
+			<span>{{ .HumanSize }}</span>
+		{{ end }}
+		{{ if not .ModTime.IsZero }}
+			<span>{{ .ModTime.Format $.TimeFormat }}</span>
+		{{ end }}
+		</li>
+		{{ end }}
+  </ul>
+	<script>
+		document.getElementById("filter").addEventListener("input", function (e) {
+			var q = e.target.value.toLowerCase();
+			document.querySelectorAll("li[data-name]").forEach(function (li) {
+				li.hidden = li.dataset.name.toLowerCase().indexOf(q) < 0;
+			});
+		});
+	</script>
+	{{ if gt .Pages 1 }}
+	<nav class="pages">
+		{{ with .PrevURL }}<a href="{{ . }}">← Previous</a>{{ end }}
+		Page {{ .Page }} of {{ .Pages }}
+		{{ with .NextURL }}<a href="{{ . }}">Next →</a>{{ end }}
+	</nav>
+	{{ end }}
+	{{ if .Readme }}
+	<article class="readme">{{ .Readme }}</article>
+	{{ end }}
+</body>
+</html>
+`
+
+type (
+	// DirListing is the data of the directory listing template.
+	DirListing struct {
+		// URL path of the directory.
+		Name string
+
+		// Entries of the directory on the page, directories first.
+		Files []DirEntry
+
+		// Sort key of the entries: "name", "size", "type" or "mtime".
+		Sort string
+
+		// Sort order of the entries: "asc" or "desc".
+		Order string
+
+		// Layout of modification times, see time.Format.
+		TimeFormat string
+
+		// Filter of the entries, which contain it in their name ignoring case.
+		Query string
+
+		// Page of the entries, from 1.
+		Page int
+
+		// Number of entries per page, 0 if the listing isn't paginated.
+		PerPage int
+
+		// Number of entries of all the pages.
+		Total int
+
+		// Rendered README file of the directory, if any.
+		Readme template.HTML
+
+		// Whether files can be uploaded to the directory.
+		Upload bool
+
+		// Whether images have thumbnails.
+		Thumbnails bool
+
+		// Whether entries were left out beyond BrowseMaxEntries, of Count
+		// entries matching the filter.
+		Truncated bool
+		Count     int
+	}
+
+	// truncatedListing is a JSON listing truncated to BrowseMaxEntries.
+	truncatedListing struct {
+		Entries   []DirEntry `json:"entries"`
+		Truncated bool       `json:"truncated"`
+		Count     int        `json:"count"`
+	}
+
+	// Breadcrumb links to the directory or one of its parents.
+	Breadcrumb struct {
+		// Name of the directory, "/" for the root.
+		Name string
+
+		// URL of the directory relative to the listing.
+		URL string
+	}
+
+	// DirEntry is an entry of a directory listing. It is also the element of
+	// JSON listings.
+	DirEntry struct {
+		Name    string    `json:"name"`
+		Size    int64     `json:"size"`
+		ModTime time.Time `json:"mtime"`
+		Dir     bool      `json:"isDir"`
+	}
+)
+
+// Breadcrumbs returns the links to the root, each parent and the directory
+// itself.
+func (l DirListing) Breadcrumbs() []Breadcrumb {
+	var names []string
+	if l.Name != "/" {
+		names = strings.Split(strings.Trim(l.Name, "/"), "/")
+	}
+	crumbs := []Breadcrumb{{Name: "/", URL: "./" + strings.Repeat("../", len(names))}}
+	for i, name := range names {
+		crumbs = append(crumbs, Breadcrumb{
+			Name: name,
+			URL:  "./" + strings.Repeat("../", len(names)-i-1),
+		})
+	}
+	return crumbs
+}
+
+// SortURL returns the query string sorting the listing by key, toggling the
+// order if it is already sorted by key and keeping the filter.
+func (l DirListing) SortURL(key string) string {
+	order := "asc"
+	if l.Sort == key && l.Order == "asc" {
+		order = "desc"
+	}
+	return l.url(key, order, 0)
+}
+
+// Pages returns the number of pages of the listing.
+func (l DirListing) Pages() int {
+	if l.PerPage <= 0 || l.Total == 0 {
+		return 1
+	}
+	return (l.Total + l.PerPage - 1) / l.PerPage
+}
+
+// PrevURL returns the query string of the previous page, or "" if the
+// listing is on the first page.
+func (l DirListing) PrevURL() string {
+	if l.Page <= 1 {
+		return ""
+	}
+	return l.url(l.Sort, l.Order, l.Page-1)
+}
+
+// NextURL returns the query string of the next page, or "" if the listing is
+// on the last page.
+func (l DirListing) NextURL() string {
+	if l.Page >= l.Pages() {
+		return ""
+	}
+	return l.url(l.Sort, l.Order, l.Page+1)
+}
+
+// url returns the query string of the listing sorted by key in order, on the
+// page if not 0, keeping the filter and page size.
+func (l DirListing) url(key, order string, page int) string {
+	query := url.Values{"sort": {key}, "order": {order}}
+	if l.Query != "" {
+		query.Set("q", l.Query)
+	}
+	if l.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(l.PerPage))
+	}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	return "?" + query.Encode()
+}
+
+// SortArrow returns an arrow showing the order if the listing is sorted by
+// key.
+func (l DirListing) SortArrow(key string) string {
+	switch {
+	case l.Sort != key:
+		return ""
+	case l.Order == "desc":
+		return " ↓"
+	}
+	return " ↑"
+}
+
+// HumanSize returns the size of the entry in a human readable format, e.g.
+// "1.50KB".
+func (e DirEntry) HumanSize() string {
+	return formatFileSize(e.Size)
+}
+
+// Thumbnail returns the URL of the thumbnail of the entry relative to the
+// listing, or "" if it isn't an image which can be resized.
+func (e DirEntry) Thumbnail() string {
+	if e.Dir || !resizable(e.Name) {
+		return ""
+	}
+	u := url.URL{Path: e.Name, RawQuery: fmt.Sprintf("w=%d&h=%[1]d&fit=cover", thumbnailSize)}
+	return u.String()
+}
+
+func (s *server) listDir(c route.Context, name string) (err error) {
+	if s.isPreflight(c.Request()) {
+		return s.preflight(c)
+	}
+	data := DirListing{
+		Name:       path.Join("/", name),
+		TimeFormat: s.BrowseTimeFormat,
+		Query:      c.QueryParam("q"),
+		Page:       1,
+		PerPage:    s.BrowsePerPage,
+		Upload:     s.Upload,
+		Thumbnails: s.ImageResize,
+	}
+	if page, err := strconv.Atoi(c.QueryParam("page")); err == nil && page > 1 {
+		data.Page = page
+	}
+	if perPage, err := strconv.Atoi(c.QueryParam("per_page")); err == nil && perPage > 0 {
+		data.PerPage = perPage
+	}
+	// Only the entries up to the page are kept, in sort order. Their FileInfo
+	// is only read when sorting by it, or once they are kept.
+	page := &pageEntries{less: data.less(c.QueryParam("sort"), c.QueryParam("order"))}
+	page.max = data.lastEntry(s.BrowseMaxEntries)
+	info := data.Sort == "size" || data.Sort == "mtime"
+	var readmes []DirEntry
+	q := strings.ToLower(data.Query)
+	err = s.readDir(c.Request().Context(), name, func(e fs.DirEntry) error {
+		if q != "" && !strings.Contains(strings.ToLower(e.Name()), q) {
+			return nil
+		}
+		p := path.Join(name, e.Name())
+		if !s.visible(p, e.IsDir()) || e.Type()&fs.ModeSymlink != 0 && s.escapes(p) {
+			return nil
+		}
+		data.Count++
+		entry := pageEntry{DirEntry{Name: e.Name(), Dir: e.IsDir()}, e}
+		if s.BrowseReadme && isReadme(entry.DirEntry) {
+			readmes = append(readmes, entry.DirEntry)
+		}
+		if info {
+			de, err := s.dirEntry(e)
+			if err != nil {
+				return err
+			}
+			entry = pageEntry{de, nil}
+		}
+		page.add(entry)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	data.Total = data.Count
+	if s.BrowseMaxEntries > 0 && data.Count > s.BrowseMaxEntries {
+		data.Total, data.Truncated = s.BrowseMaxEntries, true
+	}
+	if data.Files, err = s.sortedEntries(page); err != nil {
+		return
+	}
+	if s.BrowseReadme {
+		if data.Readme, err = s.readme(name, readmes); err != nil {
+			return
+		}
+	}
+	data.paginate()
+
+	header := s.setListingHeaders(c)
+	header.Set(headerTotalCount, strconv.Itoa(data.Total))
+	// HEAD requests render the listing too, for its Content-Length.
+	w := s.newListingWriter(c)
+	if wantsJSON(c) {
+		header.Set(route.HeaderContentType, route.MIMEApplicationJSONCharsetUTF8)
+		if s.BrowseMaxEntries > 0 {
+			err = json.NewEncoder(w).Encode(truncatedListing{data.Files, data.Truncated, data.Count})
+		} else {
+			err = json.NewEncoder(w).Encode(data.Files)
+		}
+	} else {
+		header.Set(route.HeaderContentType, route.MIMETextHTMLCharsetUTF8)
+		err = s.tmpl.Execute(w, data)
+	}
+	return w.finish(err)
+}
+
+// listingWriter buffers a listing up to BrowseBufferSize bytes to send it
+// with a Content-Length header, and streams larger listings. Listings are
+// compressed like files.
+type listingWriter struct {
+	s         *server
+	c         route.Context
+	buf       bytes.Buffer
+	streaming bool
+
+	// Called once a compressed stream is written, if any.
+	done func()
+}
+
+func (s *server) newListingWriter(c route.Context) *listingWriter {
+	return &listingWriter{s: s, c: c}
+}
+
+func (w *listingWriter) Write(p []byte) (int, error) {
+	if !w.streaming {
+		size := int64(w.buf.Len() + len(p))
+		if w.s.BrowseBufferSize <= 0 || size <= w.s.BrowseBufferSize {
+			return w.buf.Write(p)
+		}
+		// Too large, sent chunked without ranges.
+		w.streaming = true
+		res := w.c.Response()
+		// Streamed listings aren't too small to be compressed.
+		if w.s.compressible(res.Header().Get(route.HeaderContentType), w.s.CompressMinSize) {
+			w.done = compress(w.c)
+		}
+		res.WriteHeader(http.StatusOK)
+		if _, err := res.Write(w.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		w.buf = bytes.Buffer{}
+	}
+	return w.c.Response().Write(p)
+}
+
+// finish sends the buffered listing, or ends its stream, unless rendering it
+// failed with err.
+func (w *listingWriter) finish(err error) error {
+	if w.streaming {
+		if w.done != nil {
+			w.done()
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	content := w.buf.Bytes()
+	header := w.c.Response().Header()
+	if w.s.compressible(header.Get(route.HeaderContentType), int64(len(content))) {
+		header.Add(route.HeaderVary, route.HeaderAcceptEncoding)
+		if b, encoding := compressBytes(w.c.Request().Header.Get(route.HeaderAcceptEncoding), content); encoding != "" {
+			header.Set(route.HeaderContentEncoding, encoding)
+			content = b
+		}
+	}
+	w.s.serveContent(w.c, w.c.Request(), "", time.Time{}, bytes.NewReader(content))
+	return nil
+}
+
+// browseBatchSize is the number of entries read at once from directories.
+const browseBatchSize = 1000
+
+// headerTotalCount is the header of the number of entries of paginated
+// listings.
+const headerTotalCount = "X-Total-Count"
+
+// readDir calls fn with each entry of the named directory, in no particular
+// order, reading them in batches.
+func (s *server) readDir(ctx context.Context, name string, fn func(fs.DirEntry) error) error {
+	fsys := s.backend(ctx)
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dir, ok := f.(fs.ReadDirFile)
+	if !ok {
+		entries, err := fsys.ReadDir(name)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for {
+		entries, err := dir.ReadDir(browseBatchSize)
+		for _, e := range entries {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == nil && len(entries) == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// paginate keeps the entries of the page among the first Total ones,
+// moving it to the last page if it is out of range.
+func (l *DirListing) paginate() {
+	if l.PerPage <= 0 {
+		l.Page = 1
+		return
+	}
+	if pages := l.Pages(); l.Page > pages {
+		l.Page = pages
+	}
+	start := (l.Page - 1) * l.PerPage
+	end := start + l.PerPage
+	if end > l.Total {
+		end = l.Total
+	}
+	l.Files = l.Files[start:end]
+}
+
+// lastEntry returns the number of entries up to the end of the page, at most
+// maxEntries unless it is 0, or 0 if every entry is listed.
+func (l *DirListing) lastEntry(maxEntries int) int {
+	n := 0
+	if l.PerPage > 0 {
+		n = math.MaxInt32
+		if l.Page < n/l.PerPage {
+			n = l.Page * l.PerPage
+		}
+	}
+	if maxEntries > 0 && (n == 0 || n > maxEntries) {
+		n = maxEntries
+	}
+	return n
+}
+
+// pageEntry is an entry of a listing, with the directory entry whose
+// FileInfo isn't read yet, if any.
+type pageEntry struct {
+	DirEntry
+	entry fs.DirEntry
+}
+
+// pageEntries keeps the first max entries of a listing in sort order, or
+// all of them if max is 0. It is a heap of which the last of them is the
+// root.
+type pageEntries struct {
+	less    func(a, b DirEntry) bool
+	max     int
+	entries []pageEntry
+}
+
+func (p *pageEntries) Len() int { return len(p.entries) }
+func (p *pageEntries) Less(i, j int) bool {
+	return p.less(p.entries[j].DirEntry, p.entries[i].DirEntry)
+}
+func (p *pageEntries) Swap(i, j int)      { p.entries[i], p.entries[j] = p.entries[j], p.entries[i] }
+func (p *pageEntries) Push(x interface{}) { p.entries = append(p.entries, x.(pageEntry)) }
+
+func (p *pageEntries) Pop() interface{} {
+	e := p.entries[len(p.entries)-1]
+	p.entries = p.entries[:len(p.entries)-1]
+	return e
+}
+
+// add keeps the entry if it is among the first ones.
+func (p *pageEntries) add(e pageEntry) {
+	switch {
+	case p.max <= 0:
+		p.entries = append(p.entries, e)
+	case len(p.entries) < p.max:
+		heap.Push(p, e)
+	case p.less(e.DirEntry, p.entries[0].DirEntry):
+		p.entries[0] = e
+		heap.Fix(p, 0)
+	}
+}
+
+// sortedEntries returns the kept entries in sort order, reading the FileInfo
+// of those which need it.
+func (s *server) sortedEntries(p *pageEntries) ([]DirEntry, error) {
+	sort.Slice(p.entries, func(i, j int) bool {
+		return p.less(p.entries[i].DirEntry, p.entries[j].DirEntry)
+	})
+	files := make([]DirEntry, len(p.entries))
+	for i, e := range p.entries {
+		files[i] = e.DirEntry
+		if e.entry != nil {
+			de, err := s.dirEntry(e.entry)
+			if err != nil {
+				return nil, err
+			}
+			files[i] = de
+		}
+	}
+	return files, nil
+}
+
+// dirEntry returns the listing entry of the directory entry, reading its
+// FileInfo.
+func (s *server) dirEntry(e fs.DirEntry) (DirEntry, error) {
+	f, err := e.Info()
+	if err != nil {
+		return DirEntry{}, err
+	}
+	modTime := f.ModTime()
+	if s.BrowseTimeLocation != nil && !modTime.IsZero() {
+		modTime = modTime.In(s.BrowseTimeLocation)
+	}
+	return DirEntry{f.Name(), f.Size(), modTime, f.IsDir()}, nil
+}
+
+// readmeNames are the names of README files in order of preference.
+var readmeNames = []string{"README.md", "README.markdown", "README.txt", "README"}
+
+// isReadme reports whether the entry is a README file.
+func isReadme(e DirEntry) bool {
+	if e.Dir {
+		return false
+	}
+	for _, readme := range readmeNames {
+		if strings.EqualFold(e.Name, readme) {
+			return true
+		}
+	}
+	return false
+}
+
+// readme returns the first README file among the entries of the named
+// directory rendered to HTML, or "" if there is none.
+func (s *server) readme(name string, entries []DirEntry) (template.HTML, error) {
+	for _, readme := range readmeNames {
+		for _, e := range entries {
+			if e.Dir || !strings.EqualFold(e.Name, readme) {
+				continue
+			}
+			source, err := fs.ReadFile(s.fsys, path.Join(name, e.Name))
+			if err != nil {
+				return "", err
+			}
+			if !isMarkdown(e.Name) {
+				return template.HTML("<pre>" + template.HTMLEscapeString(string(source)) + "</pre>"), nil
+			}
+			buf := new(bytes.Buffer)
+			if err = s.MarkdownRenderer.Render(buf, source); err != nil {
+				return "", err
+			}
+			return template.HTML(buf.String()), nil
+		}
+	}
+	return "", nil
+}
+
+// Browse tokens.
+const (
+	browseTokenParam  = "browse_token"
+	headerBrowseToken = "X-Browse-Token"
+)
+
+// browseAllowed reports whether directories can be listed for the request,
+// checking BrowseToken and BrowseAuth.
+func (s *server) browseAllowed(c route.Context) (bool, error) {
+	if s.BrowseToken != "" && !s.hasBrowseToken(c) {
+		return false, nil
+	}
+	if s.BrowseAuth != nil {
+		return s.BrowseAuth(c)
+	}
+	return true, nil
+}
+
+// hasBrowseToken reports whether the request has the browse token. A token
+// from the query parameter is kept in a cookie, so that links of listings
+// keep working.
+func (s *server) hasBrowseToken(c route.Context) bool {
+	r := c.Request()
+	if validToken(r.Header.Get(headerBrowseToken), s.BrowseToken) {
+		return true
+	}
+	if cookie, err := r.Cookie(browseTokenParam); err == nil && validToken(cookie.Value, s.BrowseToken) {
+		return true
+	}
+	if !validToken(c.QueryParam(browseTokenParam), s.BrowseToken) {
+		return false
+	}
+	http.SetCookie(c.Response(), &http.Cookie{
+		Name:     browseTokenParam,
+		Value:    s.BrowseToken,
+		Path:     "/",
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return true
+}
+
+// validToken reports whether token is the secret, in constant time.
+func validToken(token, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// setListingHeaders sets the headers of directory listings, returning the
+// response headers.
+func (s *server) setListingHeaders(c route.Context) http.Header {
+	header := c.Response().Header()
+	header.Add(route.HeaderVary, route.HeaderAccept)
+	s.setCORSHeaders(c)
+	if s.NoSniff {
+		header.Set(route.HeaderXContentTypeOptions, "nosniff")
+	}
+	return header
+}
+
+// sort orders the entries by key, directories first. Unknown keys and orders
+// sort by name in ascending order.
+func (l *DirListing) sort(key, order string) {
+	less := l.less(key, order)
+	sort.Slice(l.Files, func(i, j int) bool {
+		return less(l.Files[i], l.Files[j])
+	})
+}
+
+// less returns the order of the entries by key, directories first, setting
+// the sort key and order of the listing.
+func (l *DirListing) less(key, order string) func(a, b DirEntry) bool {
+	less := func(a, b DirEntry) bool { return a.Name < b.Name }
+	switch key {
+	case "size":
+		less = func(a, b DirEntry) bool { return a.Size < b.Size }
+	case "mtime":
+		less = func(a, b DirEntry) bool { return a.ModTime.Before(b.ModTime) }
+	case "type":
+		less = func(a, b DirEntry) bool {
+			if ka, kb := a.Kind(), b.Kind(); ka != kb {
+				return ka < kb
+			}
+			return a.Type() < b.Type()
+		}
+	default:
+		key = "name"
+	}
+	if order != "desc" {
+		order = "asc"
+	}
+	l.Sort, l.Order = key, order
+
+	return func(a, b DirEntry) bool {
+		if a.Dir != b.Dir {
+			return a.Dir
+		}
+		if less(a, b) {
+			return order == "asc"
+		}
+		if less(b, a) {
+			return order == "desc"
+		}
+		return a.Name < b.Name
+	}
+}
+
+// wantsJSON reports whether a listing is requested as JSON, either with the
+// "format" query parameter or the Accept header.
+func wantsJSON(c route.Context) bool {
+	switch c.QueryParam("format") {
+	case "json":
+		return true
+	case "html":
+		return false
+	}
+	return strings.Contains(c.Request().Header.Get(route.HeaderAccept), route.MIMEApplicationJSON)
+}
+
+const (
+	_ = 1.0 << (10 * iota) // Ignore first value by assigning to blank identifier.
+	KB
+	MB
+	GB
+	TB
+	PB
+	EB
+)
+
+func formatFileSize(b int64) string {
+	multiple := ""
+	value := float64(b)
+
+	switch {
+	case b >= EB:
+		value /= EB
+		multiple = "EB"
+	case b >= PB:
+		value /= PB
+		multiple = "PB"
+	case b >= TB:
+		value /= TB
+		multiple = "TB"
+	case b >= GB:
+		value /= GB
+		multiple = "GB"
+	case b >= MB:
+		value /= MB
+		multiple = "MB"
+	case b >= KB:
+		value /= KB
+		multiple = "KB"
+	case b == 0:
+		return "0"
+	default:
+		return strconv.FormatInt(b, 10) + "B"
+	}
+
+	return fmt.Sprintf("%.2f%s", value, multiple)
+}
@@ -0,0 +1,488 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrowseJSON(t *testing.T) {
+	mw := New(Root("testdata"), Browse(true))
+
+	assert := assert.New(t)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/browse/?format=json", nil),
+		func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/browse/", nil)
+			req.Header.Set(route.HeaderAccept, route.MIMEApplicationJSON)
+			return req
+		}(),
+	} {
+		mux := route.NewServeMux()
+		rec := httptest.NewRecorder()
+		c := mux.NewContext(req, rec)
+		if assert.NoError(mw(c, route.NotFoundHandler)) {
+			assert.Equal(route.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(route.HeaderContentType))
+			var entries []map[string]interface{}
+			if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &entries)) && assert.Len(entries, 2) {
+				names := []interface{}{entries[0]["name"], entries[1]["name"]}
+				assert.ElementsMatch([]interface{}{"file1.txt", "file2.txt"}, names)
+				assert.Equal(false, entries[0]["isDir"])
+				assert.Equal(float64(5), entries[0]["size"])
+				assert.NotEmpty(entries[0]["mtime"])
+			}
+		}
+	}
+}
+
+func TestBrowseTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("list").Parse(
+		`{{ .Name }}:{{ range .Files }} {{ .Name }}({{ .HumanSize }}){{ end }}`,
+	))
+	mw := New(Root("testdata"), Browse(true), BrowseTemplate(tmpl))
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/browse/", nil)
+	rec := httptest.NewRecorder()
+	c := mux.NewContext(req, rec)
+
+	assert := assert.New(t)
+	if assert.NoError(mw(c, route.NotFoundHandler)) {
+		assert.Equal("/browse: file1.txt(5B) file2.txt(11B)", rec.Body.String())
+	}
+}
+
+func TestBrowseSort(t *testing.T) {
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"b.txt":     {Data: []byte("12345")},
+			"c.txt":     {Data: []byte("1")},
+			"a.txt":     {Data: []byte("123")},
+			"z/one.txt": {},
+		}),
+		Browse(true),
+	)
+	names := func(query string) (names []string) {
+		rec := serve(t, mw, http.MethodGet, "/?format=json&"+query)
+		var entries []DirEntry
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return
+	}
+
+	assert := assert.New(t)
+	assert.Equal([]string{"z", "a.txt", "b.txt", "c.txt"}, names(""))
+	assert.Equal([]string{"z", "c.txt", "b.txt", "a.txt"}, names("order=desc"))
+	assert.Equal([]string{"z", "c.txt", "a.txt", "b.txt"}, names("sort=size"))
+	assert.Equal([]string{"z", "b.txt", "a.txt", "c.txt"}, names("sort=size&order=desc"))
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/?sort=size", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `href="?order=desc&amp;sort=size">Size ↑</a>`)
+	}
+}
+
+func TestBrowseModTime(t *testing.T) {
+	modTime := time.Date(2019, 7, 18, 7, 13, 6, 0, time.UTC)
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"file.txt": {ModTime: modTime},
+		}),
+		Browse(true),
+		BrowseTimeFormat(time.RFC822),
+		BrowseTimeLocation(time.FixedZone("CEST", 2*60*60)),
+	)
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	assert := assert.New(t)
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), "18 Jul 19 09:13 CEST")
+	}
+}
+
+func TestBrowseBreadcrumbs(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]Breadcrumb{{"/", "./"}}, DirListing{Name: "/"}.Breadcrumbs())
+	assert.Equal([]Breadcrumb{
+		{"/", "./../../"},
+		{"a", "./../"},
+		{"b", "./"},
+	}, DirListing{Name: "/a/b"}.Breadcrumbs())
+
+	mw := New(Root("testdata"), Browse(true))
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/browse/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `<a href="./../">/</a><a href="./">browse</a>`)
+		assert.Contains(rec.Body.String(), `<a class="dir" href="../">../</a>`)
+	}
+}
+
+func TestBrowseAuth(t *testing.T) {
+	mw := New(Root("testdata"), Browse(true), BrowseAuth(func(c route.Context) (bool, error) {
+		return c.Request().Header.Get(route.HeaderAuthorization) == "admin", nil
+	}))
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/browse/", route.HeaderAuthorization, "admin").Code)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/browse/").Code)
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/browse/file1.txt").Code)
+}
+
+func TestBrowseToken(t *testing.T) {
+	mw := New(Root("testdata"), Browse(true), BrowseToken("secret"))
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/browse/").Code)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/browse/?browse_token=wrong").Code)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/browse/", "X-Browse-Token", "wrong").Code)
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/browse/", "X-Browse-Token", "secret").Code)
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/browse/file1.txt").Code)
+
+	rec := serve(t, mw, http.MethodGet, "/browse/?browse_token=secret")
+	assert.Equal(http.StatusOK, rec.Code)
+	cookie := rec.Header().Get("Set-Cookie")
+	assert.Contains(cookie, "browse_token=secret")
+	assert.Contains(cookie, "HttpOnly")
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/browse/", "Cookie", "browse_token=secret").Code)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/browse/", "Cookie", "browse_token=wrong").Code)
+}
+
+func TestBrowseReadme(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/readme.md":   {Data: []byte("# Docs\n\nRead *me*.")},
+		"docs/README.txt":  {Data: []byte("<plain>")},
+		"notes/README.txt": {Data: []byte("<plain>")},
+		"empty/file.txt":   {Data: []byte("file")},
+	}
+	mw := New(Filesystem(fsys), Browse(true), BrowseReadme(true))
+
+	assert := assert.New(t)
+	assert.Contains(serve(t, mw, http.MethodGet, "/docs/").Body.String(), "<h1>Docs</h1>\n<p>Read <em>me</em>.</p>")
+	assert.Contains(serve(t, mw, http.MethodGet, "/notes/").Body.String(), "<pre>&lt;plain&gt;</pre>")
+	assert.NotContains(serve(t, mw, http.MethodGet, "/empty/").Body.String(), `class="readme"`)
+}
+
+func TestDirEntryType(t *testing.T) {
+	assert := assert.New(t)
+	for _, tc := range []struct {
+		entry     DirEntry
+		kind, typ string
+	}{
+		{DirEntry{Name: "assets", Dir: true}, KindDirectory, "Folder"},
+		{DirEntry{Name: "logo.PNG"}, KindImage, "PNG image"},
+		{DirEntry{Name: "app.js"}, KindCode, "JavaScript"},
+		{DirEntry{Name: "backup.tar.gz"}, KindArchive, "GZ archive"},
+		{DirEntry{Name: "font.woff2"}, KindFont, "WOFF2 file"},
+		{DirEntry{Name: "Makefile"}, KindFile, "File"},
+	} {
+		assert.Equal(tc.kind, tc.entry.Kind(), tc.entry.Name)
+		assert.Equal(tc.typ, tc.entry.Type(), tc.entry.Name)
+		assert.Contains(string(tc.entry.Icon()), kindColors[tc.kind], tc.entry.Name)
+	}
+
+	l := DirListing{Files: []DirEntry{{Name: "b.txt"}, {Name: "a.png"}, {Name: "z", Dir: true}, {Name: "c.jpg"}}}
+	l.sort("type", "asc")
+	var names []string
+	for _, f := range l.Files {
+		names = append(names, f.Name)
+	}
+	assert.Equal([]string{"z", "c.jpg", "a.png", "b.txt"}, names)
+}
+
+func TestBrowseFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Report-2023.pdf": {Data: []byte("a")},
+		"report-2024.pdf": {Data: []byte("b")},
+		"photo.jpg":       {Data: []byte("c")},
+		"reports/x.txt":   {Data: []byte("d")},
+	}
+	mw := New(Filesystem(fsys), Browse(true))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/?q=REPORT&format=json", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		var entries []DirEntry
+		assert.NoError(json.Unmarshal(rec.Body.Bytes(), &entries))
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		assert.Equal([]string{"reports", "Report-2023.pdf", "report-2024.pdf"}, names)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?q=photo", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `value="photo"`)
+		assert.Contains(rec.Body.String(), `href="?order=asc&amp;q=photo&amp;sort=size"`)
+		assert.NotContains(rec.Body.String(), "report")
+	}
+}
+
+func TestBrowsePagination(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 25; i++ {
+		fsys[fmt.Sprintf("file%02d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	mw := New(Filesystem(fsys), Browse(true), BrowsePerPage(10))
+	get := func(query string) (names []string, rec *httptest.ResponseRecorder) {
+		rec = serve(t, mw, http.MethodGet, "/?format=json&"+query)
+		var entries []DirEntry
+		json.Unmarshal(rec.Body.Bytes(), &entries)
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return
+	}
+
+	assert := assert.New(t)
+	names, rec := get("")
+	assert.Len(names, 10)
+	assert.Equal("file00.txt", names[0])
+	assert.Equal("25", rec.Header().Get("X-Total-Count"))
+	names, _ = get("page=3")
+	assert.Equal([]string{"file20.txt", "file21.txt", "file22.txt", "file23.txt", "file24.txt"}, names)
+	names, _ = get("page=99&per_page=20")
+	assert.Equal([]string{"file20.txt", "file21.txt", "file22.txt", "file23.txt", "file24.txt"}, names)
+
+	l := DirListing{Sort: "name", Order: "asc", Page: 2, PerPage: 10, Total: 25}
+	assert.Equal(3, l.Pages())
+	assert.Equal("?order=asc&page=1&per_page=10&sort=name", l.PrevURL())
+	assert.Equal("?order=asc&page=3&per_page=10&sort=name", l.NextURL())
+	l.Page = 3
+	assert.Empty(l.NextURL())
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/?page=2", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), "Page 2 of 3")
+	}
+}
+
+func TestBrowseTree(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.txt":          {Data: []byte("i")},
+		"css/site.css":       {Data: []byte("c")},
+		"js/lib/a/b/deep.js": {Data: []byte("d")},
+		"js/.hidden/x.js":    {Data: []byte("h")},
+	}
+	mw := New(Filesystem(fsys), Browse(true), BrowseTree(true), BrowseTreeDepth(3))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/?tree=1&format=json", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		var nodes []TreeNode
+		if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &nodes)) && assert.Len(nodes, 3) {
+			assert.Equal("css", nodes[0].Name)
+			assert.Equal("./css/site.css", nodes[0].Children[0].Path)
+			js := nodes[1]
+			assert.Equal("js", js.Name)
+			if assert.Len(js.Children, 1) {
+				lib := js.Children[0]
+				assert.Equal("./js/lib", lib.Path)
+				if assert.Len(lib.Children, 1) {
+					assert.Equal("a", lib.Children[0].Name)
+					assert.Nil(lib.Children[0].Children) // Depth limit
+				}
+			}
+			assert.Equal("index.txt", nodes[2].Name)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?tree=1", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `<summary><a class="dir" href="./js/lib/">`)
+	}
+
+	// Disabled
+	mw = New(Filesystem(fsys), Browse(true))
+	req = httptest.NewRequest(http.MethodGet, "/?tree=1", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.NotContains(rec.Body.String(), "<summary>")
+	}
+}
+
+func TestBrowseThumbnails(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	var data bytes.Buffer
+	assert := assert.New(t)
+	assert.NoError(png.Encode(&data, src))
+	fsys := fstest.MapFS{
+		"photos/a b.png":   {Data: data.Bytes()},
+		"photos/notes.txt": {Data: []byte("notes")},
+	}
+	mw := New(Filesystem(fsys), Browse(true), ImageResize(true), ImageSizes("300x"))
+	body := serve(t, mw, http.MethodGet, "/photos/").Body.String()
+	assert.Contains(body, `<img class="thumbnail" src="a%20b.png?w=64&amp;h=64&amp;fit=cover"`)
+	assert.Equal(1, strings.Count(body, `class="thumbnail"`))
+	img, _, err := image.Decode(serve(t, mw, http.MethodGet, "/photos/a%20b.png?w=64&h=64&fit=cover").Body)
+	if assert.NoError(err) {
+		assert.Equal(image.Rect(0, 0, 64, 64), img.Bounds())
+	}
+
+	body = serve(t, New(Filesystem(fsys), Browse(true)), http.MethodGet, "/photos/").Body.String()
+	assert.NotContains(body, `<img class="thumbnail"`)
+}
+
+func TestBrowseContentLength(t *testing.T) {
+	assert := assert.New(t)
+	mw := New(Root("testdata"), Browse(true), BrowseTree(true))
+	for _, target := range []string{"/browse/", "/browse/?format=json", "/browse/?tree=1"} {
+		rec := serve(t, mw, http.MethodGet, target)
+		assert.Equal(http.StatusOK, rec.Code, target)
+		assert.Equal(strconv.Itoa(rec.Body.Len()), rec.Header().Get(route.HeaderContentLength), target)
+		head := serve(t, mw, http.MethodHead, target)
+		assert.Equal(rec.Header().Get(route.HeaderContentLength), head.Header().Get(route.HeaderContentLength), target)
+		assert.Empty(head.Body.String(), target)
+	}
+
+	// Larger listings are streamed.
+	full := serve(t, mw, http.MethodGet, "/browse/").Body.String()
+	rec := serve(t, New(Root("testdata"), Browse(true), BrowseBufferSize(100)), http.MethodGet, "/browse/")
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Empty(rec.Header().Get(route.HeaderContentLength))
+	assert.Equal(full, rec.Body.String())
+}
+
+func TestBrowseCompress(t *testing.T) {
+	assert := assert.New(t)
+	plain := serve(t, New(Root("testdata"), Browse(true)), http.MethodGet, "/browse/", route.HeaderAcceptEncoding, "gzip").Body.String()
+	for _, mw := range []route.MiddlewareFunc{
+		New(Root("testdata"), Browse(true), Compress(true)),
+		New(Root("testdata"), Browse(true), Compress(true), BrowseBufferSize(100)),
+	} {
+		rec := serve(t, mw, http.MethodGet, "/browse/", route.HeaderAcceptEncoding, "gzip")
+		assert.Equal("gzip", rec.Header().Get(route.HeaderContentEncoding))
+		assert.Contains(rec.Header().Values(route.HeaderVary), route.HeaderAcceptEncoding)
+		if cl := rec.Header().Get(route.HeaderContentLength); cl != "" {
+			assert.Equal(strconv.Itoa(rec.Body.Len()), cl)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if assert.NoError(err) {
+			b, _ := io.ReadAll(zr)
+			assert.Equal(plain, string(b))
+		}
+
+		rec = serve(t, mw, http.MethodGet, "/browse/")
+		assert.Empty(rec.Header().Get(route.HeaderContentEncoding))
+		assert.Equal(plain, rec.Body.String())
+	}
+}
+
+func TestBrowseMaxEntries(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 10; i++ {
+		fsys[fmt.Sprintf("file%d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	assert := assert.New(t)
+	mw := New(Filesystem(fsys), Browse(true), BrowseMaxEntries(4))
+	rec := serve(t, mw, http.MethodGet, "/")
+	assert.Contains(rec.Body.String(), "Showing the first 4 of 10 entries")
+	assert.Equal(4, strings.Count(rec.Body.String(), `<li data-name=`))
+
+	var listing struct {
+		Entries   []DirEntry `json:"entries"`
+		Truncated bool       `json:"truncated"`
+		Count     int        `json:"count"`
+	}
+	rec = serve(t, mw, http.MethodGet, "/?format=json")
+	if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &listing)) {
+		assert.Len(listing.Entries, 4)
+		assert.True(listing.Truncated)
+		assert.Equal(10, listing.Count)
+	}
+	rec = serve(t, mw, http.MethodGet, "/?format=json&q=file1")
+	if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &listing)) {
+		assert.Len(listing.Entries, 1)
+		assert.False(listing.Truncated)
+		assert.Equal(1, listing.Count)
+	}
+	assert.NotContains(serve(t, mw, http.MethodGet, "/?q=file1").Body.String(), "Showing the first")
+
+	// The first entries in sort order are listed.
+	rec = serve(t, mw, http.MethodGet, "/?format=json&sort=name&order=desc")
+	if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &listing)) && assert.Len(listing.Entries, 4) {
+		assert.Equal("file9.txt", listing.Entries[0].Name)
+		assert.Equal("file6.txt", listing.Entries[3].Name)
+	}
+	mw = New(Filesystem(fsys), Browse(true), BrowseMaxEntries(4), BrowsePerPage(3))
+	rec = serve(t, mw, http.MethodGet, "/?format=json&page=9")
+	if assert.NoError(json.Unmarshal(rec.Body.Bytes(), &listing)) && assert.Len(listing.Entries, 1) {
+		assert.Equal("file3.txt", listing.Entries[0].Name)
+		assert.Equal("4", rec.Header().Get(headerTotalCount))
+	}
+}
+
+// countingEntry counts the reads of the FileInfo of a directory entry.
+type countingEntry struct {
+	fs.DirEntry
+	infos *int
+}
+
+func (e countingEntry) Info() (fs.FileInfo, error) {
+	*e.infos++
+	return e.DirEntry.Info()
+}
+
+func TestPageEntries(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 100; i++ {
+		fsys[fmt.Sprintf("file%02d.txt", i)] = &fstest.MapFile{Data: []byte("x")}
+	}
+	entries, _ := fsys.ReadDir(".")
+
+	assert := assert.New(t)
+	l := DirListing{Page: 2, PerPage: 3}
+	p := &pageEntries{less: l.less("name", "desc"), max: l.lastEntry(0)}
+	infos := 0
+	for _, e := range entries {
+		p.add(pageEntry{DirEntry{Name: e.Name()}, countingEntry{e, &infos}})
+		assert.True(len(p.entries) <= 6)
+	}
+	files, err := (&server{}).sortedEntries(p)
+	if assert.NoError(err) && assert.Len(files, 6) {
+		assert.Equal("file99.txt", files[0].Name)
+		assert.Equal("file94.txt", files[5].Name)
+		assert.Equal(int64(1), files[5].Size)
+	}
+	assert.Equal(6, infos)
+
+	l = DirListing{Page: math.MaxInt32, PerPage: 1000}
+	assert.Equal(math.MaxInt32, l.lastEntry(0))
+	assert.Equal(10, l.lastEntry(10))
+	l.PerPage = 0
+	assert.Equal(0, l.lastEntry(0))
+}
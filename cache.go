@@ -0,0 +1,249 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// cache is an LRU cache of file contents bounded in size and entries.
+	cache struct {
+		maxBytes   int64
+		maxEntries int
+		ttl        time.Duration
+
+		mu      sync.Mutex
+		bytes   int64
+		lru     *list.List // Front is most recently used.
+		entries map[string]*list.Element
+	}
+
+	// statCache caches the results of stat for a short time.
+	statCache struct {
+		ttl time.Duration
+
+		mu      sync.Mutex
+		entries map[string]statEntry
+	}
+
+	// statEntry is a cached stat result.
+	statEntry struct {
+		fi      fs.FileInfo
+		err     error
+		expires time.Time
+	}
+
+	// cacheEntry is a cached file.
+	cacheEntry struct {
+		key     string
+		name    string // Name of the file, which differs from key for indexes.
+		fi      fs.FileInfo
+		modTime time.Time
+		etag    string
+		ctype   string
+		data    []byte
+
+		// Content in other encodings, by Content-Encoding.
+		encoded map[string][]byte
+
+		expires time.Time
+	}
+)
+
+func newCache(maxBytes int64, maxEntries int, ttl time.Duration) *cache {
+	return &cache{
+		maxBytes:   maxBytes,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		lru:        list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// invalidate removes the entries of the named file and, if it is a directory,
+// of its content. It removes all the entries if name is ".".
+func (c *cache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if e := elem.Value.(*cacheEntry); under(e.key, name) || under(e.name, name) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// cacheable reports whether a file of the given size fits in the cache. Files
+// taking more than a quarter of it are not cached so that a few large files
+// don't evict all the small hot ones.
+func (c *cache) cacheable(size int64) bool {
+	return size <= c.maxBytes/4
+}
+
+// get returns the fresh entry for key, or nil.
+func (c *cache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e := elem.Value.(*cacheEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(elem)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return e
+}
+
+// put adds e to the cache, evicting the least recently used entries to make
+// room for it.
+func (c *cache) put(e *cacheEntry) {
+	if c.ttl > 0 {
+		e.expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[e.key]; ok {
+		c.remove(elem)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.bytes += e.size()
+	for c.bytes > c.maxBytes || c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes elem from the cache. c.mu must be held.
+func (c *cache) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.bytes -= e.size()
+}
+
+// size returns the memory taken by the content of the entry.
+func (e *cacheEntry) size() int64 {
+	n := int64(len(e.data))
+	for _, b := range e.encoded {
+		n += int64(len(b))
+	}
+	return n
+}
+
+// loadEntry reads the named file into a new cache entry for key. It returns
+// nil if the file doesn't fit in the cache.
+func (s *server) loadEntry(key, name string, fi fs.FileInfo) (*cacheEntry, error) {
+	if !fi.Mode().IsRegular() || !s.cache.cacheable(fi.Size()) {
+		return nil, nil
+	}
+	data, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	e := &cacheEntry{
+		key:     key,
+		name:    name,
+		fi:      fi,
+		modTime: fi.ModTime(),
+		etag:    etag(fi),
+		ctype:   s.contentType(name, bytes.NewReader(data)),
+		data:    data,
+		encoded: map[string][]byte{},
+	}
+	if s.Precompressed {
+		for _, pe := range precompressedEncodings {
+			if s.escapes(name + pe.ext) {
+				continue
+			}
+			if b, err := fs.ReadFile(s.fsys, name+pe.ext); err == nil {
+				e.encoded[pe.encoding] = b
+			}
+		}
+	}
+	if s.precompressed != nil {
+		for encoding, b := range s.precompressed.read(name, fi) {
+			if _, ok := e.encoded[encoding]; !ok {
+				e.encoded[encoding] = b
+			}
+		}
+	}
+	if _, ok := e.encoded["gzip"]; !ok && s.compressible(e.ctype, fi.Size()) {
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		zw.Write(data)
+		if zw.Close() == nil && buf.Len() < len(data) {
+			e.encoded["gzip"] = buf.Bytes()
+		}
+	}
+	return e, nil
+}
+
+// statCacheSize is the maximum number of entries of a stat cache.
+const statCacheSize = 10000
+
+func newStatCache(ttl time.Duration) *statCache {
+	return &statCache{ttl: ttl, entries: map[string]statEntry{}}
+}
+
+// stat returns the cached result of stat for name, calling stat if there is
+// none.
+func (c *statCache) stat(name string, stat func(string) (fs.FileInfo, error)) (fs.FileInfo, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.fi, e.err
+	}
+
+	fi, err := stat(name)
+	var ue *unavailableError
+	if errors.As(err, &ue) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fi, err // Transient, or canceled.
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= statCacheSize {
+		for name, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, name)
+			}
+		}
+		if len(c.entries) >= statCacheSize {
+			c.entries = map[string]statEntry{}
+		}
+	}
+	c.entries[name] = statEntry{fi, err, now.Add(c.ttl)}
+	return fi, err
+}
+
+// invalidate removes the entries of the named file and, if it is a directory,
+// of its content. It removes all the entries if name is ".".
+func (c *statCache) invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if under(key, name) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// under reports whether the file name is dir or inside it.
+func under(name, dir string) bool {
+	return dir == "." || name == dir || strings.HasPrefix(name, dir+"/")
+}
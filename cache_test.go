@@ -0,0 +1,129 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":      {Data: []byte("a")},
+		"b.txt":      {Data: []byte("b")},
+		"index.html": {Data: []byte("index")},
+	}
+	mw := New(Filesystem(fsys), Cache(1<<20, 2, 0))
+
+	assert := assert.New(t)
+	assert.Equal("a", serve(t, mw, http.MethodGet, "/a.txt").Body.String())
+	assert.Equal("index", serve(t, mw, http.MethodGet, "/").Body.String())
+
+	// Cached files are served without the filesystem.
+	delete(fsys, "a.txt")
+	assert.Equal("a", serve(t, mw, http.MethodGet, "/a.txt").Body.String())
+
+	// Least recently used files are evicted.
+	serve(t, mw, http.MethodGet, "/b.txt")
+	serve(t, mw, http.MethodGet, "/")
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/a.txt").Code)
+}
+
+func TestCacheTTL(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	mw := New(Filesystem(fsys), Cache(1<<20, 0, time.Nanosecond))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+	assert.NoError(mw(mux.NewContext(req, httptest.NewRecorder()), route.NotFoundHandler))
+
+	time.Sleep(time.Millisecond)
+	fsys["a.txt"] = &fstest.MapFile{Data: []byte("new")}
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("new", rec.Body.String())
+	}
+}
+
+func TestCacheLimits(t *testing.T) {
+	c := newCache(16, 0, 0)
+	assert := assert.New(t)
+	assert.True(c.cacheable(4))
+	assert.False(c.cacheable(5))
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		c.put(&cacheEntry{key: key, data: []byte("1234")})
+	}
+	assert.Nil(c.get("a"))
+	assert.NotNil(c.get("e"))
+	assert.Equal(int64(16), c.bytes)
+}
+
+func TestCacheCompress(t *testing.T) {
+	text := make([]byte, 2048)
+	for i := range text {
+		text[i] = 'a'
+	}
+	mw := New(
+		Filesystem(fstest.MapFS{"a.txt": {Data: text}}),
+		Cache(1<<20, 0, 0),
+		Compress(true),
+	)
+
+	assert := assert.New(t)
+	for i := 0; i < 2; i++ {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+		req.Header.Set(route.HeaderAcceptEncoding, "gzip")
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal("gzip", rec.Header().Get(route.HeaderContentEncoding))
+			assert.True(rec.Body.Len() < len(text))
+		}
+	}
+}
+
+func TestStatCache(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("a")}}
+	mw, h := NewHandle(Filesystem(fsys), StatCache(time.Hour))
+
+	assert := assert.New(t)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/b.txt").Code)
+	fsys["b.txt"] = &fstest.MapFile{Data: []byte("b")}
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/b.txt").Code)
+
+	h.Invalidate("/b.txt")
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/b.txt").Code)
+
+	delete(fsys, "a.txt")
+	delete(fsys, "b.txt")
+	h.InvalidateAll()
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/a.txt").Code)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/b.txt").Code)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	c := newCache(1<<20, 0, 0)
+	for _, e := range []*cacheEntry{
+		{key: "a.txt", name: "a.txt"},
+		{key: "dir", name: "dir/index.html"},
+		{key: "dir/b.txt", name: "dir/b.txt"},
+	} {
+		c.put(e)
+	}
+
+	assert := assert.New(t)
+	c.invalidate("dir/index.html")
+	assert.Nil(c.get("dir"))
+	assert.NotNil(c.get("dir/b.txt"))
+	c.invalidate("dir")
+	assert.Nil(c.get("dir/b.txt"))
+	assert.NotNil(c.get("a.txt"))
+	c.invalidate(".")
+	assert.Nil(c.get("a.txt"))
+}
@@ -0,0 +1,84 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// caseIndexSize is the maximum number of indexed directories, beyond which
+// the index is emptied.
+const caseIndexSize = 10000
+
+// caseIndex caches the names of the entries of directories by lower case
+// name, to resolve request paths case-insensitively.
+type caseIndex struct {
+	mu   sync.Mutex
+	dirs map[string]map[string]string
+}
+
+// resolveCase returns the name of the file matching the named file
+// case-insensitively, or name if there is none. Exact matches win.
+func (s *server) resolveCase(ctx context.Context, name string) string {
+	if _, err := s.stat(ctx, name); !errors.Is(err, fs.ErrNotExist) {
+		return name
+	}
+	resolved := "."
+	for _, elem := range strings.Split(name, "/") {
+		next := path.Join(resolved, elem)
+		if _, err := s.stat(ctx, next); errors.Is(err, fs.ErrNotExist) {
+			actual, ok := s.caseIndex.lookup(ctx, s, resolved, elem)
+			if !ok {
+				return name
+			}
+			next = path.Join(resolved, actual)
+		}
+		resolved = next
+	}
+	return resolved
+}
+
+// lookup returns the name of the entry of the named directory matching elem
+// case-insensitively, indexing the directory if needed.
+func (x *caseIndex) lookup(ctx context.Context, s *server, dir, elem string) (string, bool) {
+	x.mu.Lock()
+	names, ok := x.dirs[dir]
+	x.mu.Unlock()
+	if !ok {
+		names = map[string]string{}
+		err := s.readDir(ctx, dir, func(e fs.DirEntry) error {
+			key := strings.ToLower(e.Name())
+			if _, ok := names[key]; !ok {
+				names[key] = e.Name()
+			}
+			return nil
+		})
+		if err != nil {
+			return "", false
+		}
+		x.mu.Lock()
+		if len(x.dirs) >= caseIndexSize {
+			x.dirs = map[string]map[string]string{}
+		}
+		x.dirs[dir] = names
+		x.mu.Unlock()
+	}
+	actual, ok := names[strings.ToLower(elem)]
+	return actual, ok
+}
+
+// invalidate removes the index of the directory of the named file and, if it
+// is a directory, of its content.
+func (x *caseIndex) invalidate(name string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for dir := range x.dirs {
+		if dir == path.Dir(name) || under(dir, name) {
+			delete(x.dirs, dir)
+		}
+	}
+}
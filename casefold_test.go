@@ -0,0 +1,66 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaseInsensitive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"images/logo.png": {Data: []byte("logo")},
+		"images/Logo.PNG": {Data: []byte("LOGO")},
+		"Docs/Guide.html": {Data: []byte("guide")},
+		".env":            {Data: []byte("SECRET=1")},
+	}
+	assert := assert.New(t)
+	mw, h := NewHandle(Filesystem(fsys), CaseInsensitive(true))
+	for path, want := range map[string]string{
+		"/images/logo.png": "logo",
+		"/images/Logo.PNG": "LOGO",
+		"/IMAGES/logo.png": "logo",
+		"/docs/guide.html": "guide",
+		"/DOCS/GUIDE.HTML": "guide",
+	} {
+		rec := serve(t, mw, http.MethodGet, path)
+		assert.Equal(http.StatusOK, rec.Code, path)
+		assert.Equal(want, rec.Body.String(), path)
+	}
+	for _, path := range []string{"/docs/missing.html", "/missing/guide.html", "/.ENV"} {
+		assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, path).Code, path)
+	}
+
+	// Directories are indexed again once invalidated.
+	fsys["Docs/FAQ.html"] = &fstest.MapFile{Data: []byte("faq")}
+	h.Invalidate("/Docs/FAQ.html")
+	assert.Equal("faq", serve(t, mw, http.MethodGet, "/docs/faq.html").Body.String())
+
+	assert.Equal(http.StatusNotFound, serve(t, New(Filesystem(fsys)), http.MethodGet, "/docs/guide.html").Code)
+}
+
+func TestCaseInsensitiveTryFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"logo.png":      {Data: []byte("logo")},
+		"caf\u00e9.txt": {Data: []byte("cafe")},
+		"index.html":    {Data: []byte("index")},
+	}
+	mw := New(Filesystem(fsys), CaseInsensitive(true), TryFiles("$uri", "/index.html"))
+
+	assert := assert.New(t)
+	for path, want := range map[string]string{
+		"/Logo.PNG":       "logo",
+		"/CAFE\u0301.TXT": "cafe",
+		"/missing":        "index",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), path) {
+			assert.Equal(want, rec.Body.String(), path)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/goroute/route"
+)
+
+type (
+	// compressor is a pooled compressing writer.
+	compressor interface {
+		io.WriteCloser
+		Reset(w io.Writer)
+	}
+
+	// compressWriter compresses the body of successful responses written to
+	// the underlying http.ResponseWriter.
+	compressWriter struct {
+		http.ResponseWriter
+		compressor  compressor
+		encoding    string
+		passthrough bool
+		wrote       bool
+	}
+)
+
+// compressEncodings are the on-the-fly encodings in order of preference.
+var compressEncodings = []struct {
+	encoding string
+	pool     *sync.Pool
+}{
+	{"br", &sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}}},
+	{"gzip", &sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}}},
+}
+
+// defaultCompressTypes are the MIME type prefixes compressed by default.
+var defaultCompressTypes = []string{
+	"text/",
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/wasm",
+	"application/xml",
+	"image/svg+xml",
+	"font/otf",
+	"font/ttf",
+}
+
+// compressible reports whether a response of the given MIME type and size
+// should be compressed.
+func (s *server) compressible(ctype string, size int64) bool {
+	return s.Compress && size >= s.CompressMinSize && s.compressType(ctype)
+}
+
+// compressType reports whether the MIME type is one of CompressTypes.
+func (s *server) compressType(ctype string) bool {
+	for _, t := range s.CompressTypes {
+		if strings.HasPrefix(ctype, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compress replaces the response writer with one compressing the body in the
+// preferred encoding accepted by the client. The returned function must be
+// called once the response is written. It returns nil if the client accepts
+// none of the encodings.
+func compress(c route.Context) func() {
+	accept := c.Request().Header.Get(route.HeaderAcceptEncoding)
+	for _, ce := range compressEncodings {
+		if !acceptsEncoding(accept, ce.encoding) {
+			continue
+		}
+		res := c.Response()
+		w := &compressWriter{
+			ResponseWriter: res.Writer,
+			compressor:     ce.pool.Get().(compressor),
+			encoding:       ce.encoding,
+		}
+		w.compressor.Reset(w.ResponseWriter)
+		res.Writer = w
+		pool := ce.pool
+		return func() {
+			if w.wrote {
+				w.compressor.Close()
+			}
+			w.compressor.Reset(io.Discard)
+			pool.Put(w.compressor)
+			res.Writer = w.ResponseWriter
+		}
+	}
+	return nil
+}
+
+// compressBytes returns the data compressed in the preferred encoding
+// accepted by the client, and the encoding. It returns an empty encoding if
+// the client accepts none of the encodings.
+func compressBytes(accept string, data []byte) ([]byte, string) {
+	for _, ce := range compressEncodings {
+		if !acceptsEncoding(accept, ce.encoding) {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		zw := ce.pool.Get().(compressor)
+		zw.Reset(buf)
+		zw.Write(data)
+		zw.Close()
+		zw.Reset(io.Discard)
+		ce.pool.Put(zw)
+		return buf.Bytes(), ce.encoding
+	}
+	return nil, ""
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code >= 100 && code < 200 {
+		// Informational responses, e.g. Early Hints, precede the final one.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	header := w.Header()
+	header.Add(route.HeaderVary, route.HeaderAcceptEncoding)
+	if code == http.StatusOK && header.Get(route.HeaderContentEncoding) == "" {
+		header.Set(route.HeaderContentEncoding, w.encoding)
+		header.Del(route.HeaderContentLength)
+	} else {
+		w.passthrough = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	w.wrote = true
+	return w.compressor.Write(b)
+}
+
+func (w *compressWriter) Flush() {
+	if !w.passthrough {
+		if f, ok := w.compressor.(interface{ Flush() error }); ok {
+			f.Flush()
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
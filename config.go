@@ -0,0 +1,44 @@
+package static
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/goroute/route"
+	"gopkg.in/yaml.v2"
+)
+
+// OptionsFromYAML returns the default options overridden by the YAML or JSON
+// config document, with the keys of the yaml tags of Options, e.g.
+//
+//	root: public
+//	html5: true
+//	cache_control:
+//	  - patterns: ["/assets/**"]
+//	    value: public, max-age=31536000, immutable
+//	aliases:
+//	  /media: /var/lib/media
+//
+// Durations are strings such as "10m". Unknown keys are errors.
+func OptionsFromYAML(r io.Reader) (Options, error) {
+	opts := GetDefaultOptions()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return opts, err
+	}
+	if err := yaml.UnmarshalStrict(b, &opts); err != nil {
+		return opts, fmt.Errorf("static: config: %v", err)
+	}
+	return opts, nil
+}
+
+// NewFromConfig returns a Static middleware configured by the YAML or JSON
+// config document, see OptionsFromYAML, and then by the options, e.g. for
+// Authorize or Skipper, which can't be configured by documents.
+func NewFromConfig(r io.Reader, options ...Option) (route.MiddlewareFunc, error) {
+	opts, err := OptionsFromYAML(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithError(append([]Option{WithOptions(opts)}, options...)...)
+}
@@ -0,0 +1,76 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsFromYAML(t *testing.T) {
+	const config = `
+root: testdata
+html5: true
+rewrite:
+  ^/old/(.*)$: /new/$1
+aliases:
+  /media: testdata/images
+cache_control:
+  - patterns: ["*.html"]
+    value: no-cache
+headers:
+  - patterns: ["**"]
+    values:
+      X-Frame-Options: DENY
+cache_ttl: 10m
+`
+	assert := assert.New(t)
+	opts, err := OptionsFromYAML(strings.NewReader(config))
+	if assert.NoError(err) {
+		assert.Equal("testdata", opts.Root)
+		assert.True(opts.HTML5)
+		assert.Equal(map[string]string{"^/old/(.*)$": "/new/$1"}, opts.Rewrite)
+		assert.Equal(map[string]string{"/media": "testdata/images"}, opts.Aliases)
+		assert.Equal([]CacheRule{{Patterns: []string{"*.html"}, Value: "no-cache"}}, opts.CacheControl)
+		assert.Equal([]HeaderRule{{Patterns: []string{"**"}, Values: map[string]string{"X-Frame-Options": "DENY"}}}, opts.Headers)
+		assert.Equal(10*time.Minute, opts.CacheTTL)
+		assert.Equal("index.html", opts.Index) // Default
+		assert.True(opts.RedirectDirSlash)
+	}
+
+	opts, err = OptionsFromYAML(strings.NewReader(`{"root": "public", "browse": true, "cors_origins": ["*"]}`))
+	if assert.NoError(err) {
+		assert.Equal("public", opts.Root)
+		assert.True(opts.Browse)
+		assert.Equal([]string{"*"}, opts.CORSOrigins)
+	}
+
+	_, err = OptionsFromYAML(strings.NewReader("brwose: true"))
+	assert.Error(err)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	assert := assert.New(t)
+	mw, err := NewFromConfig(strings.NewReader("root: testdata\nheaders:\n  - patterns: [\"*.html\"]\n    values: {X-Test: \"1\"}\n"), Skipper(func(c route.Context) bool {
+		return c.Request().URL.Path == "/skipped"
+	}))
+	if assert.NoError(err) {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Contains(rec.Body.String(), "Route")
+			assert.Equal("1", rec.Header().Get("X-Test"))
+		}
+		req = httptest.NewRequest(http.MethodGet, "/skipped", nil)
+		err := mw(mux.NewContext(req, httptest.NewRecorder()), route.NotFoundHandler)
+		assert.Equal(http.StatusNotFound, err.(*route.HTTPError).Code)
+	}
+
+	_, err = NewFromConfig(strings.NewReader("root: missing"))
+	assert.Error(err)
+}
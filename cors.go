@@ -0,0 +1,69 @@
+package static
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/goroute/route"
+)
+
+// corsExposeHeaders are the response headers exposed to cross-origin scripts
+// besides the CORS-safelisted ones, so that they can make range requests.
+const corsExposeHeaders = "Accept-Ranges, Content-Length, Content-Range, ETag"
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for the origin
+// of the request, or "" if it isn't allowed.
+func (s *server) allowedOrigin(r *http.Request) string {
+	origin := r.Header.Get(route.HeaderOrigin)
+	if origin == "" {
+		return ""
+	}
+	for _, o := range s.CORSOrigins {
+		if o == "*" {
+			return "*"
+		}
+		if o == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// isPreflight reports whether the request is a CORS preflight request.
+func (s *server) isPreflight(r *http.Request) bool {
+	return len(s.CORSOrigins) > 0 && r.Method == http.MethodOptions &&
+		r.Header.Get(route.HeaderOrigin) != "" &&
+		r.Header.Get(route.HeaderAccessControlRequestMethod) != ""
+}
+
+// preflight answers a CORS preflight request for a served file.
+func (s *server) preflight(c route.Context) error {
+	r := c.Request()
+	header := c.Response().Header()
+	header.Add(route.HeaderVary, route.HeaderOrigin)
+	if origin := s.allowedOrigin(r); origin != "" {
+		header.Set(route.HeaderAccessControlAllowOrigin, origin)
+		header.Set(route.HeaderAccessControlAllowMethods, "GET, HEAD, OPTIONS")
+		if h := r.Header.Get(route.HeaderAccessControlRequestHeaders); h != "" {
+			header.Set(route.HeaderAccessControlAllowHeaders, h)
+		}
+		if s.CORSMaxAge > 0 {
+			header.Set(route.HeaderAccessControlMaxAge, strconv.Itoa(int(s.CORSMaxAge.Seconds())))
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// setCORSHeaders sets the CORS headers of the response if its origin is
+// allowed.
+func (s *server) setCORSHeaders(c route.Context) {
+	if len(s.CORSOrigins) == 0 {
+		return
+	}
+	header := c.Response().Header()
+	header.Add(route.HeaderVary, route.HeaderOrigin)
+	if origin := s.allowedOrigin(c.Request()); origin != "" {
+		header.Set(route.HeaderAccessControlAllowOrigin, origin)
+		header.Set(route.HeaderAccessControlExposeHeaders, corsExposeHeaders)
+	}
+}
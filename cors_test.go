@@ -0,0 +1,78 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"font.woff2": {Data: []byte("wOF2")},
+	}
+	mw := New(Filesystem(fsys), CORS([]string{"https://example.com"}, time.Hour))
+
+	assert := assert.New(t)
+
+	// Allowed origin
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/font.woff2", nil)
+	req.Header.Set(route.HeaderOrigin, "https://example.com")
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("https://example.com", rec.Header().Get(route.HeaderAccessControlAllowOrigin))
+		assert.Contains(rec.Header().Get(route.HeaderAccessControlExposeHeaders), "Content-Range")
+		assert.Equal(route.HeaderOrigin, rec.Header().Get(route.HeaderVary))
+	}
+
+	// Other origin
+	req = httptest.NewRequest(http.MethodGet, "/font.woff2", nil)
+	req.Header.Set(route.HeaderOrigin, "https://evil.com")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Empty(rec.Header().Get(route.HeaderAccessControlAllowOrigin))
+	}
+
+	// Preflight
+	req = httptest.NewRequest(http.MethodOptions, "/font.woff2", nil)
+	req.Header.Set(route.HeaderOrigin, "https://example.com")
+	req.Header.Set(route.HeaderAccessControlRequestMethod, http.MethodGet)
+	req.Header.Set(route.HeaderAccessControlRequestHeaders, "range")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusNoContent, rec.Code)
+		assert.Equal("https://example.com", rec.Header().Get(route.HeaderAccessControlAllowOrigin))
+		assert.Equal("GET, HEAD, OPTIONS", rec.Header().Get(route.HeaderAccessControlAllowMethods))
+		assert.Equal("range", rec.Header().Get(route.HeaderAccessControlAllowHeaders))
+		assert.Equal("3600", rec.Header().Get(route.HeaderAccessControlMaxAge))
+		assert.Empty(rec.Body.String())
+	}
+
+	// Preflight for a missing file is left to the next handler
+	req = httptest.NewRequest(http.MethodOptions, "/api", nil)
+	req.Header.Set(route.HeaderOrigin, "https://example.com")
+	req.Header.Set(route.HeaderAccessControlRequestMethod, http.MethodPost)
+	rec = httptest.NewRecorder()
+	err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	if assert.Error(err) {
+		assert.Equal(http.StatusNotFound, err.(*route.HTTPError).Code)
+	}
+
+	// Any origin, cached
+	mw = New(Filesystem(fsys), CORS([]string{"*"}, 0), Cache(1<<20, 0, 0))
+	for i := 0; i < 2; i++ {
+		req = httptest.NewRequest(http.MethodGet, "/font.woff2", nil)
+		req.Header.Set(route.HeaderOrigin, "https://other.com")
+		rec = httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal("*", rec.Header().Get(route.HeaderAccessControlAllowOrigin))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package dbbackend serves files stored as blobs in a database as a file
+// system for the Static middleware.
+//
+//	db, err := sql.Open("postgres", "postgres://localhost/assets")
+//	...
+//	mux.Use(static.New(static.Backend(dbbackend.New(dbbackend.NewPostgres(db, "files")))))
+//
+// Databases are accessed through the Store interface, implemented by
+// Postgres. File names are paths, their slashes separating directories, which
+// exist as long as they contain files. Stats only query the metadata of files,
+// so that conditional requests never read their content, which is read in
+// chunks so that large files are streamed.
+package dbbackend
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// Store is the storage of files in a database. Names are unrooted paths,
+	// e.g. "css/site.css".
+	Store interface {
+		// Stat returns the metadata of the named file, without reading its
+		// content, or an error wrapping fs.ErrNotExist if there is none.
+		Stat(name string) (Metadata, error)
+
+		// ReadAt reads the content of the named file from the offset into b,
+		// returning io.EOF if it reads less than len(b) bytes.
+		ReadAt(name string, b []byte, offset int64) (int, error)
+
+		// List returns the metadata of the files whose names start with the
+		// prefix, e.g. "css/", or of every file if it is empty.
+		List(prefix string) ([]Metadata, error)
+	}
+
+	// Metadata describes a stored file.
+	Metadata struct {
+		Name    string
+		Size    int64
+		ModTime time.Time
+
+		// Entity tag of the file, e.g. a quoted hash of its content.
+		// Optional. Default value "", for which the middleware derives one
+		// from the size and modification time.
+		ETag string
+	}
+
+	// FS is a file system of the files of a Store. It implements fs.StatFS
+	// and fs.ReadDirFS.
+	FS struct {
+		store     Store
+		chunkSize int
+	}
+
+	// Option configures an FS.
+	Option func(*FS)
+)
+
+// New returns the file system of the files of the store.
+func New(store Store, options ...Option) *FS {
+	f := &FS{store: store, chunkSize: 1 << 20}
+	for _, o := range options {
+		o(f)
+	}
+	return f
+}
+
+// ChunkSize sets the size in bytes of the chunks in which content is read.
+// Default value 1 MiB.
+func ChunkSize(size int) Option {
+	return func(f *FS) {
+		if size > 0 {
+			f.chunkSize = size
+		}
+	}
+}
+
+// Open opens the named file or directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	fi, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &dir{fs: f, name: name, info: fi}, nil
+	}
+	return &file{fs: f, info: fi.(*fileInfo)}, nil
+}
+
+// Stat returns the FileInfo of the named file or directory.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *FS) stat(op, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+
+	m, err := f.store.Stat(name)
+	if err == nil {
+		return &fileInfo{name: path.Base(name), meta: m}, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	// Directories are the prefixes of names.
+	files, err := f.store.List(name + "/")
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(files) == 0 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	files, err := f.store.List(prefix)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if len(files) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var entries []fs.DirEntry
+	dirs := map[string]bool{}
+	for _, m := range files {
+		rel := strings.TrimPrefix(m.Name, prefix)
+		if i := strings.IndexByte(rel, '/'); i >= 0 {
+			if !dirs[rel[:i]] {
+				dirs[rel[:i]] = true
+				entries = append(entries, &fileInfo{name: rel[:i], dir: true})
+			}
+			continue
+		}
+		entries = append(entries, &fileInfo{name: rel, meta: m})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+var (
+	_ fs.StatFS    = (*FS)(nil)
+	_ fs.ReadDirFS = (*FS)(nil)
+	_ io.Seeker    = (*file)(nil)
+)
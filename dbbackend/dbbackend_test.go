@@ -0,0 +1,218 @@
+package dbbackend
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/goroute/static"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = map[string]string{
+	"index.html":     "<h1>Hello</h1>",
+	"app.js":         "console.log('app')",
+	"css/site.css":   "body {}",
+	"css/print.css":  "@media print {}",
+	"deep/a/b/c.txt": "c",
+	"100%_off.txt":   "sale",
+}
+
+var modTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fakeDriver is a database/sql driver answering the queries of Postgres
+// from testFiles, counting the queries reading content.
+type fakeDriver struct {
+	reads int
+}
+
+type (
+	fakeConn struct{ d *fakeDriver }
+	fakeStmt struct {
+		d     *fakeDriver
+		query string
+	}
+	fakeRows struct {
+		columns []string
+		values  [][]driver.Value
+	}
+)
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("unsupported") }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{}
+	switch {
+	case strings.HasPrefix(s.query, "SELECT octet_length(data), mod_time FROM \"files\" WHERE name = $1"):
+		rows.columns = []string{"octet_length", "mod_time"}
+		if data, ok := testFiles[args[0].(string)]; ok {
+			rows.values = append(rows.values, []driver.Value{int64(len(data)), modTime})
+		}
+	case strings.HasPrefix(s.query, "SELECT substring(data FROM $2 FOR $3) FROM \"files\""):
+		s.d.reads++
+		rows.columns = []string{"substring"}
+		if data, ok := testFiles[args[0].(string)]; ok {
+			from, n := int(args[1].(int64))-1, int(args[2].(int64))
+			if from+n > len(data) {
+				n = len(data) - from
+			}
+			rows.values = append(rows.values, []driver.Value{[]byte(data[from : from+n])})
+		}
+	case strings.HasPrefix(s.query, "SELECT name, octet_length(data), mod_time FROM \"files\" WHERE name LIKE $1 ESCAPE '\\' ORDER BY name"):
+		rows.columns = []string{"name", "octet_length", "mod_time"}
+		pattern := args[0].(string)
+		prefix := strings.NewReplacer(`\\`, `\`, `\%`, "%", `\_`, "_").Replace(strings.TrimSuffix(pattern, "%"))
+		var names []string
+		for name := range testFiles {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rows.values = append(rows.values, []driver.Value{name, int64(len(testFiles[name])), modTime})
+		}
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return rows, nil
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+var testDriver = &fakeDriver{}
+
+func init() {
+	sql.Register("dbbackendtest", testDriver)
+}
+
+func newTestFS(t *testing.T, options ...Option) *FS {
+	db, err := sql.Open("dbbackendtest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	testDriver.reads = 0
+	return New(NewPostgres(db, "files"), options...)
+}
+
+func TestFS(t *testing.T) {
+	fsys := newTestFS(t, ChunkSize(4))
+	if err := fstest.TestFS(fsys, "index.html", "app.js", "css/site.css", "deep/a/b/c.txt", "100%_off.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	assert := assert.New(t)
+	fi, err := fsys.Stat("css")
+	if assert.NoError(err) {
+		assert.True(fi.IsDir())
+	}
+	_, err = fsys.Stat("cs")
+	assert.True(errors.Is(err, fs.ErrNotExist))
+	entries, err := fsys.ReadDir(".")
+	if assert.NoError(err) {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.Equal([]string{"100%_off.txt", "app.js", "css", "deep", "index.html"}, names)
+	}
+}
+
+func TestFileRead(t *testing.T) {
+	fsys := newTestFS(t, ChunkSize(4))
+	assert := assert.New(t)
+	fi, err := fsys.Stat("app.js")
+	if assert.NoError(err) {
+		assert.Equal(int64(18), fi.Size())
+		assert.True(modTime.Equal(fi.ModTime()))
+	}
+	assert.Zero(testDriver.reads)
+
+	f, err := fsys.Open("app.js")
+	if !assert.NoError(err) {
+		return
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	assert.NoError(err)
+	assert.Equal("console.log('app')", string(b))
+	assert.Equal(5, testDriver.reads) // 18 bytes in chunks of 4
+
+	rs := f.(io.ReadSeeker)
+	rs.Seek(8, io.SeekStart)
+	buf := make([]byte, 3)
+	io.ReadFull(rs, buf)
+	assert.Equal("log", string(buf))
+	rs.Seek(-3, io.SeekEnd)
+	b, err = io.ReadAll(rs)
+	assert.NoError(err)
+	assert.Equal("p')", string(b))
+}
+
+func TestStatic(t *testing.T) {
+	fsys := newTestFS(t)
+	var _ static.BackendFS = fsys
+	mw := static.New(static.Backend(fsys), static.Browse(true))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("<h1>Hello</h1>", rec.Body.String())
+	}
+
+	// Conditional requests don't read content.
+	testDriver.reads = 0
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-Modified-Since", modTime.Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusNotModified, rec.Code)
+		assert.Zero(testDriver.reads)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/css/", nil)
+	req.Header.Set(route.HeaderAccept, route.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `"name":"print.css"`)
+		assert.Contains(rec.Body.String(), `"name":"site.css"`)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/missing.txt", nil)
+	rec = httptest.NewRecorder()
+	assert.Error(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+}
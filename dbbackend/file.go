@@ -0,0 +1,142 @@
+package dbbackend
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+type (
+	// fileInfo describes a file or a directory. It is also a directory
+	// entry.
+	fileInfo struct {
+		name string
+		meta Metadata
+		dir  bool
+	}
+
+	// file is an open file, read in chunks from the current offset.
+	file struct {
+		fs     *FS
+		info   *fileInfo
+		offset int64
+
+		// Chunk read at the offset, and its unread part.
+		chunk []byte
+		buf   []byte
+	}
+
+	// dir is an open directory.
+	dir struct {
+		fs      *FS
+		name    string
+		info    fs.FileInfo
+		entries []fs.DirEntry
+		read    bool
+	}
+)
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.meta.Size }
+func (fi *fileInfo) ModTime() time.Time { return fi.meta.ModTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ETag returns the entity tag of the file, if stored.
+func (fi *fileInfo) ETag() string { return fi.meta.ETag }
+
+func (fi *fileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.offset >= f.info.meta.Size {
+		return 0, io.EOF
+	}
+	if len(f.buf) == 0 {
+		if f.chunk == nil {
+			f.chunk = make([]byte, f.fs.chunkSize)
+		}
+		chunk := f.chunk
+		if rest := f.info.meta.Size - f.offset; rest < int64(len(chunk)) {
+			chunk = chunk[:rest]
+		}
+		n, err := f.fs.store.ReadAt(f.info.meta.Name, chunk, f.offset)
+		if n == 0 {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF // Truncated meanwhile
+			}
+			return 0, err
+		}
+		f.buf = chunk[:n]
+	}
+	n := copy(b, f.buf)
+	f.buf = f.buf[n:]
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.meta.Size
+	default:
+		return 0, errors.New("dbbackend: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("dbbackend: negative position")
+	}
+	if offset != f.offset {
+		f.buf = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Close() error { return nil }
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error { return nil }
+
+// ReadDir reads the next n entries of the directory, or all of them if
+// n <= 0.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
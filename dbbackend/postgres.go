@@ -0,0 +1,88 @@
+package dbbackend
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Postgres is a Store of the files of a PostgreSQL table, accessed with any
+// driver of database/sql, e.g.:
+//
+//	CREATE TABLE files (
+//		name     text PRIMARY KEY,
+//		data     bytea NOT NULL,
+//		mod_time timestamptz NOT NULL DEFAULT now()
+//	);
+//	ALTER TABLE files ALTER COLUMN data SET STORAGE EXTERNAL;
+//
+// With the external storage of the data column, chunks are read without
+// reading the content before them.
+type Postgres struct {
+	db *sql.DB
+
+	// Queries of the table.
+	stat, read, list string
+}
+
+// NewPostgres returns the store of the files of the table.
+func NewPostgres(db *sql.DB, table string) *Postgres {
+	t := `"` + strings.Replace(table, `"`, `""`, -1) + `"`
+	return &Postgres{
+		db:   db,
+		stat: "SELECT octet_length(data), mod_time FROM " + t + " WHERE name = $1",
+		read: "SELECT substring(data FROM $2 FOR $3) FROM " + t + " WHERE name = $1",
+		list: "SELECT name, octet_length(data), mod_time FROM " + t + ` WHERE name LIKE $1 ESCAPE '\' ORDER BY name`,
+	}
+}
+
+// Stat returns the metadata of the named file.
+func (p *Postgres) Stat(name string) (Metadata, error) {
+	m := Metadata{Name: name}
+	err := p.db.QueryRow(p.stat, name).Scan(&m.Size, &m.ModTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fs.ErrNotExist
+	}
+	return m, err
+}
+
+// ReadAt reads the content of the named file from the offset.
+func (p *Postgres) ReadAt(name string, b []byte, offset int64) (int, error) {
+	var data []byte
+	// Positions start at 1.
+	err := p.db.QueryRow(p.read, name, offset+1, len(b)).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fs.ErrNotExist
+	}
+	if err != nil {
+		return 0, err
+	}
+	n := copy(b, data)
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// List returns the metadata of the files whose names start with the prefix.
+func (p *Postgres) List(prefix string) ([]Metadata, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+	rows, err := p.db.Query(p.list, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var files []Metadata
+	for rows.Next() {
+		var m Metadata
+		if err := rows.Scan(&m.Name, &m.Size, &m.ModTime); err != nil {
+			return nil, err
+		}
+		files = append(files, m)
+	}
+	return files, rows.Err()
+}
+
+var _ Store = (*Postgres)(nil)
@@ -0,0 +1,119 @@
+package static
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// archiveFormats are the formats of directory downloads by the value of the
+// "archive" query parameter.
+var archiveFormats = map[string]struct {
+	ext, ctype string
+}{
+	"zip":    {".zip", "application/zip"},
+	"tar.gz": {".tar.gz", "application/gzip"},
+}
+
+// downloadDir streams the content of the named directory as an archive in
+// the format, "zip" or "tar.gz". Files which can't be served are left out.
+func (s *server) downloadDir(c route.Context, name, format string) (err error) {
+	f, ok := archiveFormats[format]
+	if !ok {
+		return route.NewHTTPError(http.StatusBadRequest, "unsupported archive format")
+	}
+	filename := path.Base(name)
+	if name == "." {
+		filename = "archive"
+	}
+
+	res := c.Response()
+	res.Header().Set(route.HeaderContentType, f.ctype)
+	res.Header().Set(route.HeaderContentDisposition, attachment(filename+f.ext))
+	res.WriteHeader(http.StatusOK)
+
+	var add func(rel string, fi fs.FileInfo, r io.Reader) error
+	var done func() error
+	if format == "zip" {
+		zw := zip.NewWriter(res)
+		add = func(rel string, fi fs.FileInfo, r io.Reader) error {
+			hdr, err := zip.FileInfoHeader(fi)
+			if err != nil {
+				return err
+			}
+			hdr.Name, hdr.Method = rel, zip.Deflate
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, r)
+			return err
+		}
+		done = zw.Close
+	} else {
+		zw := gzip.NewWriter(res)
+		tw := tar.NewWriter(zw)
+		add = func(rel string, fi fs.FileInfo, r io.Reader) error {
+			hdr, err := tar.FileInfoHeader(fi, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = rel
+			if err = tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, r)
+			return err
+		}
+		done = func() error {
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			return zw.Close()
+		}
+	}
+
+	ctx := c.Request().Context()
+	err = fs.WalkDir(s.backend(ctx), name, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == name {
+			return err
+		}
+		if !s.visible(p, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := s.stat(ctx, p)
+		if err != nil || !fi.Mode().IsRegular() {
+			return nil // Escaping symlink
+		}
+		if s.Authorize != nil && s.Authorize(c, "/"+p, fi) != nil {
+			return nil
+		}
+		file, err := s.open(ctx, p)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+		rel := strings.TrimPrefix(p, name+"/")
+		if name == "." {
+			rel = p
+		}
+		return add(rel, fi, file)
+	})
+	if err != nil {
+		return
+	}
+	return done()
+}
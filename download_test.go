@@ -0,0 +1,82 @@
+package static
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/a.txt":       {Data: []byte("a")},
+		"docs/sub/b.txt":   {Data: []byte("b")},
+		"docs/.secret":     {Data: []byte("hidden")},
+		"docs/private.key": {Data: []byte("key")},
+	}
+	mw := New(Filesystem(fsys), Browse(true), Exclude("*.key"))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	// Zip
+	req := httptest.NewRequest(http.MethodGet, "/docs/?archive=zip", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("application/zip", rec.Header().Get(route.HeaderContentType))
+		assert.Equal(`attachment; filename="docs.zip"`, rec.Header().Get(route.HeaderContentDisposition))
+		zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		if assert.NoError(err) {
+			files := map[string]string{}
+			for _, f := range zr.File {
+				r, _ := f.Open()
+				b, _ := io.ReadAll(r)
+				files[f.Name] = string(b)
+			}
+			assert.Equal(map[string]string{"a.txt": "a", "sub/b.txt": "b"}, files)
+		}
+	}
+
+	// tar.gz of the root
+	req = httptest.NewRequest(http.MethodGet, "/?archive=tar.gz", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("application/gzip", rec.Header().Get(route.HeaderContentType))
+		zr, err := gzip.NewReader(rec.Body)
+		if assert.NoError(err) {
+			var names []string
+			tr := tar.NewReader(zr)
+			for {
+				hdr, err := tr.Next()
+				if err != nil {
+					break
+				}
+				names = append(names, hdr.Name)
+			}
+			sort.Strings(names)
+			assert.Equal([]string{"docs/a.txt", "docs/sub/b.txt"}, names)
+		}
+	}
+
+	// Unsupported format
+	req = httptest.NewRequest(http.MethodGet, "/docs/?archive=rar", nil)
+	rec = httptest.NewRecorder()
+	err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	if assert.Error(err) {
+		assert.Equal(http.StatusBadRequest, err.(*route.HTTPError).Code)
+	}
+
+	// Browsing disabled
+	mw = New(Filesystem(fsys))
+	req = httptest.NewRequest(http.MethodGet, "/docs/?archive=zip", nil)
+	rec = httptest.NewRecorder()
+	assert.Error(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+}
@@ -0,0 +1,153 @@
+package static
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/goroute/route"
+)
+
+// Types of file events.
+const (
+	eventCreate = "create"
+	eventModify = "modify"
+	eventDelete = "delete"
+)
+
+// headerContentTypeEventStream is the MIME type of server-sent events.
+const headerContentTypeEventStream = "text/event-stream"
+
+// eventsBuffer is the number of events buffered for each client of an event
+// stream, which is closed when they don't keep up.
+const eventsBuffer = 64
+
+type (
+	// fileEvent is a change of a file, sent as JSON by the event stream.
+	fileEvent struct {
+		s    *server
+		Type string `json:"type"`
+		Path string `json:"path"`
+	}
+
+	// broadcaster sends the file events to the clients of the event streams.
+	broadcaster struct {
+		mu      sync.Mutex
+		clients map[chan fileEvent]struct{}
+		closed  bool
+	}
+)
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{clients: map[chan fileEvent]struct{}{}}
+}
+
+// publish sends the event to every client, dropping those which don't keep
+// up.
+func (b *broadcaster) publish(ev fileEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.clients {
+		select {
+		case ch <- ev:
+		default:
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the channel of the events of a new client, closed when it
+// is dropped, and the function unsubscribing it.
+func (b *broadcaster) subscribe() (<-chan fileEvent, func()) {
+	ch := make(chan fileEvent, eventsBuffer)
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.clients[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.clients[ch]; ok {
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// close drops every client, ending their streams, and those subscribing
+// afterwards.
+func (b *broadcaster) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.clients {
+		delete(b.clients, ch)
+		close(ch)
+	}
+}
+
+// stream sends the events of the channel to the client as server-sent events
+// with the data returned by data, skipping those for which it returns nil,
+// until the channel is closed or the client goes away.
+func stream(c route.Context, events <-chan fileEvent, data func(fileEvent) []byte) error {
+	res := c.Response()
+	res.Header().Set(route.HeaderContentType, headerContentTypeEventStream)
+	res.Header().Set(headerCacheControl, "no-cache")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+	done := c.Request().Context().Done()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			d := data(ev)
+			if d == nil {
+				continue
+			}
+			if _, err := res.Write([]byte("data: " + string(d) + "\n\n")); err != nil {
+				return nil
+			}
+			res.Flush()
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// eventVisible reports whether the file of the event is served, like
+// requests for it. Deleted files are only checked by name, as files.
+func (s *server) eventVisible(c route.Context, ev fileEvent) bool {
+	name := fsPath(ev.Path)
+	if ev.Type == eventDelete {
+		return s.visible(name, false)
+	}
+	_, err := s.stat(c.Request().Context(), name)
+	return err == nil
+}
+
+// serveEvents sends the events of the visible files of the server. Event
+// streams are authorized as listings.
+func (s *server) serveEvents(c route.Context, next route.HandlerFunc) error {
+	if ok, err := s.browseAllowed(c); err != nil {
+		return err
+	} else if !ok {
+		return next(c)
+	}
+	events, unsubscribe := s.changes.subscribe()
+	defer unsubscribe()
+	return stream(c, events, func(ev fileEvent) []byte {
+		if ev.s != s || !s.eventVisible(c, ev) {
+			return nil
+		}
+		data, _ := json.Marshal(ev)
+		return data
+	})
+}
@@ -0,0 +1,93 @@
+package static
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	dir := t.TempDir()
+	assert := assert.New(t)
+	mw := New(Root(dir), Events("/__events"), IgnoreHidden(true), Include("*.txt"))
+	mux := route.NewServeMux()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw(mux.NewContext(r, w), route.NotFoundHandler)
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/__events")
+	if !assert.NoError(err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.Equal(headerContentTypeEventStream, res.Header.Get(route.HeaderContentType))
+	r := bufio.NewReader(res.Body)
+	next := func() (ev fileEvent) {
+		for {
+			line, err := r.ReadString('\n')
+			if !assert.NoError(err) {
+				return
+			}
+			if strings.HasPrefix(line, "data: ") {
+				assert.NoError(json.Unmarshal([]byte(line[len("data: "):]), &ev))
+				return
+			}
+		}
+	}
+
+	name := filepath.Join(dir, "drop.txt")
+	assert.NoError(os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "excluded.js"), nil, 0644))
+	assert.NoError(os.WriteFile(name, nil, 0644))
+	assert.Equal(fileEvent{Type: eventCreate, Path: "/drop.txt"}, next())
+	assert.NoError(os.WriteFile(name, []byte("content"), 0644))
+	assert.Equal(fileEvent{Type: eventModify, Path: "/drop.txt"}, next())
+	assert.NoError(os.Remove(name))
+	assert.Equal(fileEvent{Type: eventDelete, Path: "/drop.txt"}, next())
+
+	mw = New(Root(dir), Events("/__events"), BrowseAuth(func(route.Context) (bool, error) {
+		return false, nil
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/__events", nil)
+	rec := httptest.NewRecorder()
+	err = mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	if he, ok := err.(*route.HTTPError); assert.True(ok) {
+		assert.Equal(http.StatusNotFound, he.Code)
+	}
+}
+
+func TestHandleClose(t *testing.T) {
+	assert := assert.New(t)
+	mw, h := NewHandle(Root(t.TempDir()), Events("/__events"))
+	mux := route.NewServeMux()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw(mux.NewContext(r, w), route.NotFoundHandler)
+	}))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/__events")
+	if !assert.NoError(err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.NoError(h.Close())
+
+	// The watcher is stopped and the event streams end.
+	select {
+	case <-h.s.watcher.(*watcher).done:
+	default:
+		assert.Fail("watcher not closed")
+	}
+	_, err = io.ReadAll(res.Body)
+	assert.NoError(err)
+}
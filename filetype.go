@@ -0,0 +1,137 @@
+package static
+
+import (
+	"html/template"
+	"path"
+	"strings"
+)
+
+// File kinds of directory entries, see DirEntry.Kind.
+const (
+	KindDirectory = "directory"
+	KindImage     = "image"
+	KindVideo     = "video"
+	KindAudio     = "audio"
+	KindArchive   = "archive"
+	KindCode      = "code"
+	KindDocument  = "document"
+	KindText      = "text"
+	KindFont      = "font"
+	KindFile      = "file"
+)
+
+// fileKinds are the kinds of files by lower case extension.
+var fileKinds = map[string]string{}
+
+func init() {
+	for kind, exts := range map[string][]string{
+		KindImage:    {".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg", ".ico", ".bmp", ".tif", ".tiff"},
+		KindVideo:    {".mp4", ".webm", ".mkv", ".mov", ".avi", ".m4v"},
+		KindAudio:    {".mp3", ".ogg", ".wav", ".flac", ".m4a", ".aac", ".opus"},
+		KindArchive:  {".zip", ".tar", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".rar", ".zst"},
+		KindCode:     {".go", ".js", ".mjs", ".ts", ".jsx", ".tsx", ".css", ".html", ".htm", ".json", ".xml", ".yaml", ".yml", ".toml", ".sh", ".py", ".rb", ".java", ".c", ".h", ".cpp", ".rs", ".php", ".wasm", ".map"},
+		KindDocument: {".pdf", ".doc", ".docx", ".odt", ".rtf", ".xls", ".xlsx", ".ods", ".ppt", ".pptx", ".odp", ".epub"},
+		KindText:     {".txt", ".md", ".markdown", ".csv", ".log", ".ini", ".conf"},
+		KindFont:     {".woff", ".woff2", ".ttf", ".otf", ".eot"},
+	} {
+		for _, ext := range exts {
+			fileKinds[ext] = kind
+		}
+	}
+}
+
+// typeNames are the human friendly types of files by lower case extension,
+// overriding the default "<EXT> <kind>".
+var typeNames = map[string]string{
+	".css":      "CSS stylesheet",
+	".csv":      "CSV table",
+	".go":       "Go source",
+	".htm":      "HTML document",
+	".html":     "HTML document",
+	".js":       "JavaScript",
+	".json":     "JSON",
+	".jsx":      "JavaScript (JSX)",
+	".markdown": "Markdown",
+	".md":       "Markdown",
+	".mjs":      "JavaScript module",
+	".pdf":      "PDF document",
+	".py":       "Python source",
+	".sh":       "Shell script",
+	".svg":      "SVG image",
+	".ts":       "TypeScript",
+	".tsx":      "TypeScript (TSX)",
+	".txt":      "Plain text",
+	".wasm":     "WebAssembly",
+	".xml":      "XML document",
+	".yaml":     "YAML",
+	".yml":      "YAML",
+}
+
+// kindColors are the colors of the icons of each kind.
+var kindColors = map[string]string{
+	KindDirectory: "#E91E63",
+	KindImage:     "#4CAF50",
+	KindVideo:     "#F44336",
+	KindAudio:     "#FF9800",
+	KindArchive:   "#795548",
+	KindCode:      "#3F51B5",
+	KindDocument:  "#009688",
+	KindText:      "#607D8B",
+	KindFont:      "#9C27B0",
+	KindFile:      "#673AB7",
+}
+
+// FileKind returns the kind of file of the name by its extension, e.g.
+// KindImage for "logo.png", or KindFile if unknown.
+func FileKind(name string) string {
+	if kind, ok := fileKinds[strings.ToLower(path.Ext(name))]; ok {
+		return kind
+	}
+	return KindFile
+}
+
+// FileType returns the human friendly type of the file name, e.g. "PNG image"
+// for "logo.png".
+func FileType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := typeNames[ext]; ok {
+		return t
+	}
+	if ext == "" {
+		return "File"
+	}
+	noun := FileKind(name)
+	switch noun {
+	case KindCode, KindText, KindDocument, KindFont:
+		noun = KindFile
+	}
+	return strings.ToUpper(ext[1:]) + " " + noun
+}
+
+// Kind returns the kind of the entry, KindDirectory or the FileKind of its
+// name.
+func (e DirEntry) Kind() string {
+	if e.Dir {
+		return KindDirectory
+	}
+	return FileKind(e.Name)
+}
+
+// Type returns the human friendly type of the entry, "Folder" or the FileType
+// of its name.
+func (e DirEntry) Type() string {
+	if e.Dir {
+		return "Folder"
+	}
+	return FileType(e.Name)
+}
+
+// Icon returns an inline SVG icon of the kind of the entry.
+func (e DirEntry) Icon() template.HTML {
+	shape := "M3 1h7l3 3v11H3z"
+	if e.Dir {
+		shape = "M1 3h5l2 2h7v8H1z"
+	}
+	return template.HTML(`<svg class="icon" viewBox="0 0 16 16" width="16" height="16" aria-hidden="true"><path fill="` +
+		kindColors[e.Kind()] + `" d="` + shape + `"/></svg>`)
+}
@@ -0,0 +1,147 @@
+package static
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fingerprintLength is the number of hex digits of the content digest in
+// fingerprinted file names.
+const fingerprintLength = 8
+
+// fingerprintCacheControl is the Cache-Control header of fingerprinted files,
+// whose content never changes.
+const fingerprintCacheControl = "public, max-age=31536000, immutable"
+
+type (
+	// digests memoizes the fingerprints of files until they change.
+	digests struct {
+		mu      sync.Mutex
+		entries map[string]digestEntry
+	}
+
+	digestEntry struct {
+		modTime     time.Time
+		size        int64
+		fingerprint string
+	}
+)
+
+// fingerprint returns the fingerprint of the named file, the first hex digits
+// of the SHA-256 digest of its content.
+func (s *server) fingerprint(ctx context.Context, name string, fi fs.FileInfo) (string, error) {
+	s.digests.mu.Lock()
+	e, ok := s.digests.entries[name]
+	s.digests.mu.Unlock()
+	if ok && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() {
+		return e.fingerprint, nil
+	}
+
+	f, err := s.open(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	e = digestEntry{fi.ModTime(), fi.Size(), hex.EncodeToString(h.Sum(nil))[:fingerprintLength]}
+
+	s.digests.mu.Lock()
+	if s.digests.entries == nil {
+		s.digests.entries = map[string]digestEntry{}
+	}
+	s.digests.entries[name] = e
+	s.digests.mu.Unlock()
+	return e.fingerprint, nil
+}
+
+// fingerprinted returns the name of a file with its fingerprint inserted
+// before the extension, e.g. "app.3f9a2b1c.js".
+func fingerprinted(name, fingerprint string) string {
+	ext := path.Ext(path.Base(name))
+	return strings.TrimSuffix(name, ext) + "." + fingerprint + ext
+}
+
+// unfingerprinted splits a fingerprinted file name into the original name and
+// the fingerprint. It returns ok false if the name isn't fingerprinted.
+func unfingerprinted(name string) (original, fingerprint string, ok bool) {
+	ext := path.Ext(path.Base(name))
+	stem := strings.TrimSuffix(name, ext)
+	if fp := path.Ext(path.Base(stem)); isFingerprint(fp) {
+		return strings.TrimSuffix(stem, fp) + ext, fp[1:], true
+	}
+	if isFingerprint(ext) {
+		return stem, ext[1:], true
+	}
+	return "", "", false
+}
+
+// isFingerprint reports whether ext is a dot followed by a fingerprint.
+func isFingerprint(ext string) bool {
+	if len(ext) != fingerprintLength+1 {
+		return false
+	}
+	_, err := hex.DecodeString(ext[1:])
+	return err == nil && strings.ToLower(ext) == ext
+}
+
+// resolveFingerprint returns the file named by the fingerprinted name if its
+// content matches the fingerprint.
+func (s *server) resolveFingerprint(ctx context.Context, name string) (string, fs.FileInfo, bool) {
+	original, fp, ok := unfingerprinted(name)
+	if !ok {
+		return "", nil, false
+	}
+	fi, err := s.stat(ctx, original)
+	if err != nil || fi.IsDir() {
+		return "", nil, false
+	}
+	if actual, err := s.fingerprint(ctx, original, fi); err != nil || actual != fp {
+		return "", nil, false
+	}
+	return original, fi, true
+}
+
+// Manifest returns the fingerprinted URL path of every file served, by URL
+// path, e.g. "/app.js": "/app.3f9a2b1c.js".
+func (h *Handle) Manifest() (map[string]string, error) {
+	s, ctx := h.s, context.Background()
+	manifest := map[string]string{}
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if !s.visible(name, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := s.stat(ctx, name)
+		if err != nil {
+			return nil // Escaping symlink
+		}
+		fp, err := s.fingerprint(ctx, name, fi)
+		if err != nil {
+			return err
+		}
+		manifest["/"+name] = "/" + fingerprinted(name, fp)
+		return nil
+	})
+	return manifest, err
+}
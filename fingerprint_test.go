@@ -0,0 +1,99 @@
+package static
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintCache(t *testing.T) {
+	sum := sha256.Sum256([]byte("app"))
+	fp := hex.EncodeToString(sum[:])[:8]
+	fsys := fstest.MapFS{"js/app.js": {Data: []byte("app")}}
+	mw := New(Filesystem(fsys), Fingerprint(true), Cache(1<<20, 0, 0))
+
+	assert := assert.New(t)
+	for i := 0; i < 2; i++ {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/js/app."+fp+".js", nil)
+		rec := httptest.NewRecorder()
+		assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+		assert.Equal("app", rec.Body.String())
+		assert.Equal(fingerprintCacheControl, rec.Header().Get("Cache-Control"), i)
+	}
+
+	// Not for the original file, cached too.
+	for i := 0; i < 2; i++ {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/js/app.js", nil)
+		rec := httptest.NewRecorder()
+		assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+		assert.Empty(rec.Header().Get("Cache-Control"), i)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	sum := sha256.Sum256([]byte("app"))
+	fp := hex.EncodeToString(sum[:])[:8]
+	fsys := fstest.MapFS{
+		"js/app.js": {Data: []byte("app")},
+		"LICENSE":   {Data: []byte("app")},
+		".env":      {Data: []byte("secret")},
+	}
+	mw, h := NewHandle(Filesystem(fsys), Fingerprint(true))
+
+	assert := assert.New(t)
+	manifest, err := h.Manifest()
+	if assert.NoError(err) {
+		assert.Equal(map[string]string{
+			"/js/app.js": "/js/app." + fp + ".js",
+			"/LICENSE":   "/LICENSE." + fp,
+		}, manifest)
+	}
+
+	for path, want := range map[string]int{
+		"/js/app." + fp + ".js": http.StatusOK,
+		"/LICENSE." + fp:        http.StatusOK,
+		"/js/app.00000000.js":   http.StatusNotFound,
+		"/js/app.js":            http.StatusOK,
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+		if want == http.StatusNotFound {
+			if assert.Error(err, path) {
+				assert.Equal(want, err.(*route.HTTPError).Code, path)
+			}
+			continue
+		}
+		if assert.NoError(err, path) {
+			assert.Equal("app", rec.Body.String(), path)
+			if path != "/js/app.js" {
+				assert.Contains(rec.Header().Get("Cache-Control"), "immutable", path)
+			}
+		}
+	}
+}
+
+func TestUnfingerprinted(t *testing.T) {
+	assert := assert.New(t)
+	for name, want := range map[string][2]string{
+		"app.3f9a2b1c.js":     {"app.js", "3f9a2b1c"},
+		"a.b/app.3f9a2b1c":    {"a.b/app", "3f9a2b1c"},
+		"app.min.3f9a2b1c.js": {"app.min.js", "3f9a2b1c"},
+		"app.3F9A2B1C.js":     {"", ""},
+		"app.3f9a2b.js":       {"", ""},
+		"app.js":              {"", ""},
+	} {
+		original, fp, ok := unfingerprinted(name)
+		assert.Equal(want[0] != "", ok, name)
+		assert.Equal(want, [2]string{original, fp}, name)
+	}
+}
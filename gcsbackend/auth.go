@@ -0,0 +1,143 @@
+package gcsbackend
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readOnlyScope is the OAuth 2.0 scope of the access tokens.
+const readOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+
+// serviceAccountKey is the JSON key of a service account.
+type serviceAccountKey struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// ServiceAccount authorizes requests with access tokens of the service
+// account of the JSON key, signing assertions with its private key. Tokens
+// are renewed a minute before they expire.
+func ServiceAccount(jsonKey []byte) Option {
+	return func(f *FS) {
+		f.token = serviceAccountToken(jsonKey, f)
+	}
+}
+
+// serviceAccountToken returns the source of the access tokens of the service
+// account of the JSON key, requested with the client of f.
+func serviceAccountToken(jsonKey []byte, f *FS) TokenSource {
+	var key serviceAccountKey
+	if err := json.Unmarshal(jsonKey, &key); err != nil {
+		return func() (string, error) { return "", fmt.Errorf("gcsbackend: service account key: %v", err) }
+	}
+	signer, err := parsePrivateKey(key.PrivateKey)
+	if err != nil {
+		return func() (string, error) { return "", fmt.Errorf("gcsbackend: service account key: %v", err) }
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	var (
+		mu      sync.Mutex
+		token   string
+		expires time.Time
+	)
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		if token != "" && now.Before(expires) {
+			return token, nil
+		}
+		assertion, err := signJWT(signer, key.PrivateKeyID, map[string]interface{}{
+			"iss":   key.ClientEmail,
+			"scope": readOnlyScope,
+			"aud":   key.TokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return "", err
+		}
+		res, err := f.client.PostForm(key.TokenURI, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("gcsbackend: token request: %s", res.Status)
+		}
+		var t struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int64  `json:"expires_in"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&t); err != nil {
+			return "", err
+		}
+		token, expires = t.AccessToken, now.Add(time.Duration(t.ExpiresIn)*time.Second-time.Minute)
+		return token, nil
+	}
+}
+
+// parsePrivateKey parses the PEM encoded PKCS #8 or PKCS #1 RSA private key.
+func parsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key isn't an RSA key")
+	}
+	return key, nil
+}
+
+// signJWT returns the JSON Web Token of the claims signed with RS256.
+func signJWT(key *rsa.PrivateKey, keyID string, claims map[string]interface{}) (string, error) {
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+	var parts []string
+	for _, v := range []interface{}{header, claims} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, base64.RawURLEncoding.EncodeToString(b))
+	}
+	signed := strings.Join(parts, ".")
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,301 @@
+// Package gcsbackend serves the objects of a Google Cloud Storage bucket as a
+// file system for the Static middleware.
+//
+//	mux.Use(static.New(static.Backend(gcsbackend.New("my-bucket"))))
+//
+// Object names are file paths, their slashes separating directories listed
+// from prefixes. Files are read with ranged requests of the generation of
+// their objects, which is also their entity tag, so that a response never
+// mixes the content of two versions of an object.
+package gcsbackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// FS is a file system of the objects of a GCS bucket. It implements
+	// fs.StatFS and fs.ReadDirFS.
+	FS struct {
+		bucket   string
+		prefix   string
+		endpoint string
+		token    TokenSource
+		client   *http.Client
+	}
+
+	// Option configures an FS.
+	Option func(*FS)
+
+	// TokenSource returns the OAuth 2.0 access token authorizing requests.
+	TokenSource func() (string, error)
+)
+
+// New returns the file system of the bucket. Requests are authorized with the
+// service account key of the GOOGLE_APPLICATION_CREDENTIALS environment
+// variable if it is set, and anonymous otherwise, e.g. for public buckets.
+func New(bucket string, options ...Option) *FS {
+	f := &FS{
+		bucket:   bucket,
+		endpoint: "https://storage.googleapis.com",
+		client:   http.DefaultClient,
+	}
+	if file := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); file != "" {
+		key, err := os.ReadFile(file)
+		if err == nil {
+			ServiceAccount(key)(f)
+		} else {
+			f.token = func() (string, error) { return "", err }
+		}
+	}
+	for _, o := range options {
+		o(f)
+	}
+	return f
+}
+
+// Endpoint sets the URL of the JSON API, e.g. "http://localhost:4443" for an
+// emulator.
+// Default value "https://storage.googleapis.com".
+func Endpoint(endpoint string) Option {
+	return func(f *FS) {
+		f.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// Prefix sets the name prefix of the root directory, e.g. "public/".
+func Prefix(prefix string) Option {
+	return func(f *FS) {
+		f.prefix = strings.Trim(prefix, "/")
+	}
+}
+
+// Token sets the source of the access tokens authorizing requests, e.g. of a
+// metadata server. A nil source sends anonymous requests.
+func Token(source TokenSource) Option {
+	return func(f *FS) {
+		f.token = source
+	}
+}
+
+// HTTPClient sets the client sending requests.
+// Default value http.DefaultClient.
+func HTTPClient(client *http.Client) Option {
+	return func(f *FS) {
+		f.client = client
+	}
+}
+
+// Open opens the named file or directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	fi, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &dir{fs: f, name: name, info: fi}, nil
+	}
+	return &object{fs: f, object: f.object(name), info: fi.(*fileInfo)}, nil
+}
+
+// Stat returns the FileInfo of the named file or directory.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *FS) stat(op, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+
+	res, err := f.do(f.object(name), nil, nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		var o objectResource
+		if err := json.NewDecoder(res.Body).Decode(&o); err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		return o.info(path.Base(name)), nil
+	case http.StatusNotFound:
+	default:
+		return nil, &fs.PathError{Op: op, Path: name, Err: statusError(res)}
+	}
+
+	// Directories are the prefixes of names.
+	l, err := f.list(f.object(name)+"/", "", 1)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(l.Items) == 0 && len(l.Prefixes) == 0 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := f.object(name) + "/"
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	var entries []fs.DirEntry
+	found := name == "."
+	token := ""
+	for {
+		l, err := f.list(prefix, token, 0)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for _, p := range l.Prefixes {
+			entries = append(entries, &fileInfo{name: strings.TrimSuffix(p[len(prefix):], "/"), dir: true})
+		}
+		for _, o := range l.Items {
+			found = true
+			if o.Name == prefix { // Directory placeholder
+				continue
+			}
+			entries = append(entries, o.info(o.Name[len(prefix):]))
+		}
+		found = found || len(l.Prefixes) > 0
+		if l.NextPageToken == "" {
+			break
+		}
+		token = l.NextPageToken
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// object returns the object name of the named file.
+func (f *FS) object(name string) string {
+	if name == "." {
+		return f.prefix
+	}
+	if f.prefix == "" {
+		return name
+	}
+	return f.prefix + "/" + name
+}
+
+type (
+	// objectResource is the metadata of an object.
+	objectResource struct {
+		Name       string    `json:"name"`
+		Size       int64     `json:"size,string"`
+		Updated    time.Time `json:"updated"`
+		Generation int64     `json:"generation,string"`
+	}
+
+	// listResult is the result of an objects list request.
+	listResult struct {
+		Items         []objectResource `json:"items"`
+		Prefixes      []string         `json:"prefixes"`
+		NextPageToken string           `json:"nextPageToken"`
+	}
+)
+
+// info returns the FileInfo of the object, named name.
+func (o *objectResource) info(name string) *fileInfo {
+	return &fileInfo{name: name, size: o.Size, modTime: o.Updated, generation: o.Generation}
+}
+
+// list lists the objects whose names start with prefix, grouping the objects
+// of subdirectories into prefixes.
+func (f *FS) list(prefix, token string, maxResults int) (*listResult, error) {
+	query := url.Values{"delimiter": {"/"}, "prefix": {prefix}}
+	if token != "" {
+		query.Set("pageToken", token)
+	}
+	if maxResults > 0 {
+		query.Set("maxResults", strconv.Itoa(maxResults))
+	}
+	res, err := f.do("", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+	l := new(listResult)
+	if err = json.NewDecoder(res.Body).Decode(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// do sends a GET request for the named object, or the objects of the bucket
+// if it is empty.
+func (f *FS) do(object string, query url.Values, header http.Header) (*http.Response, error) {
+	u := f.url(object)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if f.token != nil {
+		token, err := f.token()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return f.client.Do(req)
+}
+
+// url returns the URL of the named object, or of the objects of the bucket if
+// it is empty.
+func (f *FS) url(object string) string {
+	u := f.endpoint + "/storage/v1/b/" + url.PathEscape(f.bucket) + "/o"
+	if object != "" {
+		u += "/" + url.PathEscape(object) // Slashes of names are escaped too.
+	}
+	return u
+}
+
+// statusError returns the error of an unexpected response.
+func statusError(res *http.Response) error {
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		return fs.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fs.ErrPermission
+	}
+	return fmt.Errorf("gcsbackend: %s %s: %s", res.Request.Method, res.Request.URL.Path, res.Status)
+}
+
+var (
+	_ fs.StatFS    = (*FS)(nil)
+	_ fs.ReadDirFS = (*FS)(nil)
+	_ io.Seeker    = (*object)(nil)
+)
@@ -0,0 +1,236 @@
+package gcsbackend
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/goroute/static"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGCS serves the objects of a bucket, listing at most two names per page,
+// to requests authorized with the access tokens of the service account key.
+// Objects have the generation of their size.
+func fakeGCS(t *testing.T, bucket string, objects map[string]string, key *rsa.PrivateKey, tokens *int32) *httptest.Server {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	resource := func(name string) objectResource {
+		data := objects[name]
+		return objectResource{name, int64(len(data)), modTime, int64(len(data))}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			parts := strings.Split(r.FormValue("assertion"), ".")
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[len(parts)-1])
+			sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			atomic.AddInt32(tokens, 1)
+			w.Write([]byte(`{"access_token": "TOKEN", "expires_in": 3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer TOKEN" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		base := "/storage/v1/b/" + bucket + "/o"
+		if r.URL.Path == base {
+			q := r.URL.Query()
+			prefix, token := q.Get("prefix"), q.Get("pageToken")
+			maxResults, _ := strconv.Atoi(q.Get("maxResults"))
+			if maxResults == 0 || maxResults > 2 {
+				maxResults = 2
+			}
+			var names []string
+			seen := map[string]bool{}
+			for name := range objects {
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				if i := strings.Index(name[len(prefix):], "/"); i >= 0 {
+					name = name[:len(prefix)+i+1]
+				}
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			var l listResult
+			for _, name := range names {
+				if name <= token {
+					continue
+				}
+				if len(l.Items)+len(l.Prefixes) == maxResults {
+					l.NextPageToken = token
+					break
+				}
+				token = name
+				if strings.HasSuffix(name, "/") && name != prefix {
+					l.Prefixes = append(l.Prefixes, name)
+					continue
+				}
+				l.Items = append(l.Items, resource(name))
+			}
+			json.NewEncoder(w).Encode(l)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, base+"/")
+		data, ok := objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("alt") != "media" {
+			json.NewEncoder(w).Encode(resource(name))
+			return
+		}
+		if r.URL.Query().Get("generation") != strconv.Itoa(len(data)) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, name, modTime, strings.NewReader(data))
+	}))
+}
+
+func newTestFS(t *testing.T, options ...Option) (*FS, *int32, func()) {
+	objects := map[string]string{
+		"public/index.html":     "<h1>Hello</h1>",
+		"public/app.js":         "console.log('app')",
+		"public/css/site.css":   "body {}",
+		"public/css/print.css":  "@media print {}",
+		"public/img/":           "",
+		"public/img/logo.svg":   "<svg/>",
+		"public/deep/a/b/c.txt": "c",
+		"private/secret.txt":    "secret",
+		"public/space name.txt": "space",
+		"public/50%/off?.txt":   "sale",
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens int32
+	srv := fakeGCS(t, "bucket", objects, key, &tokens)
+	jsonKey, _ := json.Marshal(serviceAccountKey{
+		ClientEmail: "static@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		TokenURI:    srv.URL + "/token",
+	})
+	options = append([]Option{
+		Endpoint(srv.URL),
+		ServiceAccount(jsonKey),
+		Prefix("public/"),
+	}, options...)
+	return New("bucket", options...), &tokens, srv.Close
+}
+
+func TestFS(t *testing.T) {
+	fsys, tokens, done := newTestFS(t)
+	defer done()
+
+	if err := fstest.TestFS(fsys, "index.html", "app.js", "css/site.css", "img/logo.svg", "deep/a/b/c.txt", "space name.txt", "50%/off?.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	assert := assert.New(t)
+	fi, err := fsys.Stat("css")
+	if assert.NoError(err) {
+		assert.True(fi.IsDir())
+	}
+	_, err = fsys.Stat("secret.txt")
+	assert.Error(err)
+	entries, err := fsys.ReadDir(".")
+	if assert.NoError(err) {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.Equal([]string{"50%", "app.js", "css", "deep", "img", "index.html", "space name.txt"}, names)
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(tokens))
+}
+
+func TestObjectSeek(t *testing.T) {
+	fsys, _, done := newTestFS(t)
+	defer done()
+
+	assert := assert.New(t)
+	f, err := fsys.Open("index.html")
+	if !assert.NoError(err) {
+		return
+	}
+	defer f.Close()
+	rs := f.(io.ReadSeeker)
+	buf := make([]byte, 5)
+	rs.Seek(4, io.SeekStart)
+	io.ReadFull(rs, buf)
+	assert.Equal("Hello", string(buf))
+	rs.Seek(-5, io.SeekEnd)
+	b, err := io.ReadAll(rs)
+	assert.NoError(err)
+	assert.Equal("</h1>", string(b))
+}
+
+func TestStatic(t *testing.T) {
+	fsys, _, done := newTestFS(t)
+	defer done()
+
+	var _ static.BackendFS = fsys
+	mw := static.New(static.Backend(fsys), static.Browse(true))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("<h1>Hello</h1>", rec.Body.String())
+		assert.Equal(`"14"`, rec.Header().Get("ETag"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("Range", "bytes=8-10")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusPartialContent, rec.Code)
+		assert.Equal("log", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/css/", nil)
+	req.Header.Set(route.HeaderAccept, route.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `"name":"print.css"`)
+		assert.Contains(rec.Body.String(), `"name":"site.css"`)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/missing.txt", nil)
+	rec = httptest.NewRecorder()
+	assert.Error(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+}
+
+func TestAnonymous(t *testing.T) {
+	fsys, _, done := newTestFS(t, Token(nil))
+	defer done()
+
+	_, err := fsys.Stat("index.html")
+	assert.Error(t, err)
+}
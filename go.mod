@@ -1,9 +1,23 @@
 module github.com/goroute/static
 
-go 1.12
+go 1.19
 
 require (
+	github.com/andybalholm/brotli v1.1.0
+	github.com/bmatcuk/doublestar/v4 v4.8.1
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/goroute/route v0.0.0-20190718071306-63785885e8a5
-	github.com/stretchr/objx v0.2.0 // indirect
-	github.com/stretchr/testify v1.3.0
+	github.com/stretchr/testify v1.4.0
+	github.com/yuin/goldmark v1.4.12
+	golang.org/x/net v0.11.0
+	golang.org/x/text v0.10.0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.9.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 )
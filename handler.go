@@ -0,0 +1,29 @@
+package static
+
+import (
+	"net/http"
+
+	"github.com/goroute/route"
+)
+
+// Handler returns an http.Handler serving the files like the Static
+// middleware, for use with http.ServeMux or other routers, e.g.
+// `http.Handle("/assets/", static.Handler(static.Root("public"), static.StripPrefix("/assets")))`.
+// Errors are answered with their status text. It panics if the options are
+// invalid, like New.
+func Handler(options ...Option) http.Handler {
+	mux := route.NewServeMux(route.WithHTTPErrorHandler(handlerError))
+	mux.Use(New(options...))
+	return mux
+}
+
+// handlerError answers the request with the status of the error.
+func handlerError(err error, c route.Context) {
+	code := http.StatusInternalServerError
+	if he, ok := err.(*route.HTTPError); ok {
+		code = he.Code
+	}
+	if !c.Response().Committed {
+		http.Error(c.Response(), http.StatusText(code), code)
+	}
+}
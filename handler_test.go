@@ -0,0 +1,33 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/assets/", Handler(Root("testdata"), Browse(true), StripPrefix("/assets")))
+
+	assert := assert.New(t)
+	for target, want := range map[string]int{
+		"/assets/":                 http.StatusOK,
+		"/assets/browse/":          http.StatusOK,
+		"/assets/browse/file1.txt": http.StatusOK,
+		"/assets/missing.txt":      http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		assert.Equal(want, rec.Code, target)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/missing.txt", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal("Not Found\n", rec.Body.String())
+	assert.Equal("text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+}
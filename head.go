@@ -0,0 +1,95 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/goroute/route"
+)
+
+// serveHead answers a HEAD request for the named file from its metadata,
+// without opening it. It returns false when the headers depend on the
+// content of the file, i.e. when its type is sniffed, it is compressed on
+// the fly or it has precompressed variants.
+func (s *server) serveHead(c route.Context, name string, fi fs.FileInfo) (bool, error) {
+	if s.mimeType(name) == "" && !s.NoSniff {
+		return false, nil
+	}
+	ctype := s.contentType(name, nil) // Not sniffed.
+	sfi, encoding := s.statPrecompressed(c, name)
+	if encoding == "" && s.Compress && s.compressible(ctype, fi.Size()) {
+		return false, nil
+	}
+	if encoding == "" && s.precompressed != nil && s.precompressed.lookup(name, fi) != nil {
+		return false, nil
+	}
+	header := c.Response().Header()
+	if s.Precompressed || s.precompressed != nil {
+		header.Add(route.HeaderVary, route.HeaderAcceptEncoding)
+	}
+
+	if s.OnServe != nil {
+		c.Set(servedFileKey, "/"+name)
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(c, "/"+name, fi); err != nil {
+			return true, err
+		}
+	}
+	if encoding != "" {
+		header.Set(route.HeaderContentEncoding, encoding)
+		fi = sfi
+	}
+	header.Set(route.HeaderContentType, ctype)
+	s.setHeaders(c, name, etag(fi))
+	s.serveContent(c, c.Request(), fi.Name(), fi.ModTime(), &headContent{size: fi.Size()})
+	return true, nil
+}
+
+// statPrecompressed returns the FileInfo of the preferred sidecar of the
+// named file accepted by the client, and its encoding. It returns an empty
+// encoding if there is none.
+func (s *server) statPrecompressed(c route.Context, name string) (fs.FileInfo, string) {
+	if !s.Precompressed {
+		return nil, ""
+	}
+	accept := c.Request().Header.Get(route.HeaderAcceptEncoding)
+	for _, pe := range precompressedEncodings {
+		if !acceptsEncoding(accept, pe.encoding) || s.escapes(name+pe.ext) {
+			continue
+		}
+		if fi, err := s.fsys.Stat(name + pe.ext); err == nil && fi.Mode().IsRegular() {
+			return fi, pe.encoding
+		}
+	}
+	return nil, ""
+}
+
+// errHeadContent is returned when reading a headContent.
+var errHeadContent = errors.New("static: content of a HEAD response read")
+
+// headContent is the content of a HEAD response, of which http.ServeContent
+// only needs the size.
+type headContent struct {
+	size   int64
+	offset int64
+}
+
+func (h *headContent) Read(p []byte) (int, error) {
+	return 0, errHeadContent
+}
+
+func (h *headContent) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += h.offset
+	case io.SeekEnd:
+		offset += h.size
+	}
+	if offset < 0 {
+		return 0, errors.New("static: negative position")
+	}
+	h.offset = offset
+	return offset, nil
+}
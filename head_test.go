@@ -0,0 +1,92 @@
+package static
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// openCounter counts the files opened, other than directories. Stat doesn't
+// open them.
+type openCounter struct {
+	fs.FS
+	opened int
+}
+
+func (o *openCounter) Open(name string) (fs.File, error) {
+	f, err := o.FS.Open(name)
+	if err == nil {
+		if fi, err := f.Stat(); err == nil && !fi.IsDir() {
+			o.opened++
+		}
+	}
+	return f, err
+}
+
+func (o *openCounter) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(o.FS, name)
+}
+
+func TestStaticHead(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := &openCounter{FS: fstest.MapFS{
+		"app.js":    {Data: []byte("console.log(1)"), ModTime: modTime},
+		"app.js.br": {Data: []byte("br"), ModTime: modTime},
+		"page":      {Data: []byte("<html></html>")},
+		"docs/a.md": {Data: []byte("# A")},
+	}}
+	mw := New(Filesystem(fsys), Precompressed(true), Browse(true), BrowseReadme(true))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodHead, "/app.js", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("14", rec.Header().Get(route.HeaderContentLength))
+		assert.Equal("text/javascript; charset=utf-8", rec.Header().Get(route.HeaderContentType))
+		assert.Equal(modTime.Format(http.TimeFormat), rec.Header().Get(route.HeaderLastModified))
+		assert.NotEmpty(rec.Header().Get(headerETag))
+		assert.Empty(rec.Body.String())
+	}
+
+	// Precompressed
+	req = httptest.NewRequest(http.MethodHead, "/app.js", nil)
+	req.Header.Set(route.HeaderAcceptEncoding, "br")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("br", rec.Header().Get(route.HeaderContentEncoding))
+	}
+
+	// Range
+	req = httptest.NewRequest(http.MethodHead, "/app.js", nil)
+	req.Header.Set(headerRange, "bytes=0-3")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusPartialContent, rec.Code)
+		assert.Equal("4", rec.Header().Get(route.HeaderContentLength))
+	}
+
+	// Listing
+	req = httptest.NewRequest(http.MethodHead, "/docs/", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(route.MIMETextHTMLCharsetUTF8, rec.Header().Get(route.HeaderContentType))
+		assert.Equal("1", rec.Header().Get(headerTotalCount))
+	}
+	assert.Equal(0, fsys.opened)
+
+	// Sniffed
+	req = httptest.NewRequest(http.MethodHead, "/page", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("text/html; charset=utf-8", rec.Header().Get(route.HeaderContentType))
+	}
+	assert.Equal(1, fsys.opened)
+}
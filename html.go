@@ -0,0 +1,217 @@
+package static
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io/fs"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goroute/route"
+)
+
+// Content Security Policy nonces.
+const (
+	headerContentSecurityPolicy = "Content-Security-Policy"
+
+	// cspNoncePlaceholder is replaced with the nonce in HTML files and in
+	// the policy.
+	cspNoncePlaceholder = "__NONCE__"
+)
+
+// cspNonceVar is the variable of the CSP nonce in HTML templates.
+const cspNonceVar = "CSP_NONCE"
+
+// varPattern matches the placeholders of variables in HTML templates.
+var varPattern = regexp.MustCompile(`%[A-Z][A-Z0-9_]*%`)
+
+type (
+	// htmlTemplate is an HTML file with placeholders of variables.
+	htmlTemplate struct {
+		modTime time.Time
+		size    int64
+
+		// Literal text and names of the variables, alternately.
+		parts []string
+	}
+
+	// htmlTemplates caches the parsed HTML templates by file name.
+	htmlTemplates struct {
+		mu        sync.Mutex
+		templates map[string]*htmlTemplate
+	}
+)
+
+// parseHTMLTemplate returns the template of the HTML content.
+func parseHTMLTemplate(content []byte) *htmlTemplate {
+	t := new(htmlTemplate)
+	last := 0
+	for _, loc := range varPattern.FindAllIndex(content, -1) {
+		t.parts = append(t.parts, string(content[last:loc[0]]), string(content[loc[0]+1:loc[1]-1]))
+		last = loc[1]
+	}
+	t.parts = append(t.parts, string(content[last:]))
+	return t
+}
+
+// execute returns the content of the template with the values of the
+// variables. Placeholders of undefined variables are kept.
+func (t *htmlTemplate) execute(vars map[string]string) []byte {
+	var buf bytes.Buffer
+	for i, part := range t.parts {
+		if i%2 == 0 {
+			buf.WriteString(part)
+		} else if v, ok := vars[part]; ok {
+			buf.WriteString(v)
+		} else {
+			buf.WriteString("%" + part + "%")
+		}
+	}
+	return buf.Bytes()
+}
+
+// template returns the parsed template of the named HTML file, parsing it
+// again if it was modified.
+func (s *server) template(name string, fi fs.FileInfo) (*htmlTemplate, error) {
+	s.templates.mu.Lock()
+	t, ok := s.templates.templates[name]
+	s.templates.mu.Unlock()
+	if ok && t.modTime.Equal(fi.ModTime()) && t.size == fi.Size() {
+		return t, nil
+	}
+
+	content, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	t = parseHTMLTemplate(content)
+	t.modTime, t.size = fi.ModTime(), fi.Size()
+	s.templates.mu.Lock()
+	s.templates.templates[name] = t
+	s.templates.mu.Unlock()
+	return t, nil
+}
+
+// invalidate removes the templates of the named file and, if it is a
+// directory, of its content.
+func (h *htmlTemplates) invalidate(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key := range h.templates {
+		if under(key, name) {
+			delete(h.templates, key)
+		}
+	}
+}
+
+// rewritesHTML reports whether the named file is an HTML file rewritten when
+// served, to inject the live reload script, CSP nonces or variables.
+func (s *server) rewritesHTML(c route.Context, name string) bool {
+	return (s.LiveReload || s.CSPNonce != "" || s.InjectVars != nil) && strings.HasPrefix(s.mimeType(name), route.MIMETextHTML) && !s.download(c, name)
+}
+
+// serveHTML sends the named HTML file rewritten.
+func (s *server) serveHTML(c route.Context, name string, fi fs.FileInfo) error {
+	if s.OnServe != nil {
+		c.Set(servedFileKey, "/"+name)
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(c, "/"+name, fi); err != nil {
+			return err
+		}
+	}
+	release, err := s.acquireTransfer(c)
+	if err != nil {
+		return err
+	}
+	defer release()
+	var content []byte
+	var nonce string
+	if s.CSPNonce != "" {
+		if nonce, err = newNonce(); err != nil {
+			return err
+		}
+	}
+	if s.InjectVars != nil {
+		t, err := s.template(name, fi)
+		if err != nil {
+			return err
+		}
+		vars := s.InjectVars(c)
+		if nonce != "" {
+			withNonce := map[string]string{cspNonceVar: nonce}
+			for k, v := range vars {
+				if k != cspNonceVar {
+					withNonce[k] = v
+				}
+			}
+			vars = withNonce
+		}
+		content = t.execute(vars)
+	} else {
+		if content, err = fs.ReadFile(s.fsys, name); err != nil {
+			return err
+		}
+	}
+
+	header := c.Response().Header()
+	header.Set(route.HeaderContentType, s.contentType(name, nil))
+	s.setHeaders(c, name, "")
+	modTime := fi.ModTime()
+	if s.LiveReload {
+		script := liveReloadScript
+		if s.CSPNonce != "" {
+			script = liveReloadNonceScript
+		}
+		content = injectScript(content, script)
+		header.Set(headerCacheControl, "no-cache")
+	}
+	if s.InjectVars != nil {
+		// Variables may change without the file.
+		header.Set(headerCacheControl, "no-cache")
+		modTime = time.Time{}
+	}
+	if nonce != "" {
+		content = bytes.Replace(content, []byte(cspNoncePlaceholder), []byte(nonce), -1)
+		header.Set(headerContentSecurityPolicy, strings.Replace(s.CSPNonce, cspNoncePlaceholder, nonce, -1))
+		// Pages are never reused, their nonce with them.
+		header.Set(headerCacheControl, "no-store")
+		modTime = time.Time{}
+	}
+	r := c.Request()
+	if s.Compress && s.compressible(header.Get(route.HeaderContentType), int64(len(content))) {
+		if done := compress(c); done != nil {
+			defer done()
+			// Ranges of the compressed body can't be served.
+			r = withoutRange(r)
+		}
+	}
+	s.serveContent(c, r, "", modTime, bytes.NewReader(content))
+	return nil
+}
+
+// newNonce returns a random nonce.
+func newNonce() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b[:]), nil
+}
+
+// injectScript returns the HTML content with the script inserted before the
+// closing body tag, or at its end.
+func injectScript(content []byte, script string) []byte {
+	i := bytes.LastIndex(bytes.ToLower(content), []byte("</body>"))
+	if i < 0 {
+		i = len(content)
+	}
+	injected := make([]byte, 0, len(content)+len(script))
+	injected = append(injected, content[:i]...)
+	injected = append(injected, script...)
+	return append(injected, content[i:]...)
+}
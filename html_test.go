@@ -0,0 +1,127 @@
+package static
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSPNonce(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<script nonce="__NONCE__" src="/app.js"></script>`)},
+		"app.js":     {Data: []byte("__NONCE__")},
+	}
+	mw := New(Filesystem(fsys), HTML5(true), Cache(1<<20, 0, 0), CSPNonce("script-src 'nonce-__NONCE__' 'strict-dynamic'"))
+	assert := assert.New(t)
+	re := regexp.MustCompile(`^<script nonce="([A-Za-z0-9+/=]{24})" src="/app.js"></script>$`)
+	var nonces []string
+	for _, target := range []string{"/", "/", "/index.html", "/users/1"} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			m := re.FindStringSubmatch(rec.Body.String())
+			if assert.NotNil(m, rec.Body.String()) {
+				assert.Equal("script-src 'nonce-"+m[1]+"' 'strict-dynamic'", rec.Header().Get(headerContentSecurityPolicy))
+				assert.NotContains(nonces, m[1])
+				nonces = append(nonces, m[1])
+			}
+			assert.Equal("no-store", rec.Header().Get(headerCacheControl))
+			assert.Empty(rec.Header().Get(route.HeaderLastModified))
+		}
+	}
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("__NONCE__", rec.Body.String())
+		assert.Empty(rec.Header().Get(headerContentSecurityPolicy))
+	}
+}
+
+func TestInjectScript(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("<p>Hi</p><script></script>", string(injectScript([]byte("<p>Hi</p>"), "<script></script>")))
+	assert.Equal("<body></body><s></body>", string(injectScript([]byte("<body></body></body>"), "<s>")))
+}
+
+func TestInjectVars(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<script nonce="%CSP_NONCE%">api = "%API_URL%"; user = "%USER%"; w = "50%"; q = "%E2%80%"</script>`)},
+	}
+	mw := New(Filesystem(fsys), Cache(1<<20, 0, 0), CSPNonce("script-src 'nonce-__NONCE__'"), InjectVars(func(c route.Context) map[string]string {
+		return map[string]string{"API_URL": c.Request().Host + "/api", "CSP_NONCE": "ignored"}
+	}))
+	assert := assert.New(t)
+	re := regexp.MustCompile(`^<script nonce="([A-Za-z0-9+/=]{24})">api = "example.com/api"; user = "%USER%"; w = "50%"; q = "%E2%80%"</script>$`)
+	for i := 0; i < 2; i++ {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			m := re.FindStringSubmatch(rec.Body.String())
+			if assert.NotNil(m, rec.Body.String()) {
+				assert.Equal("script-src 'nonce-"+m[1]+"'", rec.Header().Get(headerContentSecurityPolicy))
+			}
+			assert.Empty(rec.Header().Get(route.HeaderLastModified))
+		}
+	}
+
+	// Modified files are parsed again.
+	fsys["index.html"] = &fstest.MapFile{Data: []byte("<p>%API_URL%</p>"), ModTime: time.Now()}
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("<p>example.com/api</p>", rec.Body.String())
+	}
+}
+
+func TestParseHTMLTemplate(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"<p>", "A", "", "B_1", "%</p>"}, parseHTMLTemplate([]byte("<p>%A%%B_1%%</p>")).parts)
+	assert.Equal("<p>1%B_1%%</p>", string(parseHTMLTemplate([]byte("<p>%A%%B_1%%</p>")).execute(map[string]string{"A": "1"})))
+}
+
+func TestServeHTMLTransfer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`<script nonce="__NONCE__"></script>` + strings.Repeat("hello ", 1000))},
+	}
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	_, h := NewHandle(Filesystem(fsys), CSPNonce("script-src 'nonce-__NONCE__'"), Compress(true), MaxTransfers(1, 0))
+	mw := h.s.serve
+
+	req := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	req.Header.Set(route.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("gzip", rec.Header().Get(route.HeaderContentEncoding))
+		zr, err := gzip.NewReader(rec.Body)
+		if assert.NoError(err) {
+			b, err := io.ReadAll(zr)
+			assert.NoError(err)
+			assert.Contains(string(b), "hello hello")
+			assert.NotContains(string(b), cspNoncePlaceholder)
+		}
+	}
+	assert.Len(h.s.transfers, 0)
+
+	h.s.transfers <- struct{}{} // A transfer in progress
+	req = httptest.NewRequest(http.MethodGet, "/index.html", nil)
+	rec = httptest.NewRecorder()
+	err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	if assert.Error(err) {
+		assert.Equal(http.StatusServiceUnavailable, err.(*route.HTTPError).Code)
+	}
+}
@@ -0,0 +1,120 @@
+package httpbackend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+type (
+	// fileInfo describes an upstream file or directory.
+	fileInfo struct {
+		name    string
+		size    int64
+		modTime time.Time
+		etag    string
+		dir     bool
+	}
+
+	// file is an open upstream file, read with ranged GET requests from the
+	// current offset.
+	file struct {
+		fs     *FS
+		name   string
+		info   *fileInfo
+		offset int64
+		body   io.ReadCloser
+	}
+
+	// dir is an open directory, which can't be read.
+	dir struct {
+		name string
+		info fs.FileInfo
+	}
+)
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.dir }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ETag returns the entity tag of the upstream file.
+func (fi *fileInfo) ETag() string { return fi.etag }
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) Read(b []byte) (int, error) {
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		header := http.Header{}
+		if f.offset > 0 {
+			header.Set("Range", fmt.Sprintf("bytes=%d-", f.offset))
+		}
+		res, err := f.fs.do(http.MethodGet, f.name, header)
+		if err != nil {
+			return 0, err
+		}
+		if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent ||
+			res.StatusCode == http.StatusOK && f.offset > 0 {
+			res.Body.Close()
+			return 0, fmt.Errorf("httpbackend: GET %s: %s", f.name, res.Status)
+		}
+		f.body = res.Body
+	}
+	n, err := f.body.Read(b)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	default:
+		return 0, errors.New("httpbackend: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("httpbackend: negative position")
+	}
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) Close() error {
+	if f.body != nil {
+		return f.body.Close()
+	}
+	return nil
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) ReadDir(int) ([]fs.DirEntry, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: errNotListable}
+}
+
+func (d *dir) Close() error { return nil }
@@ -0,0 +1,243 @@
+// Package httpbackend serves the files of a remote HTTP server, e.g. a CDN
+// origin or another static server, as a file system for the Static
+// middleware.
+//
+//	mux.Use(static.New(static.Backend(httpbackend.New(
+//		"https://origin.example.com/assets",
+//		httpbackend.CacheDir("/var/cache/assets"),
+//	))))
+//
+// With a cache directory, the middleware acts as a pull-through cache: files
+// are downloaded once, then revalidated with conditional requests when their
+// TTL expires, and served stale if the upstream is unreachable.
+package httpbackend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// FS is a file system of the files of an upstream server. It implements
+	// fs.StatFS. Directories can't be listed.
+	FS struct {
+		upstream string
+		cacheDir string
+		ttl      time.Duration
+		client   *http.Client
+
+		mu        sync.Mutex
+		validated map[string]time.Time
+	}
+
+	// Option configures an FS.
+	Option func(*FS)
+)
+
+// errNotListable is the error of reading a directory.
+var errNotListable = errors.New("httpbackend: directories can't be listed")
+
+// New returns the file system of the files under the upstream URL.
+func New(upstream string, options ...Option) *FS {
+	f := &FS{
+		upstream:  strings.TrimSuffix(upstream, "/"),
+		ttl:       time.Minute,
+		client:    http.DefaultClient,
+		validated: map[string]time.Time{},
+	}
+	for _, o := range options {
+		o(f)
+	}
+	return f
+}
+
+// CacheDir sets the directory where files are cached.
+// Default value "", which disables the cache.
+func CacheDir(dir string) Option {
+	return func(f *FS) {
+		f.cacheDir = dir
+	}
+}
+
+// CacheTTL sets the time for which cached files are served without being
+// revalidated.
+// Default value 1 minute.
+func CacheTTL(ttl time.Duration) Option {
+	return func(f *FS) {
+		f.ttl = ttl
+	}
+}
+
+// HTTPClient sets the client sending requests.
+// Default value http.DefaultClient.
+func HTTPClient(client *http.Client) Option {
+	return func(f *FS) {
+		f.client = client
+	}
+}
+
+// Open opens the named file or directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	fi, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &dir{name: name, info: fi}, nil
+	}
+	if f.cacheDir != "" {
+		return os.Open(f.cachePath(name))
+	}
+	return &file{fs: f, name: name, info: fi.(*fileInfo)}, nil
+}
+
+// Stat returns the FileInfo of the named file or directory.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *FS) stat(op, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+	if f.cacheDir != "" {
+		return f.fetch(op, name)
+	}
+
+	res, err := f.do(http.MethodHead, name, nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	res.Body.Close()
+	return f.info(op, name, res)
+}
+
+// info returns the FileInfo of the named file from the upstream response.
+func (f *FS) info(op, name string, res *http.Response) (*fileInfo, error) {
+	switch {
+	case isDir(name, res):
+		return &fileInfo{name: path.Base(name), dir: true}, nil
+	case res.StatusCode == http.StatusOK:
+		size, _ := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+		modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+		return &fileInfo{name: path.Base(name), size: size, modTime: modTime, etag: res.Header.Get("ETag")}, nil
+	case res.StatusCode == http.StatusNotFound:
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case res.StatusCode == http.StatusForbidden:
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	}
+	return nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("httpbackend: %s", res.Status)}
+}
+
+// isDir reports whether the request for the named file was redirected to
+// the URL of a directory, with a trailing slash.
+func isDir(name string, res *http.Response) bool {
+	return res.StatusCode == http.StatusOK && strings.HasSuffix(res.Request.URL.Path, "/"+name+"/")
+}
+
+// fetch downloads the named file into the cache directory unless its cached
+// copy is fresh, returning the FileInfo of the cached copy.
+func (f *FS) fetch(op, name string) (fs.FileInfo, error) {
+	p := f.cachePath(name)
+	cached, err := os.Stat(p)
+	if err != nil {
+		cached = nil
+	}
+	f.mu.Lock()
+	validated, ok := f.validated[name]
+	f.mu.Unlock()
+	if cached != nil && ok && time.Since(validated) < f.ttl {
+		return cached, nil
+	}
+
+	header := http.Header{}
+	if cached != nil {
+		header.Set("If-Modified-Since", cached.ModTime().UTC().Format(http.TimeFormat))
+	}
+	res, err := f.do(http.MethodGet, name, header)
+	if err != nil {
+		if cached != nil { // Stale
+			return cached, nil
+		}
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+	case res.StatusCode == http.StatusOK && !isDir(name, res):
+		info, _ := f.info(op, name, res)
+		if cached, err = f.store(p, res.Body, info.modTime); err != nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+	default:
+		if res.StatusCode == http.StatusNotFound {
+			os.Remove(p)
+		}
+		if res.StatusCode >= 500 && cached != nil { // Stale
+			return cached, nil
+		}
+		return f.info(op, name, res)
+	}
+	f.mu.Lock()
+	f.validated[name] = time.Now()
+	f.mu.Unlock()
+	return cached, nil
+}
+
+// store writes the content of a file to the path p of the cache.
+func (f *FS) store(p string, r io.Reader, modTime time.Time) (fs.FileInfo, error) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, err
+	}
+	if !modTime.IsZero() {
+		os.Chtimes(tmp.Name(), modTime, modTime)
+	}
+	if err = os.Rename(tmp.Name(), p); err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+// cachePath returns the path of the cached copy of the named file.
+func (f *FS) cachePath(name string) string {
+	return filepath.Join(f.cacheDir, filepath.FromSlash(name))
+}
+
+// do sends a request for the named file.
+func (f *FS) do(method, name string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, f.upstream+(&url.URL{Path: "/" + name}).EscapedPath(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return f.client.Do(req)
+}
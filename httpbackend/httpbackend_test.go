@@ -0,0 +1,125 @@
+package httpbackend
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/goroute/static"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUpstream() (*httptest.Server, *int32, fstest.MapFS) {
+	files := fstest.MapFS{
+		"index.html":    {Data: []byte("<h1>Hello</h1>"), ModTime: time.Unix(1e9, 0)},
+		"app.js":        {Data: []byte("console.log('app')"), ModTime: time.Unix(1e9, 0)},
+		"docs/guide.md": {Data: []byte("# Guide"), ModTime: time.Unix(1e9, 0)},
+	}
+	var gets int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+		}
+		http.FileServer(http.FS(files)).ServeHTTP(w, r)
+	}))
+	return srv, &gets, files
+}
+
+func TestFS(t *testing.T) {
+	srv, _, _ := newUpstream()
+	defer srv.Close()
+	fsys := New(srv.URL)
+
+	assert := assert.New(t)
+	fi, err := fsys.Stat("docs")
+	if assert.NoError(err) {
+		assert.True(fi.IsDir())
+	}
+	fi, err = fsys.Stat("app.js")
+	if assert.NoError(err) {
+		assert.False(fi.IsDir())
+		assert.Equal(int64(18), fi.Size())
+		assert.True(time.Unix(1e9, 0).Equal(fi.ModTime()))
+	}
+	_, err = fsys.Stat("missing.js")
+	assert.True(errors.Is(err, fs.ErrNotExist))
+
+	f, err := fsys.Open("app.js")
+	if assert.NoError(err) {
+		defer f.Close()
+		rs := f.(io.ReadSeeker)
+		rs.Seek(8, io.SeekStart)
+		buf := make([]byte, 3)
+		io.ReadFull(rs, buf)
+		assert.Equal("log", string(buf))
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	srv, gets, files := newUpstream()
+	defer srv.Close()
+	fsys := New(srv.URL, CacheDir(t.TempDir()), CacheTTL(time.Hour))
+
+	mw := static.New(static.Backend(fsys))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal("console.log('app')", rec.Body.String())
+		}
+	}
+	assert.Equal(int32(1), atomic.LoadInt32(gets))
+
+	// Revalidated once expired
+	fsys.ttl = 0
+	files["app.js"] = &fstest.MapFile{Data: []byte("console.log('v2')"), ModTime: time.Unix(2e9, 0)}
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("console.log('v2')", rec.Body.String())
+	}
+
+	// Served stale when the upstream is down
+	srv.Close()
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("console.log('v2')", rec.Body.String())
+	}
+}
+
+func TestStatic(t *testing.T) {
+	srv, _, _ := newUpstream()
+	defer srv.Close()
+
+	mw := static.New(static.Backend(New(srv.URL)))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("<h1>Hello</h1>", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("Range", "bytes=8-10")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusPartialContent, rec.Code)
+		assert.Equal("log", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/missing.js", nil)
+	rec = httptest.NewRecorder()
+	assert.Error(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+}
@@ -0,0 +1,54 @@
+package static
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// imageFormats are the formats of image variants in order of preference, by
+// MIME type and extension.
+var imageFormats = []struct {
+	mime string
+	ext  string
+}{
+	{"image/avif", ".avif"},
+	{"image/webp", ".webp"},
+}
+
+// hasImageVariants reports whether the named file may have image variants.
+func hasImageVariants(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// acceptedImageFormats returns the extensions of the image variant formats
+// accepted by the client, in order of preference.
+func acceptedImageFormats(r *http.Request) []string {
+	accept := r.Header.Get(route.HeaderAccept)
+	var exts []string
+	for _, f := range imageFormats {
+		if acceptsEncoding(accept, f.mime) {
+			exts = append(exts, f.ext)
+		}
+	}
+	return exts
+}
+
+// imageVariant returns the name and FileInfo of the preferred variant of the
+// named image accepted by the client, e.g. "photo.jpg.avif" for "photo.jpg".
+// It returns false if there is none.
+func (s *server) imageVariant(r *http.Request, name string) (string, fs.FileInfo, bool) {
+	for _, ext := range acceptedImageFormats(r) {
+		if fi, err := s.stat(r.Context(), name+ext); err == nil && !fi.IsDir() {
+			return name + ext, fi, true
+		}
+	}
+	return name, nil, false
+}
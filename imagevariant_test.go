@@ -0,0 +1,47 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageVariants(t *testing.T) {
+	fsys := fstest.MapFS{
+		"photo.jpg":      {Data: []byte("jpeg")},
+		"photo.jpg.avif": {Data: []byte("avif")},
+		"photo.jpg.webp": {Data: []byte("webp")},
+		"logo.png":       {Data: []byte("png")},
+		"logo.png.webp":  {Data: []byte("webp logo")},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), ImageVariants(true)),
+		New(Filesystem(fsys), ImageVariants(true), Cache(1<<20, 0, 0)),
+	} {
+		for _, tc := range []struct {
+			target, accept, want, ctype string
+		}{
+			{"/photo.jpg", "image/avif,image/webp,*/*", "avif", "image/avif"},
+			{"/photo.jpg", "image/webp,*/*", "webp", "image/webp"},
+			{"/photo.jpg", "image/avif;q=0,image/webp", "webp", "image/webp"},
+			{"/photo.jpg", "*/*", "jpeg", "image/jpeg"},
+			{"/logo.png", "image/avif,image/webp", "webp logo", "image/webp"},
+			{"/photo.jpg", "", "jpeg", "image/jpeg"},
+		} {
+			mux := route.NewServeMux()
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.Header.Set(route.HeaderAccept, tc.accept)
+			rec := httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+				assert.Equal(tc.want, rec.Body.String(), tc.target+" "+tc.accept)
+				assert.Equal(tc.ctype, rec.Header().Get(route.HeaderContentType), tc.target+" "+tc.accept)
+				assert.Equal(route.HeaderAccept, rec.Header().Get(route.HeaderVary))
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/goroute/route"
+)
+
+type (
+	// IOEvent describes a transient I/O error of the filesystem, e.g. too
+	// many open files, observed with OnIOError.
+	IOEvent struct {
+		// Operation which failed, "open", "stat" or "readdir".
+		Op string
+
+		// Name of the file, from the root.
+		Name string
+
+		// Error of the operation.
+		Err error
+
+		// Number of the failed attempt, from 1.
+		Attempt int
+
+		// Whether the operation is retried.
+		Retry bool
+
+		// Whether the circuit breaker opened after the error, requests being
+		// answered with status 503 for IOBreakerCooldown.
+		Broken bool
+	}
+
+	// ioHealth retries the transient I/O errors of a filesystem and tracks
+	// its health.
+	ioHealth struct {
+		retries   int
+		backoff   time.Duration
+		threshold int
+		cooldown  time.Duration
+		onError   func(IOEvent)
+
+		mu        sync.Mutex
+		failures  int       // Consecutive transient errors.
+		openUntil time.Time // End of the cooldown of the circuit breaker.
+	}
+
+	// healthFS is a backend whose operations go through an ioHealth, until
+	// the context is done.
+	healthFS struct {
+		BackendFS
+		h   *ioHealth
+		ctx context.Context
+	}
+
+	// unavailableError is a transient I/O error, answered with status 503.
+	unavailableError struct {
+		err        error
+		retryAfter time.Duration
+	}
+)
+
+// errUnhealthy is the error of the operations rejected by the open circuit
+// breaker.
+var errUnhealthy = errors.New("static: filesystem unhealthy")
+
+// transientErrnos are the errors of the OS which may not happen on retry.
+var transientErrnos = []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.EAGAIN, syscall.EINTR, syscall.ETIMEDOUT}
+
+// newIOHealth returns the ioHealth of the options, or nil if transient
+// errors aren't handled.
+func newIOHealth(opts Options) *ioHealth {
+	if opts.IORetries <= 0 && opts.IOBreakerThreshold <= 0 && opts.OnIOError == nil {
+		return nil
+	}
+	return &ioHealth{
+		retries:   opts.IORetries,
+		backoff:   opts.IORetryBackoff,
+		threshold: opts.IOBreakerThreshold,
+		cooldown:  opts.IOBreakerCooldown,
+		onError:   opts.OnIOError,
+	}
+}
+
+// do runs the operation on the named file, retrying it after transient
+// errors until the context is done. Transient errors which aren't retried
+// are returned as unavailableErrors.
+func (h *ioHealth) do(ctx context.Context, op, name string, fn func() error) error {
+	if wait := h.broken(); wait > 0 {
+		return &unavailableError{&fs.PathError{Op: op, Path: name, Err: errUnhealthy}, wait}
+	}
+	backoff := h.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !transient(err) {
+			h.succeeded()
+			return err
+		}
+		ev := IOEvent{Op: op, Name: name, Err: err, Attempt: attempt, Retry: attempt <= h.retries}
+		if !ev.Retry {
+			ev.Broken = h.failed()
+		}
+		if h.onError != nil {
+			h.onError(ev)
+		}
+		if !ev.Retry {
+			retryAfter := time.Second
+			if ev.Broken {
+				retryAfter = h.cooldown
+			}
+			return &unavailableError{err, retryAfter}
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return &fs.PathError{Op: op, Path: name, Err: ctx.Err()}
+		}
+		backoff *= 2
+	}
+}
+
+// broken returns the remaining cooldown of the circuit breaker, or 0 if it
+// is closed.
+func (h *ioHealth) broken() time.Duration {
+	if h.threshold <= 0 {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if wait := time.Until(h.openUntil); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// failed records a transient error and reports whether the circuit breaker
+// opened.
+func (h *ioHealth) failed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures++
+	if h.threshold <= 0 || h.failures < h.threshold {
+		return false
+	}
+	h.openUntil = time.Now().Add(h.cooldown)
+	return true
+}
+
+// succeeded records a successful operation, closing the circuit breaker.
+func (h *ioHealth) succeeded() {
+	h.mu.Lock()
+	h.failures = 0
+	h.mu.Unlock()
+}
+
+// transient reports whether the error may not happen on retry.
+func transient(err error) bool {
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
+
+func (f healthFS) Open(name string) (file fs.File, err error) {
+	err = f.h.do(f.ctx, "open", name, func() (err error) {
+		file, err = f.BackendFS.Open(name)
+		return
+	})
+	return
+}
+
+func (f healthFS) Stat(name string) (fi fs.FileInfo, err error) {
+	err = f.h.do(f.ctx, "stat", name, func() (err error) {
+		fi, err = f.BackendFS.Stat(name)
+		return
+	})
+	return
+}
+
+func (f healthFS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	err = f.h.do(f.ctx, "readdir", name, func() (err error) {
+		entries, err = f.BackendFS.ReadDir(name)
+		return
+	})
+	return
+}
+
+// backend returns the filesystem of the server, whose retries of transient
+// errors stop when the context is done.
+func (s *server) backend(ctx context.Context) BackendFS {
+	if f, ok := s.fsys.(healthFS); ok {
+		f.ctx = ctx
+		return f
+	}
+	return s.fsys
+}
+
+func (e *unavailableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// unavailable answers transient I/O errors with status 503 and a Retry-After
+// header, returning other errors as is.
+func unavailable(c route.Context, err error) error {
+	var ue *unavailableError
+	if !errors.As(err, &ue) || c.Response().Committed {
+		return err
+	}
+	secs := int64((ue.retryAfter + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	return route.ErrServiceUnavailable
+}
@@ -0,0 +1,90 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyFS fails with EMFILE while failing is positive, decrementing it.
+type flakyFS struct {
+	fs.FS
+
+	mu      sync.Mutex
+	failing int
+	opens   int
+}
+
+func (f *flakyFS) Open(name string) (fs.File, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opens++
+	if f.failing > 0 {
+		f.failing--
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EMFILE}
+	}
+	return f.FS.Open(name)
+}
+
+func TestIOErrors(t *testing.T) {
+	fsys := &flakyFS{FS: fstest.MapFS{"file.txt": {Data: []byte("file")}}}
+	var events []IOEvent
+	mw := New(Filesystem(fsys), IORetry(2, time.Millisecond), IOBreaker(2, time.Minute), OnIOError(func(ev IOEvent) {
+		events = append(events, ev)
+	}))
+	assert := assert.New(t)
+
+	// Retried.
+	fsys.failing = 2
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/file.txt").Code)
+	if assert.Len(events, 2) {
+		assert.Equal(IOEvent{Op: "stat", Name: "file.txt", Err: events[0].Err, Attempt: 1, Retry: true}, events[0])
+		assert.True(errors.Is(events[0].Err, syscall.EMFILE))
+	}
+
+	// Unavailable once retried.
+	events, fsys.failing = nil, 3
+	rec := serve(t, mw, http.MethodGet, "/file.txt")
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("1", rec.Header().Get("Retry-After"))
+	if assert.Len(events, 3) {
+		assert.False(events[2].Retry)
+		assert.False(events[2].Broken)
+	}
+
+	// Circuit breaker opened by consecutive errors.
+	events, fsys.failing = nil, 3
+	rec = serve(t, mw, http.MethodGet, "/file.txt")
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("60", rec.Header().Get("Retry-After"))
+	if assert.Len(events, 3) {
+		assert.True(events[2].Broken)
+	}
+	opens := fsys.opens
+	rec = serve(t, mw, http.MethodGet, "/file.txt")
+	assert.Equal(http.StatusServiceUnavailable, rec.Code)
+	assert.Equal("60", rec.Header().Get("Retry-After"))
+	assert.Equal(opens, fsys.opens)
+
+	// Not retried once the request is canceled.
+	fsys = &flakyFS{FS: fstest.MapFS{"file.txt": {Data: []byte("file")}}, failing: 10}
+	mw = New(Filesystem(fsys), IORetry(5, time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	req := httptest.NewRequest(http.MethodGet, "/file.txt", nil).WithContext(ctx)
+	start := time.Now()
+	err := mw(route.NewServeMux().NewContext(req, httptest.NewRecorder()), route.NotFoundHandler)
+	assert.True(errors.Is(err, context.Canceled), err)
+	assert.True(time.Since(start) < time.Minute)
+	assert.Equal(1, fsys.opens)
+}
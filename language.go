@@ -0,0 +1,73 @@
+package static
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// headerAcceptLanguage is the header of the languages accepted by clients,
+// not defined by route.
+const headerAcceptLanguage = "Accept-Language"
+
+// acceptedLanguages returns the lower case language tags of the
+// Accept-Language header value, by decreasing quality. Wildcards and
+// languages of quality 0 are omitted.
+func acceptedLanguages(accept string) []string {
+	type language struct {
+		tag string
+		q   float64
+	}
+	var languages []language
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag == "" || tag == "*" || strings.ContainsAny(tag, "/.") {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		if q > 0 {
+			languages = append(languages, language{tag, q})
+		}
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].q > languages[j].q
+	})
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// languageVariant returns the name and FileInfo of the variant of the named
+// file in the language preferred by the client, and its language. It returns
+// an empty language if there is none. Language tags are tried before their
+// primary language, e.g. "de-at" before "de".
+func (s *server) languageVariant(r *http.Request, name string) (string, fs.FileInfo, string) {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	for _, tag := range acceptedLanguages(r.Header.Get(headerAcceptLanguage)) {
+		for lang := tag; ; {
+			variant := strings.NewReplacer("{base}", strings.TrimSuffix(base, ext), "{lang}", lang, "{ext}", ext).
+				Replace(s.LanguageVariants)
+			if fi, err := s.stat(r.Context(), path.Join(dir, variant)); err == nil && !fi.IsDir() {
+				return path.Join(dir, variant), fi, lang
+			}
+			i := strings.LastIndexByte(lang, '-')
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	return name, nil, ""
+}
@@ -0,0 +1,54 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	assert.Equal(t, []string{"fr-ch", "fr", "en", "de"}, acceptedLanguages("de;q=0.7, fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5, it;q=0"))
+	assert.Empty(t, acceptedLanguages(""))
+}
+
+func TestLanguageVariants(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html":          {Data: []byte("page")},
+		"page.de.html":       {Data: []byte("Seite")},
+		"page.de-ch.html":    {Data: []byte("Siite")},
+		"docs/index.html":    {Data: []byte("docs")},
+		"docs/index.fr.html": {Data: []byte("documentation")},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), LanguageVariants("{base}.{lang}{ext}")),
+		New(Filesystem(fsys), LanguageVariants("{base}.{lang}{ext}"), Cache(1<<20, 0, 0)),
+	} {
+		for _, tc := range []struct {
+			target, accept, want string
+		}{
+			{"/page.html", "", "page"},
+			{"/page.html", "de", "Seite"},
+			{"/page.html", "de-AT, en;q=0.5", "Seite"},
+			{"/page.html", "de-CH", "Siite"},
+			{"/page.html", "fr, de;q=0.5", "Seite"},
+			{"/page.html", "it", "page"},
+			{"/page.html", "", "page"},
+			{"/docs/", "fr", "documentation"},
+			{"/docs/", "en", "docs"},
+		} {
+			mux := route.NewServeMux()
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			req.Header.Set(headerAcceptLanguage, tc.accept)
+			rec := httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+				assert.Equal(tc.want, rec.Body.String(), tc.target+" "+tc.accept)
+				assert.Equal(headerAcceptLanguage, rec.Header().Get(route.HeaderVary))
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package static
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/goroute/route"
+)
+
+// acquireTransfer takes a transfer slot, waiting up to TransferWait for one to
+// be released. The returned function releases the slot. It returns
+// route.ErrServiceUnavailable if no slot is available in time.
+func (s *server) acquireTransfer(c route.Context) (func(), error) {
+	if s.transfers == nil || c.Request().Method == http.MethodHead {
+		return func() {}, nil
+	}
+	release := func() { <-s.transfers }
+	select {
+	case s.transfers <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if s.TransferWait > 0 {
+		t := time.NewTimer(s.TransferWait)
+		defer t.Stop()
+		select {
+		case s.transfers <- struct{}{}:
+			return release, nil
+		case <-t.C:
+		case <-c.Request().Context().Done():
+			return nil, c.Request().Context().Err()
+		}
+	}
+	c.Response().Header().Set("Retry-After", "1")
+	return nil, route.ErrServiceUnavailable
+}
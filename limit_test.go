@@ -0,0 +1,59 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxTransfers(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.txt": {Data: []byte("hello")},
+	}
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	_, h := NewHandle(Filesystem(fsys), MaxTransfers(1, 0))
+	mw := h.s.serve
+	h.s.transfers <- struct{}{} // A transfer in progress
+
+	req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	rec := httptest.NewRecorder()
+	err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	if assert.Error(err) {
+		assert.Equal(http.StatusServiceUnavailable, err.(*route.HTTPError).Code)
+		assert.Equal("1", rec.Header().Get("Retry-After"))
+	}
+
+	// HEAD requests don't transfer
+	req = httptest.NewRequest(http.MethodHead, "/file.txt", nil)
+	rec = httptest.NewRecorder()
+	assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+
+	<-h.s.transfers
+	req = httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("hello", rec.Body.String())
+	}
+	assert.Len(h.s.transfers, 0)
+
+	// Queued
+	_, h = NewHandle(Filesystem(fsys), MaxTransfers(1, time.Second))
+	mw = h.s.serve
+	h.s.transfers <- struct{}{}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		<-h.s.transfers
+	}()
+	req = httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("hello", rec.Body.String())
+	}
+}
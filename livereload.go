@@ -0,0 +1,26 @@
+package static
+
+import (
+	"github.com/goroute/route"
+)
+
+// Live reload.
+const (
+	// liveReloadPath is the URL path of the event stream of file changes.
+	liveReloadPath = "/__livereload"
+
+	// liveReloadScript reloads the page on file changes.
+	liveReloadScript = `<script>new EventSource("` + liveReloadPath + `").onmessage = function () { location.reload(); };</script>`
+
+	// liveReloadNonceScript is liveReloadScript allowed by the CSP nonce.
+	liveReloadNonceScript = `<script nonce="` + cspNoncePlaceholder + `">new EventSource("` + liveReloadPath + `").onmessage = function () { location.reload(); };</script>`
+)
+
+// serveLiveReload sends an event for each file change of any virtual host.
+func (s *server) serveLiveReload(c route.Context) error {
+	events, unsubscribe := s.changes.subscribe()
+	defer unsubscribe()
+	return stream(c, events, func(fileEvent) []byte {
+		return []byte("reload")
+	})
+}
@@ -0,0 +1,53 @@
+package static
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveReload(t *testing.T) {
+	dir := t.TempDir()
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html><BODY>Hello</BODY></html>"), 0644))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "page.txt"), []byte("Hello"), 0644))
+	mw := New(Root(dir), LiveReload(true), Cache(1<<20, 0, 0))
+	mux := route.NewServeMux()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw(mux.NewContext(r, w), route.NotFoundHandler)
+	}))
+	defer srv.Close()
+	get := func(p string) string {
+		res, err := http.Get(srv.URL + p)
+		if !assert.NoError(err) {
+			return ""
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return string(body)
+	}
+
+	for i := 0; i < 2; i++ {
+		assert.Equal("<html><BODY>Hello"+liveReloadScript+"</BODY></html>", get("/"))
+		assert.Equal("Hello", get("/page.txt"))
+	}
+
+	res, err := http.Get(srv.URL + liveReloadPath)
+	if !assert.NoError(err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.Equal(headerContentTypeEventStream, res.Header.Get(route.HeaderContentType))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "page.txt"), []byte("Changed"), 0644))
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	assert.NoError(err)
+	assert.Equal("data: reload\n", line)
+	assert.Equal("Changed", get("/page.txt"))
+}
@@ -0,0 +1,86 @@
+package static
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// manages reports whether the request deletes or moves a file.
+func (s *server) manages(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodDelete:
+		return s.AllowDelete
+	case http.MethodPost:
+		return s.AllowRename && r.URL.Query().Get("move") != ""
+	}
+	return false
+}
+
+// manage deletes the named file or empty directory, or moves it to the path
+// of the "move" query parameter, relative to its directory unless absolute.
+func (s *server) manage(c route.Context, name string, next route.HandlerFunc) error {
+	fi, err := s.stat(c.Request().Context(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return next(c)
+	}
+	if err != nil {
+		return err
+	}
+	if name == "." || s.escapes(name) {
+		return route.ErrForbidden
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(c, "/"+name, fi); err != nil {
+			return err
+		}
+	}
+	src := filepath.Join(s.writeDir, filepath.FromSlash(name))
+
+	if c.Request().Method == http.MethodDelete {
+		if err := os.Remove(src); err != nil {
+			if fi.IsDir() && !errors.Is(err, fs.ErrNotExist) {
+				return route.NewHTTPError(http.StatusConflict, "directory not empty")
+			}
+			return err
+		}
+		s.invalidate(name)
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	to := c.QueryParam("move")
+	if !strings.HasPrefix(to, "/") {
+		to = path.Join(path.Dir(name), to)
+	}
+	dst, err := resolveName(to)
+	if err != nil || dst == "." || under(dst, name) {
+		return route.NewHTTPError(http.StatusBadRequest, "invalid destination")
+	}
+	if !s.visible(dst, fi.IsDir()) || s.escapes(path.Dir(dst)) {
+		return route.ErrForbidden
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(c, "/"+dst, fi); err != nil {
+			return err
+		}
+	}
+	target := filepath.Join(s.writeDir, filepath.FromSlash(dst))
+	if _, err := os.Lstat(target); err == nil {
+		return route.NewHTTPError(http.StatusConflict, "file exists")
+	}
+	if err := os.Rename(src, target); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return route.NewHTTPError(http.StatusConflict, "destination directory not found")
+		}
+		return err
+	}
+	s.invalidate(name)
+	s.invalidate(dst)
+	return c.NoContent(http.StatusNoContent)
+}
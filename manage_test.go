@@ -0,0 +1,95 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+// authorizeAll authorizes every file.
+func authorizeAll(route.Context, string, os.FileInfo) error {
+	return nil
+}
+
+func TestAllowDelete(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(root, "locked.txt"), []byte("locked"), 0o644)
+	os.MkdirAll(filepath.Join(root, "full"), 0o755)
+	os.WriteFile(filepath.Join(root, "full", "b.txt"), []byte("b"), 0o644)
+	mw := New(Root(root), AllowDelete(true), Cache(1<<20, 0, 0),
+		Authorize(func(c route.Context, p string, fi os.FileInfo) error {
+			if p == "/locked.txt" {
+				return route.ErrForbidden
+			}
+			return nil
+		}))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	do := func(method, target string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(method, target, nil)
+		rec := httptest.NewRecorder()
+		return rec, mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	}
+
+	rec, err := do(http.MethodGet, "/a.txt")
+	assert.NoError(err)
+	assert.Equal("a", rec.Body.String())
+	rec, err = do(http.MethodDelete, "/a.txt")
+	if assert.NoError(err) {
+		assert.Equal(http.StatusNoContent, rec.Code)
+	}
+	_, err = do(http.MethodGet, "/a.txt")
+	assert.Equal(route.ErrNotFound, err)
+
+	_, err = do(http.MethodDelete, "/locked.txt")
+	assert.Equal(route.ErrForbidden, err)
+	_, err = do(http.MethodDelete, "/full/")
+	if assert.IsType(&route.HTTPError{}, err) {
+		assert.Equal(http.StatusConflict, err.(*route.HTTPError).Code)
+	}
+	_, err = do(http.MethodDelete, "/")
+	assert.Equal(route.ErrForbidden, err)
+	_, err = do(http.MethodDelete, "/missing.txt")
+	assert.Equal(route.ErrNotFound, err)
+}
+
+func TestAllowRename(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(root, "b.txt"), []byte("b"), 0o644)
+	os.MkdirAll(filepath.Join(root, "dir"), 0o755)
+	mw := New(Root(root), AllowRename(true), Authorize(authorizeAll))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	move := func(target string) error {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		rec := httptest.NewRecorder()
+		return mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	}
+
+	assert.NoError(move("/a.txt?move=c.txt"))
+	b, _ := os.ReadFile(filepath.Join(root, "c.txt"))
+	assert.Equal("a", string(b))
+	assert.NoError(move("/c.txt?move=/dir/d.txt"))
+	b, _ = os.ReadFile(filepath.Join(root, "dir", "d.txt"))
+	assert.Equal("a", string(b))
+
+	err := move("/b.txt?move=dir/d.txt")
+	if assert.IsType(&route.HTTPError{}, err) {
+		assert.Equal(http.StatusConflict, err.(*route.HTTPError).Code)
+	}
+	assert.Equal(route.ErrForbidden, move("/b.txt?move=.b.txt"))
+	err = move("/dir/?move=/dir/sub")
+	if assert.IsType(&route.HTTPError{}, err) {
+		assert.Equal(http.StatusBadRequest, err.(*route.HTTPError).Code)
+	}
+	assert.NoError(move("/b.txt?move=../../../b2.txt"))
+	_, err = os.Stat(filepath.Join(root, "b2.txt"))
+	assert.NoError(err)
+}
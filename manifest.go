@@ -0,0 +1,89 @@
+package static
+
+import (
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// assetManifest is a build tool manifest mapping logical asset names to
+// hashed file names, reloaded when the file changes.
+type assetManifest struct {
+	mu      sync.Mutex
+	modTime time.Time
+	assets  map[string]string
+}
+
+// assets returns the hashed file names by logical name, from the manifest
+// file. It returns nil if there is no manifest.
+func (s *server) assets() map[string]string {
+	if s.ManifestFile == "" {
+		return nil
+	}
+	name := fsPath(s.ManifestFile)
+	fi, err := s.fsys.Stat(name)
+	if err != nil {
+		return nil
+	}
+
+	m := &s.manifest
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.assets != nil && m.modTime.Equal(fi.ModTime()) {
+		return m.assets
+	}
+	b, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return m.assets
+	}
+	if assets, err := parseManifest(b); err == nil {
+		m.assets, m.modTime = assets, fi.ModTime()
+	}
+	return m.assets
+}
+
+// parseManifest parses a Vite manifest, whose entries are objects with a
+// "file" field, or a webpack manifest, whose entries are file names.
+func parseManifest(b []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	assets := make(map[string]string, len(raw))
+	for name, value := range raw {
+		var file string
+		if err := json.Unmarshal(value, &file); err != nil {
+			var chunk struct {
+				File string `json:"file"`
+			}
+			if err := json.Unmarshal(value, &chunk); err != nil || chunk.File == "" {
+				continue
+			}
+			file = chunk.File
+		}
+		assets[fsPath(name)] = fsPath(file)
+	}
+	return assets, nil
+}
+
+// resolveAsset returns the hashed file name of the logical asset name from
+// the manifest.
+func (s *server) resolveAsset(name string) (string, bool) {
+	file, ok := s.assets()[name]
+	return file, ok
+}
+
+// ResolveAsset returns the URL path of the hashed file of the logical asset
+// name from the manifest file, e.g. "/assets/main.4f3a9c.js" for "main.js".
+// It returns name if the manifest has no such asset.
+func (h *Handle) ResolveAsset(name string) string {
+	if file, ok := h.s.resolveAsset(fsPath(name)); ok {
+		return "/" + file
+	}
+	if !strings.HasPrefix(name, "/") {
+		return "/" + name
+	}
+	return name
+}
@@ -0,0 +1,44 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		".vite/manifest.json": {Data: []byte(`{
+			"src/main.ts": {"file": "assets/main.4f3a9c.js", "src": "src/main.ts", "isEntry": true},
+			"style.css": "/assets/style.9b2e1d.css"
+		}`)},
+		"assets/main.4f3a9c.js":   {Data: []byte("main")},
+		"assets/style.9b2e1d.css": {Data: []byte("style")},
+		"assets/main.5e6f7a.js":   {Data: []byte("next")},
+	}
+	mw, h := NewHandle(Filesystem(fsys), Manifest(".vite/manifest.json"))
+
+	assert := assert.New(t)
+	assert.Equal("/assets/main.4f3a9c.js", h.ResolveAsset("src/main.ts"))
+	assert.Equal("/assets/style.9b2e1d.css", h.ResolveAsset("/style.css"))
+	assert.Equal("/none.js", h.ResolveAsset("none.js"))
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/src/main.ts", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("main", rec.Body.String())
+	}
+
+	// The manifest is reloaded when it changes.
+	fsys[".vite/manifest.json"] = &fstest.MapFile{
+		Data:    []byte(`{"src/main.ts": {"file": "assets/main.5e6f7a.js"}}`),
+		ModTime: time.Now(),
+	}
+	assert.Equal("/assets/main.5e6f7a.js", h.ResolveAsset("src/main.ts"))
+}
@@ -0,0 +1,129 @@
+package static
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/goroute/route"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const markdownHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>{{ .Name }}</title>
+  <style>
+    body {
+      font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+      line-height: 1.6;
+      max-width: 860px;
+      margin: 0 auto;
+      padding: 48px 16px;
+      color: #24292f;
+    }
+    pre, code {
+      font-family: Menlo, Consolas, monospace;
+      background: #f6f8fa;
+    }
+    pre {
+      padding: 16px;
+      overflow: auto;
+    }
+    table {
+      border-collapse: collapse;
+    }
+    th, td {
+      border: 1px solid #d0d7de;
+      padding: 6px 13px;
+    }
+  </style>
+</head>
+<body>
+  <article>{{ .Content }}</article>
+</body>
+</html>
+`
+
+type (
+	// MarkdownRenderer renders Markdown to HTML.
+	MarkdownRenderer interface {
+		Render(w io.Writer, source []byte) error
+	}
+
+	// MarkdownPage is the data of the page of a rendered Markdown file.
+	MarkdownPage struct {
+		// URL path of the file.
+		Name string
+
+		// Rendered HTML.
+		Content template.HTML
+	}
+
+	// goldmarkRenderer is the default MarkdownRenderer, rendering GitHub
+	// Flavored Markdown without raw HTML.
+	goldmarkRenderer struct {
+		md goldmark.Markdown
+	}
+)
+
+var markdownTemplate = template.Must(template.New("markdown").Parse(markdownHTML))
+
+func newGoldmarkRenderer() MarkdownRenderer {
+	return goldmarkRenderer{goldmark.New(goldmark.WithExtensions(extension.GFM))}
+}
+
+func (r goldmarkRenderer) Render(w io.Writer, source []byte) error {
+	return r.md.Convert(source, w)
+}
+
+// isMarkdown reports whether the file name has a Markdown extension.
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// rendersMarkdown reports whether the named file is sent rendered to HTML
+// rather than as is.
+func (s *server) rendersMarkdown(c route.Context, name string) bool {
+	return s.RenderMarkdown && isMarkdown(name) && !s.download(c, name)
+}
+
+// renderMarkdown sends the named Markdown file rendered to an HTML page.
+func (s *server) renderMarkdown(c route.Context, name string, fi fs.FileInfo) (err error) {
+	if s.Authorize != nil {
+		if err = s.Authorize(c, "/"+name, fi); err != nil {
+			return
+		}
+	}
+	source, err := fs.ReadFile(s.fsys, name)
+	if err != nil {
+		return
+	}
+	content := new(bytes.Buffer)
+	if err = s.MarkdownRenderer.Render(content, source); err != nil {
+		return
+	}
+	buf := new(bytes.Buffer)
+	err = markdownTemplate.Execute(buf, MarkdownPage{
+		Name:    path.Join("/", name),
+		Content: template.HTML(content.String()),
+	})
+	if err != nil {
+		return
+	}
+
+	c.Response().Header().Set(route.HeaderContentType, route.MIMETextHTMLCharsetUTF8)
+	s.setHeaders(c, name, "")
+	s.serveContent(c, c.Request(), "", fi.ModTime(), bytes.NewReader(buf.Bytes()))
+	return
+}
@@ -0,0 +1,73 @@
+package static
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+type upperRenderer struct{}
+
+func (upperRenderer) Render(w io.Writer, source []byte) error {
+	_, err := io.WriteString(w, strings.ToUpper(string(source)))
+	return err
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/guide.md": {Data: []byte("# Guide\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n<script>alert(1)</script>\n")},
+		"notes.txt":     {Data: []byte("# not markdown")},
+	}
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), RenderMarkdown(true)),
+		New(Filesystem(fsys), RenderMarkdown(true), Cache(1<<20, 0, 0)),
+	} {
+		// Source, cached if enabled
+		req := httptest.NewRequest(http.MethodGet, "/docs/guide.md?download=1", nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.True(strings.HasPrefix(rec.Body.String(), "# Guide"))
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/docs/guide.md", nil)
+		rec = httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal(route.MIMETextHTMLCharsetUTF8, rec.Header().Get(route.HeaderContentType))
+			assert.Contains(rec.Body.String(), "<title>/docs/guide.md</title>")
+			assert.Contains(rec.Body.String(), "<h1>Guide</h1>")
+			assert.Contains(rec.Body.String(), "<table>")
+			assert.NotContains(rec.Body.String(), "<script>")
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/notes.txt", nil)
+		rec = httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal("# not markdown", rec.Body.String())
+		}
+	}
+
+	// Custom renderer
+	mw := New(Filesystem(fsys), Markdown(upperRenderer{}))
+	req := httptest.NewRequest(http.MethodGet, "/docs/guide.md", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), "<article># GUIDE")
+	}
+
+	// Disabled
+	mw = New(Filesystem(fsys))
+	req = httptest.NewRequest(http.MethodGet, "/docs/guide.md", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.True(strings.HasPrefix(rec.Body.String(), "# Guide"))
+	}
+}
@@ -0,0 +1,54 @@
+package static
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// allow returns the methods allowed for a file or directory, as the value of
+// the Allow header.
+func (s *server) allow(fi fs.FileInfo) string {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	if s.Upload && s.Browse && fi.IsDir() || s.AllowRename {
+		methods = append(methods, http.MethodPost)
+	}
+	if s.AllowDelete {
+		methods = append(methods, http.MethodDelete)
+	}
+	return strings.Join(methods, ", ")
+}
+
+// options answers an OPTIONS request for the named file with the methods
+// allowed for it.
+func (s *server) options(c route.Context, name string, next route.HandlerFunc) error {
+	fi, err := s.stat(c.Request().Context(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return next(c)
+	}
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set(route.HeaderAllow, s.allow(fi))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkMethod returns route.ErrMethodNotAllowed if methods are restricted and
+// the request method isn't allowed for the file or directory.
+func (s *server) checkMethod(c route.Context, fi fs.FileInfo) error {
+	if !s.RestrictMethods {
+		return nil
+	}
+	allow := s.allow(fi)
+	method := c.Request().Method
+	for _, m := range strings.Split(allow, ", ") {
+		if m == method {
+			return nil
+		}
+	}
+	c.Response().Header().Set(route.HeaderAllow, allow)
+	return route.ErrMethodNotAllowed
+}
@@ -0,0 +1,92 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticOptions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("a")},
+		"docs/b.md": {Data: []byte("b")},
+	}
+	mw := New(Filesystem(fsys))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	for _, target := range []string{"/a.txt", "/docs/"} {
+		req := httptest.NewRequest(http.MethodOptions, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal(http.StatusNoContent, rec.Code)
+			assert.Equal("GET, HEAD, OPTIONS", rec.Header().Get(route.HeaderAllow))
+			assert.Empty(rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/missing.txt", nil)
+	rec := httptest.NewRecorder()
+	assert.Equal(route.ErrNotFound, mw(mux.NewContext(req, rec), route.NotFoundHandler))
+
+	// Uploads and deletes
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	mw = New(Root(root), Browse(true), Upload(true), AllowDelete(true), Authorize(authorizeAll))
+	for target, want := range map[string]string{
+		"/":      "GET, HEAD, OPTIONS, POST, DELETE",
+		"/a.txt": "GET, HEAD, OPTIONS, DELETE",
+	} {
+		req := httptest.NewRequest(http.MethodOptions, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal(want, rec.Header().Get(route.HeaderAllow), target)
+		}
+	}
+}
+
+func TestStaticRestrictMethods(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), RestrictMethods(true)),
+		New(Filesystem(fsys), RestrictMethods(true), Cache(1<<20, 0, 0)),
+	} {
+		mux := route.NewServeMux()
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut} {
+			req := httptest.NewRequest(method, "/a.txt", nil)
+			rec := httptest.NewRecorder()
+			err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+			if method == http.MethodGet {
+				assert.NoError(err)
+				assert.Equal("a", rec.Body.String())
+				continue
+			}
+			assert.Equal(route.ErrMethodNotAllowed, err, method)
+			assert.Equal("GET, HEAD, OPTIONS", rec.Header().Get(route.HeaderAllow), method)
+			assert.Empty(rec.Body.String())
+		}
+
+		// Missing files are still not found.
+		req := httptest.NewRequest(http.MethodPost, "/missing.txt", nil)
+		rec := httptest.NewRecorder()
+		assert.Equal(route.ErrNotFound, mw(mux.NewContext(req, rec), route.NotFoundHandler))
+	}
+
+	// Served by default
+	mw := New(Filesystem(fsys))
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodPost, "/a.txt", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("a", rec.Body.String())
+	}
+}
@@ -0,0 +1,102 @@
+package static
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/goroute/route"
+)
+
+type (
+	// Collector collects the metrics of the requests served by the
+	// middleware, e.g. to export them to a monitoring system. See the
+	// prometheus package for a Prometheus implementation.
+	Collector interface {
+		Observe(m RequestMetrics)
+	}
+
+	// RequestMetrics are the metrics of a request served by the middleware.
+	RequestMetrics struct {
+		// URL path of the request.
+		Path string
+
+		// Status code of the response.
+		Status int
+
+		// Number of bytes of the body sent, before compression.
+		Bytes int64
+
+		// Time taken to serve the request.
+		Duration time.Duration
+
+		// Whether the file was served from the cache of file contents.
+		CacheHit bool
+	}
+
+	// ServeEvent describes a request served by the middleware.
+	ServeEvent struct {
+		// URL path of the request.
+		Path string
+
+		// Path from the root of the file served, e.g. "/docs/index.html" for
+		// "/docs/". Empty if no file was served, e.g. for directory listings.
+		File string
+
+		// Status code of the response.
+		Status int
+
+		// Number of bytes of the body sent, before compression.
+		Size int64
+
+		// Time taken to serve the request.
+		Duration time.Duration
+
+		// Whether the file was served from the cache of file contents.
+		CacheHit bool
+
+		// Whether the client's copy was fresh, answered with status 304.
+		NotModified bool
+	}
+)
+
+// servedFileKey is the context key of the path of the file served, set when
+// OnServe is.
+const servedFileKey = "static.file"
+
+// observe reports a request served to the collector and the OnServe callback.
+func (s *server) observe(c route.Context, d time.Duration, hit bool, err error) {
+	code := status(c, err)
+	if s.Metrics != nil {
+		s.Metrics.Observe(RequestMetrics{
+			Path:     c.Request().URL.Path,
+			Status:   code,
+			Bytes:    c.Response().Size,
+			Duration: d,
+			CacheHit: hit,
+		})
+	}
+	if s.OnServe != nil {
+		file, _ := c.Get(servedFileKey).(string)
+		s.OnServe(ServeEvent{
+			Path:        c.Request().URL.Path,
+			File:        file,
+			Status:      code,
+			Size:        c.Response().Size,
+			Duration:    d,
+			CacheHit:    hit,
+			NotModified: code == http.StatusNotModified,
+		})
+	}
+}
+
+// status returns the status code of the response to a request served with
+// the error err.
+func status(c route.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+	if he, ok := err.(*route.HTTPError); ok {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
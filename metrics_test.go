@@ -0,0 +1,80 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+type collectorFunc func(RequestMetrics)
+
+func (f collectorFunc) Observe(m RequestMetrics) { f(m) }
+
+func TestMetrics(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.txt": {Data: []byte("hello")},
+	}
+	var metrics []RequestMetrics
+	mw := New(Filesystem(fsys), Cache(1<<20, 0, 0), Metrics(collectorFunc(func(m RequestMetrics) {
+		metrics = append(metrics, m)
+	})))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	for _, p := range []string{"/file.txt", "/file.txt", "/missing"} {
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		rec := httptest.NewRecorder()
+		mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	}
+	// Handled by the next handler
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	rec := httptest.NewRecorder()
+	mw(mux.NewContext(req, rec), func(c route.Context) error {
+		return c.String(http.StatusOK, "api")
+	})
+
+	if assert.Len(metrics, 3) {
+		assert.Equal("/file.txt", metrics[0].Path)
+		assert.Equal(http.StatusOK, metrics[0].Status)
+		assert.Equal(int64(5), metrics[0].Bytes)
+		assert.False(metrics[0].CacheHit)
+		assert.True(metrics[1].CacheHit)
+		assert.Equal("/missing", metrics[2].Path)
+		assert.Equal(http.StatusNotFound, metrics[2].Status)
+	}
+}
+
+func TestOnServe(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/index.html": {Data: []byte("<p>docs</p>"), ModTime: time.Unix(1e9, 0)},
+	}
+	var events []ServeEvent
+	mw := New(Filesystem(fsys), OnServe(func(e ServeEvent) {
+		events = append(events, e)
+	}))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/docs/", nil)
+	rec := httptest.NewRecorder()
+	mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	req = httptest.NewRequest(http.MethodGet, "/docs/", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get(headerETag))
+	rec = httptest.NewRecorder()
+	mw(mux.NewContext(req, rec), route.NotFoundHandler)
+
+	if assert.Len(events, 2) {
+		assert.Equal("/docs/", events[0].Path)
+		assert.Equal("/docs/index.html", events[0].File)
+		assert.Equal(http.StatusOK, events[0].Status)
+		assert.Equal(int64(11), events[0].Size)
+		assert.False(events[0].NotModified)
+		assert.Equal(http.StatusNotModified, events[1].Status)
+		assert.True(events[1].NotModified)
+	}
+}
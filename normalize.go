@@ -0,0 +1,28 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalize returns the named file normalized to the Unicode normalization
+// form of the server, unless only name matches a file.
+func (s *server) normalize(ctx context.Context, name string) string {
+	form := norm.NFC
+	if s.UnicodeNormalization == "NFD" {
+		form = norm.NFD
+	}
+	if form.IsNormalString(name) {
+		return name
+	}
+	normalized := form.String(name)
+	if _, err := s.stat(ctx, normalized); errors.Is(err, fs.ErrNotExist) {
+		if _, err := s.stat(ctx, name); err == nil {
+			return name
+		}
+	}
+	return normalized
+}
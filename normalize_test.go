@@ -0,0 +1,43 @@
+package static
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnicodeNormalization(t *testing.T) {
+	const (
+		composed   = "caf\u00e9"  // "é"
+		decomposed = "cafe\u0301" // "e" and a combining acute accent
+	)
+	fsys := fstest.MapFS{
+		composed + ".txt":            {Data: []byte("nfc")},
+		"mac/" + decomposed + ".txt": {Data: []byte("nfd")},
+	}
+	target := func(name string) string {
+		return (&url.URL{Path: "/" + name}).EscapedPath()
+	}
+
+	assert := assert.New(t)
+	mw := New(Filesystem(fsys))
+	for name, want := range map[string]string{
+		composed + ".txt":            "nfc",
+		decomposed + ".txt":          "nfc",
+		"mac/" + decomposed + ".txt": "nfd",
+	} {
+		rec := serve(t, mw, http.MethodGet, target(name))
+		assert.Equal(http.StatusOK, rec.Code, name)
+		assert.Equal(want, rec.Body.String(), name)
+	}
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, target("mac/"+composed+".txt")).Code)
+
+	mw = New(Filesystem(fsys), UnicodeNormalization("NFD"))
+	assert.Equal("nfd", serve(t, mw, http.MethodGet, target("mac/"+composed+".txt")).Body.String())
+
+	mw = New(Filesystem(fsys), UnicodeNormalization(""))
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, target(decomposed+".txt")).Code)
+}
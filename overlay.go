@@ -0,0 +1,104 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// overlayFS is a read-only union of filesystems. Files of earlier filesystems
+// shadow the files with the same name in later ones.
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (f fs.File, err error) {
+	for _, fsys := range o {
+		if f, err = fsys.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		// Directories list the merged entries.
+		return &overlayDir{File: f, fsys: o, name: name}, nil
+	}
+	return
+}
+
+func (o overlayFS) Stat(name string) (fi fs.FileInfo, err error) {
+	for _, fsys := range o {
+		if fi, err = fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+	}
+	return
+}
+
+// ReadDir merges the entries of the named directory in every filesystem.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		entries []fs.DirEntry
+		seen    = map[string]bool{}
+		found   bool
+		err     error
+	)
+	for _, fsys := range o {
+		list, e := fs.ReadDir(fsys, name)
+		if e != nil {
+			if !errors.Is(e, fs.ErrNotExist) {
+				return nil, e
+			}
+			err = e
+			continue
+		}
+		found = true
+		for _, entry := range list {
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// overlayDir is a directory of an overlayFS.
+type overlayDir struct {
+	fs.File
+	fsys    overlayFS
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
@@ -0,0 +1,16 @@
+package static
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	o := overlayFS{
+		fstest.MapFS{"a.txt": {Data: []byte("upper")}, "dir/b.txt": {}},
+		fstest.MapFS{"a.txt": {Data: []byte("lower")}, "c.txt": {}, "dir/d.txt": {}},
+	}
+	if err := fstest.TestFS(o, "a.txt", "c.txt", "dir/b.txt", "dir/d.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,213 @@
+package static
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/goroute/route"
+)
+
+type (
+	// precompressedFiles are the compressed variants of the files generated
+	// when the server is created, by name.
+	precompressedFiles struct {
+		// Directory of the variants, or "" if they are in memory.
+		dir   string
+		files map[string]*precompressedFile
+	}
+
+	// precompressedFile is the compressed variants of a file.
+	precompressedFile struct {
+		size    int64
+		modTime time.Time
+
+		// SHA-256 of the content, naming the variants in dir.
+		hash string
+
+		// Content by Content-Encoding, only for the encodings smaller than
+		// the file. The content is nil if it is in dir.
+		encoded map[string][]byte
+	}
+
+	// bytesFile is a compressed variant in memory.
+	bytesFile struct {
+		*bytes.Reader
+	}
+)
+
+// precompressEncoders are the encoders of precompressed variants, by
+// Content-Encoding.
+var precompressEncoders = map[string]func(io.Writer) io.WriteCloser{
+	"br": func(w io.Writer) io.WriteCloser {
+		return brotli.NewWriterLevel(w, brotli.BestCompression)
+	},
+	"gzip": func(w io.Writer) io.WriteCloser {
+		zw, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+		return zw
+	},
+}
+
+// precompress generates the compressed variants of the visible files of the
+// server whose type is compressed.
+func (s *server) precompress() error {
+	p := &precompressedFiles{dir: s.PrecompressDir, files: map[string]*precompressedFile{}}
+	if p.dir != "" {
+		if err := os.MkdirAll(p.dir, 0o755); err != nil {
+			return err
+		}
+	}
+	err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if !s.visible(name, d.IsDir()) || s.escapes(name) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || s.Precompressed && isSidecar(name) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil || fi.Size() < s.CompressMinSize || s.tooLarge(fi) {
+			return err
+		}
+		data, err := fs.ReadFile(s.fsys, name)
+		if err != nil {
+			return err
+		}
+		if !s.compressType(s.contentType(name, bytes.NewReader(data))) {
+			return nil
+		}
+		pf, err := p.compress(data)
+		if err != nil {
+			return err
+		}
+		if len(pf.encoded) > 0 {
+			pf.size, pf.modTime = fi.Size(), fi.ModTime()
+			p.files[name] = pf
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.precompressed = p
+	return nil
+}
+
+// compress returns the variants of the content smaller than it, writing them
+// to dir unless they already are.
+func (p *precompressedFiles) compress(data []byte) (*precompressedFile, error) {
+	sum := sha256.Sum256(data)
+	pf := &precompressedFile{hash: hex.EncodeToString(sum[:]), encoded: map[string][]byte{}}
+	for _, pe := range precompressedEncodings {
+		file := filepath.Join(p.dir, pf.hash+pe.ext)
+		if p.dir != "" {
+			if _, err := os.Stat(file); err == nil {
+				pf.encoded[pe.encoding] = nil
+				continue
+			}
+		}
+		buf := new(bytes.Buffer)
+		zw := precompressEncoders[pe.encoding](buf)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		if buf.Len() >= len(data) {
+			continue
+		}
+		if p.dir == "" {
+			pf.encoded[pe.encoding] = buf.Bytes()
+			continue
+		}
+		if err := writeFileAtomic(file, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		pf.encoded[pe.encoding] = nil
+	}
+	return pf, nil
+}
+
+// lookup returns the variants of the named file, or nil if there are none or
+// the file changed since they were generated.
+func (p *precompressedFiles) lookup(name string, fi fs.FileInfo) *precompressedFile {
+	pf := p.files[name]
+	if pf == nil || pf.size != fi.Size() || !pf.modTime.Equal(fi.ModTime()) {
+		return nil
+	}
+	return pf
+}
+
+// open opens the preferred variant of the named file accepted by the client.
+// It returns a nil file if there is none.
+func (p *precompressedFiles) open(r *http.Request, name string, fi fs.FileInfo) (io.ReadSeekCloser, string) {
+	pf := p.lookup(name, fi)
+	if pf == nil {
+		return nil, ""
+	}
+	accept := r.Header.Get(route.HeaderAcceptEncoding)
+	for _, pe := range precompressedEncodings {
+		b, ok := pf.encoded[pe.encoding]
+		if !ok || !acceptsEncoding(accept, pe.encoding) {
+			continue
+		}
+		if p.dir == "" {
+			return bytesFile{bytes.NewReader(b)}, pe.encoding
+		}
+		if f, err := os.Open(filepath.Join(p.dir, pf.hash+pe.ext)); err == nil {
+			return f, pe.encoding
+		}
+	}
+	return nil, ""
+}
+
+// read returns the variants of the named file, by Content-Encoding.
+func (p *precompressedFiles) read(name string, fi fs.FileInfo) map[string][]byte {
+	pf := p.lookup(name, fi)
+	if pf == nil {
+		return nil
+	}
+	if p.dir == "" {
+		return pf.encoded
+	}
+	encoded := map[string][]byte{}
+	for _, pe := range precompressedEncodings {
+		if _, ok := pf.encoded[pe.encoding]; !ok {
+			continue
+		}
+		if b, err := os.ReadFile(filepath.Join(p.dir, pf.hash+pe.ext)); err == nil {
+			encoded[pe.encoding] = b
+		}
+	}
+	return encoded
+}
+
+// isSidecar reports whether the named file is a precompressed sidecar.
+func isSidecar(name string) bool {
+	for _, pe := range precompressedEncodings {
+		if strings.HasSuffix(name, pe.ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (bytesFile) Close() error {
+	return nil
+}
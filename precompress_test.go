@@ -0,0 +1,84 @@
+package static
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrecompress(t *testing.T) {
+	root, dir := t.TempDir(), t.TempDir()
+	script := strings.Repeat("console.log('precompressed');\n", 100)
+	os.WriteFile(filepath.Join(root, "app.js"), []byte(script), 0o644)
+	os.WriteFile(filepath.Join(root, "small.js"), []byte("small"), 0o644)
+	os.WriteFile(filepath.Join(root, "image.png"), []byte(strings.Repeat("\x89PNG", 1000)), 0o644)
+
+	assert := assert.New(t)
+	decode := func(rec *httptest.ResponseRecorder) string {
+		var r io.Reader = rec.Body
+		switch rec.Header().Get(route.HeaderContentEncoding) {
+		case "br":
+			r = brotli.NewReader(r)
+		case "gzip":
+			zr, err := gzip.NewReader(r)
+			if !assert.NoError(err) {
+				return ""
+			}
+			r = zr
+		}
+		b, err := io.ReadAll(r)
+		assert.NoError(err)
+		return string(b)
+	}
+
+	for _, options := range [][]Option{
+		{Root(root), Precompress(true)},
+		{Root(root), Precompress(true), PrecompressDir(dir)},
+		{Root(root), Precompress(true), Cache(1<<20, 0, 0)},
+	} {
+		mw := New(options...)
+		etags := map[string]bool{}
+		for accept, encoding := range map[string]string{"gzip, br": "br", "gzip": "gzip", "": ""} {
+			rec := serve(t, mw, http.MethodGet, "/app.js", route.HeaderAcceptEncoding, accept)
+			assert.Equal(encoding, rec.Header().Get(route.HeaderContentEncoding), accept)
+			assert.Equal(route.HeaderAcceptEncoding, rec.Header().Get(route.HeaderVary))
+			assert.Contains(rec.Header().Get(route.HeaderContentType), "javascript")
+			assert.Equal(script, decode(rec))
+			etags[rec.Header().Get(headerETag)] = true
+
+			// HEAD requests get the headers of GET ones.
+			head := serve(t, mw, http.MethodHead, "/app.js", route.HeaderAcceptEncoding, accept)
+			for _, k := range []string{route.HeaderContentEncoding, route.HeaderContentLength, route.HeaderVary, headerETag} {
+				assert.Equal(rec.Header().Get(k), head.Header().Get(k), k)
+			}
+		}
+		// Each encoding has its own entity tag.
+		assert.Len(etags, 3)
+		for _, path := range []string{"/small.js", "/image.png"} {
+			rec := serve(t, mw, http.MethodGet, path, route.HeaderAcceptEncoding, "br")
+			assert.Equal(http.StatusOK, rec.Code, path)
+			assert.Empty(rec.Header().Get(route.HeaderContentEncoding), path)
+		}
+	}
+
+	// Stored by content hash.
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Len(files, 2)
+
+	// Changed files are sent as is.
+	mw := New(Root(root), Precompress(true))
+	os.Chtimes(filepath.Join(root, "app.js"), time.Now(), time.Now().Add(time.Hour))
+	rec := serve(t, mw, http.MethodGet, "/app.js", route.HeaderAcceptEncoding, "br")
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Empty(rec.Header().Get(route.HeaderContentEncoding))
+}
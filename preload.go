@@ -0,0 +1,113 @@
+package static
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// headerLink is the Link header of preloaded assets.
+const headerLink = "Link"
+
+// pushedHeaders are the request headers of pushed assets copied from the
+// request of the page, for their variants to match.
+var pushedHeaders = []string{route.HeaderAcceptEncoding, headerAcceptLanguage, route.HeaderAccept}
+
+// preloadLinks returns the Link values preloading the assets of the rules
+// matching the named file, and of those of Push which can't be pushed.
+func (s *server) preloadLinks(c route.Context, name string) []string {
+	var links []string
+	for _, asset := range s.push(c, name) {
+		links = append(links, preloadLink(asset))
+	}
+	for _, r := range s.Preload {
+		if !matchAny(r.Patterns, name) {
+			continue
+		}
+		for _, asset := range r.Assets {
+			links = append(links, preloadLink(asset))
+		}
+	}
+	return links
+}
+
+// preloadLink returns the Link value preloading the asset at the URL, with
+// the destination of its type, e.g. `</app.js>; rel=preload; as=script`.
+func preloadLink(asset string) string {
+	link := "<" + asset + ">; rel=preload"
+	u := asset
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	switch strings.ToLower(path.Ext(u)) {
+	case ".js", ".mjs":
+		link += "; as=script"
+	case ".css":
+		link += "; as=style"
+	case ".woff", ".woff2", ".ttf", ".otf":
+		// Fonts are always fetched in CORS mode.
+		link += "; as=font; crossorigin"
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg", ".ico":
+		link += "; as=image"
+	case ".json":
+		link += "; as=fetch; crossorigin"
+	}
+	return link
+}
+
+// setPreloadHeaders pushes the assets of the named file, and adds the Link
+// headers preloading the others, first sent in a 103 Early Hints response if
+// enabled.
+func (s *server) setPreloadHeaders(c route.Context, name string) {
+	links := s.preloadLinks(c, name)
+	if len(links) == 0 {
+		return
+	}
+	header := c.Response().Header()
+	for _, link := range links {
+		header.Add(headerLink, link)
+	}
+	if r := c.Request(); s.EarlyHints && r.Method == http.MethodGet && r.ProtoAtLeast(1, 1) && !c.Response().Committed {
+		// Only the Link headers are hinted.
+		hints := c.Response().Writer
+		final := hints.Header().Clone()
+		for k := range hints.Header() {
+			if k != headerLink {
+				hints.Header().Del(k)
+			}
+		}
+		hints.WriteHeader(http.StatusEarlyHints)
+		for k, v := range final {
+			hints.Header()[k] = v
+		}
+	}
+}
+
+// push pushes the assets of the named file with it if the connection
+// supports it, and returns those which weren't pushed.
+func (s *server) push(c route.Context, name string) []string {
+	assets := s.Push["/"+name]
+	if len(assets) == 0 {
+		return nil
+	}
+	r := c.Request()
+	pusher, ok := c.Response().Writer.(http.Pusher)
+	if !ok || r.Method != http.MethodGet {
+		return assets
+	}
+	opts := &http.PushOptions{Header: http.Header{}}
+	for _, k := range pushedHeaders {
+		if v := r.Header.Get(k); v != "" {
+			opts.Header.Set(k, v)
+		}
+	}
+	for i, asset := range assets {
+		if err := pusher.Push(asset, opts); err != nil {
+			// Unsupported by the client, or disabled by it meanwhile.
+			return assets[i:]
+		}
+	}
+	return nil
+}
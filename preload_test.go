@@ -0,0 +1,154 @@
+package static
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<p>Hi</p>")},
+		"admin/index.html": {Data: []byte("<p>Admin</p>")},
+		"app.js":           {Data: []byte("app")},
+	}
+	mw := New(Filesystem(fsys), Preload(
+		PreloadRule{Patterns: []string{"*.html"}, Assets: []string{"/app.js", "/app.css"}},
+		PreloadRule{Patterns: []string{"/admin/**"}, Assets: []string{"/inter.woff2?v=2"}},
+	))
+	assert := assert.New(t)
+	for target, links := range map[string][]string{
+		"/":           {"</app.js>; rel=preload; as=script", "</app.css>; rel=preload; as=style"},
+		"/admin/":     {"</app.js>; rel=preload; as=script", "</app.css>; rel=preload; as=style", "</inter.woff2?v=2>; rel=preload; as=font; crossorigin"},
+		"/app.js":     nil,
+		"/index.html": {"</app.js>; rel=preload; as=script", "</app.css>; rel=preload; as=style"},
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			assert.Equal(http.StatusOK, rec.Code, target)
+			assert.Equal(links, rec.Header()[headerLink], target)
+		}
+	}
+}
+
+func TestEarlyHints(t *testing.T) {
+	page := "<p>" + strings.Repeat("Hi", 1000) + "</p>"
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(page)},
+	}
+	assert := assert.New(t)
+	for _, compress := range []bool{false, true} {
+		mw := New(Filesystem(fsys), Compress(compress), EarlyHints(true), Preload(PreloadRule{Patterns: []string{"/index.html"}, Assets: []string{"/app.js"}}))
+		mux := route.NewServeMux()
+		mux.Use(mw)
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		var hints []textproto.MIMEHeader
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+				if code == http.StatusEarlyHints {
+					hints = append(hints, header)
+				}
+				return nil
+			},
+		}
+		req, _ := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL+"/", nil)
+		// The transport requests and decompresses gzip.
+		res, err := http.DefaultClient.Do(req)
+		if assert.NoError(err) {
+			body, err := io.ReadAll(res.Body)
+			res.Body.Close()
+			assert.NoError(err)
+			assert.Equal(http.StatusOK, res.StatusCode)
+			assert.Equal(compress, res.Uncompressed)
+			assert.Equal(page, string(body))
+			assert.Equal("</app.js>; rel=preload; as=script", res.Header.Get(headerLink))
+			if assert.Len(hints, 1) {
+				assert.Equal("</app.js>; rel=preload; as=script", hints[0].Get(headerLink))
+				assert.Empty(hints[0].Get(route.HeaderContentType))
+				assert.Empty(hints[0].Get(route.HeaderContentEncoding))
+			}
+		}
+	}
+}
+
+func TestPreloadLink(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("</hero.webp>; rel=preload; as=image", preloadLink("/hero.webp"))
+	assert.Equal("</data>; rel=preload", preloadLink("/data"))
+}
+
+// pushRecorder is a ResponseRecorder supporting server push.
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+	header []http.Header
+	err    error
+}
+
+func (r *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.pushed = append(r.pushed, target)
+	r.header = append(r.header, opts.Header)
+	return nil
+}
+
+func TestPush(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<p>Hi</p>")},
+		"about.html": {Data: []byte("<p>About</p>")},
+	}
+	mw := New(Filesystem(fsys), Push(map[string][]string{"/index.html": {"/app.js", "/app.css"}}))
+	assert := assert.New(t)
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(route.HeaderAcceptEncoding, "br")
+	rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal([]string{"/app.js", "/app.css"}, rec.pushed)
+		assert.Equal("br", rec.header[0].Get(route.HeaderAcceptEncoding))
+		assert.Empty(rec.Header()[headerLink])
+	}
+
+	// Assets which can't be pushed are preloaded.
+	for _, w := range []http.ResponseWriter{httptest.NewRecorder(), &pushRecorder{ResponseRecorder: httptest.NewRecorder(), err: http.ErrNotSupported}} {
+		req := httptest.NewRequest(http.MethodGet, "/index.html", nil)
+		if assert.NoError(mw(mux.NewContext(req, w), route.NotFoundHandler)) {
+			assert.Equal([]string{"</app.js>; rel=preload; as=script", "</app.css>; rel=preload; as=style"}, w.Header()[headerLink])
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/about.html", nil)
+	rec = &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Empty(rec.pushed)
+		assert.Empty(rec.Header()[headerLink])
+	}
+
+	// Assets are pushed through compressed and throttled responses.
+	for _, opt := range []Option{Compress(true), Throttle(1<<20, 0)} {
+		mw := New(Filesystem(fsys), CompressMinSize(0), opt, Push(map[string][]string{"/index.html": {"/app.js"}}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(route.HeaderAcceptEncoding, "gzip")
+		rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal([]string{"/app.js"}, rec.pushed)
+			assert.Empty(rec.Header()[headerLink])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package prometheus exports the metrics of Static middlewares to Prometheus.
+//
+//	collector := prometheus.New("static", 100)
+//	prom.MustRegister(collector)
+//	mux.Use(static.New(static.Root("public"), static.Metrics(collector)))
+package prometheus
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/goroute/static"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// otherPaths is the path label of requests beyond the tracked paths.
+const otherPaths = "other"
+
+// Collector is a static.Collector exporting Prometheus metrics. It must be
+// registered, e.g. with prometheus.MustRegister.
+type Collector struct {
+	requests *prom.CounterVec
+	bytes    *prom.CounterVec
+	duration *prom.HistogramVec
+	paths    *prom.CounterVec
+
+	maxPaths int
+
+	mu      sync.Mutex
+	tracked map[string]bool
+}
+
+var _ static.Collector = (*Collector)(nil)
+var _ prom.Collector = (*Collector)(nil)
+
+// New returns a Collector of metrics prefixed with namespace, counting the
+// requests of up to maxPaths URL paths, the first requested ones. Requests
+// of other paths are counted under the path "other".
+func New(namespace string, maxPaths int) *Collector {
+	return &Collector{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests by status code and cache result.",
+		}, []string{"code", "cache"}),
+		bytes: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "response_bytes_total",
+			Help:      "Number of bytes of the bodies sent by status code.",
+		}, []string{"code"}),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to serve requests by status code.",
+			Buckets:   prom.DefBuckets,
+		}, []string{"code"}),
+		paths: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Name:      "path_requests_total",
+			Help:      "Number of requests by URL path.",
+		}, []string{"path"}),
+		maxPaths: maxPaths,
+		tracked:  map[string]bool{},
+	}
+}
+
+// Observe implements static.Collector.
+func (c *Collector) Observe(m static.RequestMetrics) {
+	code := strconv.Itoa(m.Status)
+	cache := "miss"
+	if m.CacheHit {
+		cache = "hit"
+	}
+	c.requests.WithLabelValues(code, cache).Inc()
+	c.bytes.WithLabelValues(code).Add(float64(m.Bytes))
+	c.duration.WithLabelValues(code).Observe(m.Duration.Seconds())
+	c.paths.WithLabelValues(c.path(m.Path)).Inc()
+}
+
+// path returns the path label of the URL path p.
+func (c *Collector) path(p string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.tracked[p] {
+		if len(c.tracked) >= c.maxPaths {
+			return otherPaths
+		}
+		c.tracked[p] = true
+	}
+	return p
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.requests.Describe(ch)
+	c.bytes.Describe(ch)
+	c.duration.Describe(ch)
+	c.paths.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.requests.Collect(ch)
+	c.bytes.Collect(ch)
+	c.duration.Collect(ch)
+	c.paths.Collect(ch)
+}
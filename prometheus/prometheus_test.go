@@ -0,0 +1,25 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goroute/static"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	c := New("static", 1)
+	c.Observe(static.RequestMetrics{Path: "/a", Status: 200, Bytes: 10, Duration: time.Millisecond})
+	c.Observe(static.RequestMetrics{Path: "/a", Status: 200, Bytes: 10, CacheHit: true})
+	c.Observe(static.RequestMetrics{Path: "/b", Status: 404, Bytes: 5})
+
+	assert := assert.New(t)
+	assert.Equal(1.0, testutil.ToFloat64(c.requests.WithLabelValues("200", "miss")))
+	assert.Equal(1.0, testutil.ToFloat64(c.requests.WithLabelValues("200", "hit")))
+	assert.Equal(20.0, testutil.ToFloat64(c.bytes.WithLabelValues("200")))
+	assert.Equal(2.0, testutil.ToFloat64(c.paths.WithLabelValues("/a")))
+	assert.Equal(1.0, testutil.ToFloat64(c.paths.WithLabelValues(otherPaths)))
+	assert.Equal(9, testutil.CollectAndCount(c))
+}
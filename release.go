@@ -0,0 +1,109 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/goroute/route"
+)
+
+type (
+	// ReleaseManager serves the files of the current release, a directory
+	// which is switched atomically for zero-downtime deploys, e.g.
+	//
+	//	releases, err := static.NewReleaseManager("/srv/releases/current", static.Precompressed(true))
+	//	mux.Use(releases.Serve)
+	//	...
+	//	err = releases.Switch(ctx, "/srv/releases/v42")
+	//
+	// Symlinks to releases are resolved when switching, so that requests in
+	// progress keep reading the release they started with when a symlink is
+	// updated.
+	ReleaseManager struct {
+		options []Option
+
+		mu      sync.RWMutex
+		current *release
+	}
+
+	// release is the server of a release directory.
+	release struct {
+		dir string
+		s   *server
+
+		// Requests in progress.
+		inflight sync.WaitGroup
+	}
+)
+
+// NewReleaseManager returns a ReleaseManager serving the directory dir with
+// the options, or an error if they are invalid. Root and Roots are set by the
+// release manager.
+func NewReleaseManager(dir string, options ...Option) (*ReleaseManager, error) {
+	m := &ReleaseManager{options: options}
+	r, err := m.newRelease(dir)
+	if err != nil {
+		return nil, err
+	}
+	m.current = r
+	return m, nil
+}
+
+// newRelease returns the release of the directory dir.
+func (m *ReleaseManager) newRelease(dir string) (*release, error) {
+	dir = realPath(dir)
+	s, err := newHandle(append(m.options[:len(m.options):len(m.options)], func(o *Options) {
+		o.Root, o.Roots = dir, nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return &release{dir: dir, s: s}, nil
+}
+
+// Serve is the Static middleware serving the current release.
+func (m *ReleaseManager) Serve(c route.Context, next route.HandlerFunc) error {
+	m.mu.RLock()
+	r := m.current
+	r.inflight.Add(1)
+	m.mu.RUnlock()
+	defer r.inflight.Done()
+	return r.s.serve(c, next)
+}
+
+// Current returns the directory of the current release, with symlinks
+// resolved.
+func (m *ReleaseManager) Current() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current.dir
+}
+
+// Switch makes the directory dir the current release, new requests being
+// served from it, then waits for the requests in progress on the previous
+// release to complete, or for the context to be done. The previous release
+// is kept if dir isn't a valid root, and closed once drained otherwise.
+func (m *ReleaseManager) Switch(ctx context.Context, dir string) error {
+	r, err := m.newRelease(dir)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	old := m.current
+	m.current = r
+	m.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		old.inflight.Wait()
+		old.s.close()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("static: draining release %s: %w", old.dir, ctx.Err())
+	}
+}
@@ -0,0 +1,81 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseManager(t *testing.T) {
+	base := t.TempDir()
+	for _, release := range []string{"v1", "v2"} {
+		os.Mkdir(filepath.Join(base, release), 0o755)
+		os.WriteFile(filepath.Join(base, release, "app.js"), []byte(release), 0o644)
+	}
+	current := filepath.Join(base, "current")
+	os.Symlink("v1", current)
+
+	m, err := NewReleaseManager(current, Cache(1<<20, 0, 0))
+	assert := assert.New(t)
+	if !assert.NoError(err) {
+		return
+	}
+	get := func(path string, next route.HandlerFunc) string {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		assert.NoError(m.Serve(mux.NewContext(req, rec), next))
+		return rec.Body.String()
+	}
+	assert.Equal("v1", get("/app.js", route.NotFoundHandler))
+	assert.Equal(realPath(filepath.Join(base, "v1")), m.Current())
+
+	// Requests in progress are drained.
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		get("/missing", func(c route.Context) error {
+			close(started)
+			<-release
+			return c.NoContent(http.StatusNoContent)
+		})
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = m.Switch(ctx, filepath.Join(base, "v2"))
+	assert.True(errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal("v2", get("/app.js", route.NotFoundHandler))
+	close(release)
+	<-done
+
+	assert.NoError(m.Switch(context.Background(), current))
+	assert.Equal("v1", get("/app.js", route.NotFoundHandler))
+
+	// Invalid releases are not switched to.
+	assert.Error(m.Switch(context.Background(), filepath.Join(base, "v3")))
+	assert.Equal("v1", get("/app.js", route.NotFoundHandler))
+
+	// Drained releases are closed.
+	m, err = NewReleaseManager(current, Events("/__events"))
+	if !assert.NoError(err) {
+		return
+	}
+	old := m.current
+	assert.NoError(m.Switch(context.Background(), filepath.Join(base, "v2")))
+	select {
+	case <-old.s.watcher.(*watcher).done:
+	default:
+		assert.Fail("watcher of the previous release not closed")
+	}
+	assert.Equal("v2", get("/app.js", route.NotFoundHandler))
+}
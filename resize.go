@@ -0,0 +1,312 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Decoder of resized images.
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// Image resizing.
+const (
+	// imageMaxDimension is the maximum width and height of resized images
+	// without ImageSizes.
+	imageMaxDimension = 4096
+
+	// imageCacheSize is the size in bytes of the in-memory cache of resized
+	// images.
+	imageCacheSize = 32 << 20
+
+	// thumbnailSize is the width and height of the thumbnails of directory
+	// listings, which ImageSizes always allows.
+	thumbnailSize = 64
+)
+
+// resizable reports whether the named file is an image which can be resized.
+func resizable(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// resizes reports whether the request is for a resized image.
+func (s *server) resizes(c route.Context, name string) bool {
+	if !s.ImageResize || !resizable(name) {
+		return false
+	}
+	return c.QueryParam("w") != "" || c.QueryParam("h") != ""
+}
+
+// imageSize is the size of a resized image requested with the "w", "h" and
+// "fit" query parameters.
+type imageSize struct {
+	w, h int
+	fit  string
+}
+
+// parseImageSize returns the requested image size.
+func (s *server) parseImageSize(c route.Context) (imageSize, error) {
+	var (
+		size imageSize
+		err  error
+	)
+	if v := c.QueryParam("w"); v != "" {
+		if size.w, err = strconv.Atoi(v); err != nil || size.w <= 0 {
+			return size, route.NewHTTPError(http.StatusBadRequest, "invalid width")
+		}
+	}
+	if v := c.QueryParam("h"); v != "" {
+		if size.h, err = strconv.Atoi(v); err != nil || size.h <= 0 {
+			return size, route.NewHTTPError(http.StatusBadRequest, "invalid height")
+		}
+	}
+	switch size.fit = c.QueryParam("fit"); size.fit {
+	case "":
+		size.fit = "contain"
+	case "contain", "cover", "fill":
+	default:
+		return size, route.NewHTTPError(http.StatusBadRequest, "invalid fit")
+	}
+
+	if len(s.ImageSizes) == 0 {
+		if size.w > imageMaxDimension || size.h > imageMaxDimension {
+			return size, route.NewHTTPError(http.StatusBadRequest, "image size not allowed")
+		}
+		return size, nil
+	}
+	if s.Browse && size == (imageSize{thumbnailSize, thumbnailSize, "cover"}) {
+		return size, nil
+	}
+	for _, allowed := range s.ImageSizes {
+		if allowed == size.dimensions() {
+			return size, nil
+		}
+	}
+	return size, route.NewHTTPError(http.StatusBadRequest, "image size not allowed")
+}
+
+// dimensions returns the size as in ImageSizes, e.g. "300x200", "300x" or
+// "x200".
+func (size imageSize) dimensions() string {
+	var w, h string
+	if size.w > 0 {
+		w = strconv.Itoa(size.w)
+	}
+	if size.h > 0 {
+		h = strconv.Itoa(size.h)
+	}
+	return w + "x" + h
+}
+
+// resizedImage is a resized image.
+type resizedImage struct {
+	ctype string
+	data  []byte
+}
+
+// serveResized sends the named image resized to the requested size.
+func (s *server) serveResized(c route.Context, name string, fi os.FileInfo) error {
+	size, err := s.parseImageSize(c)
+	if err != nil {
+		return err
+	}
+	if s.OnServe != nil {
+		c.Set(servedFileKey, "/"+name)
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(c, "/"+name, fi); err != nil {
+			return err
+		}
+	}
+
+	// Variants of a modified image have another digest.
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d\x00%s\x00%s", name, fi.Size(), fi.ModTime().UnixNano(), size.dimensions(), size.fit)))
+	digest := hex.EncodeToString(sum[:8])
+	img, err := s.resizedImage(c.Request().Context(), name, size, digest)
+	if err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(route.HeaderContentType, img.ctype)
+	s.setHeaders(c, name, `"`+digest+`"`)
+	s.serveContent(c, c.Request(), "", fi.ModTime(), bytes.NewReader(img.data))
+	return nil
+}
+
+// resizedImage returns the named image resized, from the cache of resized
+// images if possible.
+func (s *server) resizedImage(ctx context.Context, name string, size imageSize, digest string) (*resizedImage, error) {
+	ext := ".png"
+	if e := strings.ToLower(path.Ext(name)); e == ".jpg" || e == ".jpeg" {
+		ext = ".jpg"
+	}
+	ctype := "image/png"
+	if ext == ".jpg" {
+		ctype = "image/jpeg"
+	}
+
+	var file, key string
+	if s.ImageCacheDir != "" {
+		file = filepath.Join(s.ImageCacheDir, digest+ext)
+		if data, err := os.ReadFile(file); err == nil {
+			return &resizedImage{ctype, data}, nil
+		}
+	} else {
+		// Cached in the "directory" of the image so that they are
+		// invalidated with it.
+		key = name + "/@" + digest
+		if e := s.images.get(key); e != nil {
+			return &resizedImage{e.ctype, e.data}, nil
+		}
+	}
+
+	data, err := s.resize(ctx, name, size, ext)
+	if err != nil {
+		return nil, err
+	}
+	if file != "" {
+		if err := writeFileAtomic(file, data); err != nil {
+			return nil, err
+		}
+	} else if s.images.cacheable(int64(len(data))) {
+		s.images.put(&cacheEntry{key: key, name: name, ctype: ctype, data: data})
+	}
+	return &resizedImage{ctype, data}, nil
+}
+
+// resize returns the named image resized and encoded in the format of the
+// extension, ".jpg" or ".png".
+func (s *server) resize(ctx context.Context, name string, size imageSize, ext string) ([]byte, error) {
+	f, err := s.open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, route.NewHTTPError(http.StatusUnprocessableEntity, "invalid image")
+	}
+
+	crop, w, h := fitImage(src.Bounds(), size)
+	dst := resizeImage(src, crop, w, h)
+	buf := new(bytes.Buffer)
+	if ext == ".jpg" {
+		err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(buf, dst)
+	}
+	return buf.Bytes(), err
+}
+
+// fitImage returns the rectangle of the source image bounds b and the size
+// of the resized image. Images are never enlarged.
+func fitImage(b image.Rectangle, size imageSize) (image.Rectangle, int, int) {
+	sw, sh := b.Dx(), b.Dy()
+	w, h := size.w, size.h
+	switch {
+	case w == 0:
+		w = (sw*h + sh/2) / sh
+	case h == 0:
+		h = (sh*w + sw/2) / sw
+	}
+
+	switch size.fit {
+	case "contain":
+		if w*sh > h*sw { // The image is narrower than the box.
+			w = (sw*h + sh/2) / sh
+		} else {
+			h = (sh*w + sw/2) / sw
+		}
+		if w > sw || h > sh {
+			w, h = sw, sh
+		}
+	case "cover":
+		cw, ch := sw, sh
+		if w*sh > h*sw { // The box is wider than the image.
+			ch = (sw*h + w/2) / w
+		} else {
+			cw = (sh*w + h/2) / h
+		}
+		if w > cw {
+			w, h = cw, ch
+		}
+		x, y := b.Min.X+(sw-cw)/2, b.Min.Y+(sh-ch)/2
+		b = image.Rect(x, y, x+cw, y+ch)
+	case "fill":
+		if w > sw {
+			w = sw
+		}
+		if h > sh {
+			h = sh
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return b, w, h
+}
+
+// resizeImage returns the rectangle r of src scaled to w by h, averaging the
+// source pixels of each pixel.
+func resizeImage(src image.Image, r image.Rectangle, w, h int) *image.RGBA64 {
+	dst := image.NewRGBA64(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := r.Min.Y+y*r.Dy()/h, r.Min.Y+(y+1)*r.Dy()/h
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := r.Min.X+x*r.Dx()/w, r.Min.X+(x+1)*r.Dx()/w
+			if x1 == x0 {
+				x1++
+			}
+			var sr, sg, sb, sa, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, a := src.At(sx, sy).RGBA()
+					sr, sg, sb, sa = sr+uint64(r), sg+uint64(g), sb+uint64(b), sa+uint64(a)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(sr / n), uint16(sg / n), uint16(sb / n), uint16(sa / n)})
+		}
+	}
+	return dst
+}
+
+// writeFileAtomic writes the file, which is never read partially.
+func writeFileAtomic(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // Fails once renamed.
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,99 @@
+package static
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			src.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var pngData, jpegData bytes.Buffer
+	assert := assert.New(t)
+	assert.NoError(png.Encode(&pngData, src))
+	assert.NoError(jpeg.Encode(&jpegData, src, nil))
+	fsys := fstest.MapFS{
+		"photo.png": {Data: pngData.Bytes()},
+		"photo.jpg": {Data: jpegData.Bytes()},
+	}
+
+	dir := t.TempDir()
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), ImageResize(true)),
+		New(Filesystem(fsys), ImageResize(true), ImageCacheDir(dir)),
+		New(Filesystem(fsys), ImageResize(true), Cache(1<<20, 0, 0)),
+	} {
+		for _, tc := range []struct {
+			target, ctype string
+			w, h          int
+		}{
+			{"/photo.png?w=100", "image/png", 100, 50},
+			{"/photo.png?h=100", "image/png", 200, 100},
+			{"/photo.png?w=100&h=100", "image/png", 100, 50},
+			{"/photo.png?w=100&h=100&fit=cover", "image/png", 100, 100},
+			{"/photo.png?w=100&h=100&fit=fill", "image/png", 100, 100},
+			{"/photo.png?w=800", "image/png", 400, 200},
+			{"/photo.jpg?w=100&h=100&fit=cover", "image/jpeg", 100, 100},
+			{"/photo.png", "image/png", 400, 200},
+		} {
+			for i := 0; i < 2; i++ {
+				mux := route.NewServeMux()
+				req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+				rec := httptest.NewRecorder()
+				if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+					assert.Equal(http.StatusOK, rec.Code, tc.target)
+					assert.Equal(tc.ctype, rec.Header().Get(route.HeaderContentType), tc.target)
+					img, _, err := image.Decode(rec.Body)
+					if assert.NoError(err, tc.target) {
+						assert.Equal(tc.w, img.Bounds().Dx(), tc.target)
+						assert.Equal(tc.h, img.Bounds().Dy(), tc.target)
+					}
+				}
+			}
+		}
+	}
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(files, 7)
+	for _, f := range files {
+		assert.Contains([]string{".png", ".jpg"}, filepath.Ext(f.Name()))
+	}
+
+	mw := New(Filesystem(fsys), ImageResize(true), ImageSizes("100x", "100x100"))
+	for target, code := range map[string]int{
+		"/photo.png?w=100":         http.StatusOK,
+		"/photo.png?w=100&h=100":   http.StatusOK,
+		"/photo.png?w=200":         http.StatusBadRequest,
+		"/photo.png?h=100":         http.StatusBadRequest,
+		"/photo.png?w=-1":          http.StatusBadRequest,
+		"/photo.png?w=100&fit=bad": http.StatusBadRequest,
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+		if code == http.StatusOK {
+			assert.NoError(err, target)
+			assert.Equal(code, rec.Code, target)
+		} else if he, ok := err.(*route.HTTPError); assert.True(ok, target) {
+			assert.Equal(code, he.Code, target)
+		}
+	}
+}
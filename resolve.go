@@ -0,0 +1,118 @@
+package static
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrInvalidPath is returned by ResolvePath for request paths which can't
+// name a file, answered with status 404 by the middleware.
+var ErrInvalidPath = errors.New("static: invalid path")
+
+// windows reports whether request paths are resolved with the rules of
+// Windows filesystems.
+var windows = runtime.GOOS == "windows"
+
+// windowsDevices are the reserved names of Windows devices, in upper case,
+// which can't name files whatever their extension.
+var windowsDevices = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"COM¹": true, "COM²": true, "COM³": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"LPT¹": true, "LPT²": true, "LPT³": true,
+	"CONIN$": true, "CONOUT$": true, "CLOCK$": true,
+}
+
+// ResolvePath returns the path of the file of the root directory at the
+// escaped request path, as the middleware resolves it, e.g.
+// "/docs/../a%20b.txt" is "root/a b.txt". Escaped and repeated separators are
+// separators, backslashes too on Windows, and ".." elements never leave the
+// root. It returns ErrInvalidPath for badly escaped paths and paths
+// containing null bytes, and on Windows for names which the filesystem would
+// resolve to another file, a device or outside the root: UNC paths such as
+// "\\server\share", drive letters such as "C:", alternate data streams such
+// as "file.txt::$DATA", short names such as "PROGRA~1", names ending with dots
+// or spaces and reserved device names such as "CON" or "nul.txt". Symlinks
+// aren't resolved.
+func ResolvePath(root, requestPath string) (string, error) {
+	p, err := url.PathUnescape(requestPath)
+	if err != nil {
+		return "", ErrInvalidPath
+	}
+	name, err := resolveName(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(name)), nil
+}
+
+// resolveName returns the io/fs name of the file at the unescaped request
+// path p, which never contains "..".
+func resolveName(p string) (string, error) {
+	if strings.IndexByte(p, 0) >= 0 {
+		return "", ErrInvalidPath
+	}
+	if !windows {
+		return fsPath(p), nil
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+	if strings.HasPrefix(p, "//") {
+		return "", ErrInvalidPath // UNC, e.g. "\\server\share" or "\\?\C:\".
+	}
+	name := fsPath(p)
+	for _, elem := range strings.Split(name, "/") {
+		if !validWindowsName(elem) {
+			return "", ErrInvalidPath
+		}
+	}
+	return name, nil
+}
+
+// validWindowsName reports whether the path element names the same file on
+// Windows.
+func validWindowsName(elem string) bool {
+	if elem == "." {
+		return true
+	}
+	if strings.ContainsRune(elem, ':') || strings.HasSuffix(elem, ".") || strings.HasSuffix(elem, " ") {
+		return false
+	}
+	base := elem
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	if windowsDevices[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return false
+	}
+	return !shortName(elem)
+}
+
+// shortName reports whether the path element has the shape of an 8.3 short
+// name, e.g. "PROGRA~1" or "REPORT~2.TXT": up to 6 characters, "~" and
+// digits, 8 characters at most, then an optional extension of up to 3
+// characters.
+func shortName(elem string) bool {
+	base, ext := elem, ""
+	if i := strings.IndexByte(elem, '.'); i >= 0 {
+		base, ext = elem[:i], elem[i+1:]
+	}
+	if len(base) > 8 || len(ext) > 3 || strings.IndexByte(ext, '.') >= 0 {
+		return false
+	}
+	i := strings.LastIndexByte(base, '~')
+	if i < 1 || i > 6 || i == len(base)-1 {
+		return false
+	}
+	for _, c := range base[i+1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
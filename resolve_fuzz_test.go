@@ -0,0 +1,51 @@
+package static
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func FuzzResolvePath(f *testing.F) {
+	for _, p := range []string{
+		"/index.html",
+		"/../../etc/passwd",
+		"/..%2f..%2fetc/passwd",
+		"/%2e%2e/%2e%2e/",
+		"/..%5c..%5cwindows",
+		"/a%00.txt",
+		"//server/share",
+		"/C:/Windows",
+		"/%252e%252e/",
+	} {
+		f.Add(p)
+	}
+	defer func(w bool) { windows = w }(windows)
+	root := filepath.FromSlash("/srv/www")
+	f.Fuzz(func(t *testing.T, p string) {
+		for _, windows = range []bool{false, true} {
+			checkResolvePath(t, root, p)
+		}
+	})
+}
+
+// checkResolvePath fails if the request path p resolves outside of the root.
+func checkResolvePath(t *testing.T, root, p string) {
+	resolved, err := ResolvePath(root, p)
+	if err != nil {
+		return
+	}
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		t.Fatalf("ResolvePath(%q) = %q, outside of the root", p, resolved)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		t.Fatalf("ResolvePath(%q) = %q, outside of the root", p, resolved)
+	}
+	if strings.IndexByte(resolved, 0) >= 0 {
+		t.Fatalf("ResolvePath(%q) = %q, with a null byte", p, resolved)
+	}
+	if windows && strings.ContainsAny(rel, `:\`) {
+		t.Fatalf("ResolvePath(%q) = %q, with a drive or stream separator", p, resolved)
+	}
+}
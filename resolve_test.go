@@ -0,0 +1,120 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvePath(t *testing.T) {
+	defer func(w bool) { windows = w }(windows)
+	windows = false
+
+	root := filepath.FromSlash("/srv/www")
+	assert := assert.New(t)
+	for p, want := range map[string]string{
+		"":                       "/srv/www",
+		"/":                      "/srv/www",
+		"/index.html":            "/srv/www/index.html",
+		"//docs///a.txt":         "/srv/www/docs/a.txt",
+		"/docs/../a.txt":         "/srv/www/a.txt",
+		"/../../etc/passwd":      "/srv/www/etc/passwd",
+		"/..%2f..%2fetc/passwd":  "/srv/www/etc/passwd",
+		"/%2e%2e/%2E%2E/passwd":  "/srv/www/passwd",
+		"/a%20b.txt":             "/srv/www/a b.txt",
+		"docs/./a.txt":           "/srv/www/docs/a.txt",
+		"/docs/a.txt/":           "/srv/www/docs/a.txt",
+		"/%2f%2fexample.com/x":   "/srv/www/example.com/x",
+		"/..%252f..%252fpasswd":  "/srv/www/..%2f..%2fpasswd",
+		"/docs/%2e%2e%2f%2e%2e/": "/srv/www",
+		"/PROGRA~1/report~2.txt": "/srv/www/PROGRA~1/report~2.txt",
+	} {
+		resolved, err := ResolvePath(root, p)
+		assert.NoError(err, p)
+		assert.Equal(filepath.FromSlash(want), resolved, p)
+	}
+	for _, p := range []string{"/a%00.txt", "/a\x00.txt", "/%zz", "/a%"} {
+		_, err := ResolvePath(root, p)
+		assert.Equal(ErrInvalidPath, err, p)
+	}
+}
+
+func TestStaticInvalidPath(t *testing.T) {
+	mw := New(Root("testdata"))
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/file1.txt%00.png", nil)
+	rec := httptest.NewRecorder()
+	err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*route.HTTPError).Code)
+	}
+}
+
+func TestResolvePathWindows(t *testing.T) {
+	defer func(w bool) { windows = w }(windows)
+	windows = true
+
+	assert := assert.New(t)
+	for p, want := range map[string]string{
+		`/docs\a.txt`:             "docs/a.txt",
+		`/..\..\windows\win.ini`:  "windows/win.ini",
+		"/..%5c..%5cwindows":      "windows",
+		`/docs\\\a.txt`:           "docs/a.txt",
+		"/nullable.txt":           "nullable.txt",
+		"/console/com10.txt":      "console/com10.txt",
+		"/file~name.txt":          "file~name.txt",
+		"/backup~1.tar.gz":        "backup~1.tar.gz",
+		"/draft~2.html":           "draft~2.html",
+		"/changelog~1.txt":        "changelog~1.txt",
+		"/v~1a.txt":               "v~1a.txt",
+		"/.well-known/index.html": ".well-known/index.html",
+	} {
+		resolved, err := ResolvePath("", p)
+		assert.NoError(err, p)
+		assert.Equal(filepath.FromSlash(want), resolved, p)
+	}
+	for _, p := range []string{
+		"/C:/Windows/win.ini",
+		`/c:\windows`,
+		"/C%3a/Windows",
+		"/docs/a.txt::$DATA",
+		"/a.txt:stream",
+		"/server.key.",
+		"/server.key%20",
+		"/docs./a.txt",
+		"/PROGRA~1/app.exe",
+		"/GIT~1/config",
+		"/REPORT~2.TXT",
+		"/a~12.js",
+		`\\server\share\file.txt`,
+		"//server/share/file.txt",
+		`/\\?\C:\Windows`,
+		"/%5c%5c.%5cpipe%5cname",
+		"/CON",
+		"/con.txt",
+		"/docs/Nul.tar.gz",
+		"/aux .txt",
+		"/COM1",
+		"/lpt9.log",
+		"/COM¹",
+		"/conin$",
+	} {
+		_, err := ResolvePath("", p)
+		assert.Equal(ErrInvalidPath, err, p)
+	}
+
+	mw := New(Root("testdata"))
+	for _, p := range []string{"/..%5cstatic.go", "/file1.txt.", "/C:/file1.txt", "/%5c%5cserver/share", "/nul"} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		rec := httptest.NewRecorder()
+		err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+		if assert.Error(err, p) {
+			assert.Equal(http.StatusNotFound, err.(*route.HTTPError).Code, p)
+		}
+	}
+}
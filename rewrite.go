@@ -0,0 +1,57 @@
+package static
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rewriteRule rewrites the paths matching re to the expansion of to.
+type rewriteRule struct {
+	re *regexp.Regexp
+	to string
+}
+
+// compileRewrites returns the rules of the rewrite patterns, longest first.
+// Patterns starting with "^" are regular expressions, others match whole
+// paths with "*" wildcards captured as $1, $2, etc.
+func compileRewrites(rewrites map[string]string) ([]rewriteRule, error) {
+	patterns := make([]string, 0, len(rewrites))
+	for pattern := range rewrites {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	rules := make([]rewriteRule, len(patterns))
+	for i, pattern := range patterns {
+		expr := pattern
+		if !strings.HasPrefix(pattern, "^") {
+			expr = "^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, "(.*)", -1) + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rewriteRule{re, rewrites[pattern]}
+	}
+	return rules, nil
+}
+
+// rewrite returns the request path p rewritten by the first matching rule.
+func (s *server) rewrite(p string) string {
+	if len(s.rewrites) == 0 {
+		return p
+	}
+	p = "/" + strings.TrimPrefix(p, "/") // Group wildcards have no leading slash.
+	for _, r := range s.rewrites {
+		if m := r.re.FindStringSubmatchIndex(p); m != nil {
+			return string(r.re.ExpandString(nil, r.to, p, m))
+		}
+	}
+	return p
+}
@@ -0,0 +1,50 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":          {Data: []byte("app")},
+		"docs/index.html": {Data: []byte("docs")},
+		"new/page.html":   {Data: []byte("page")},
+		"users/1/2.json":  {Data: []byte("order")},
+	}
+	mw := New(Filesystem(fsys), Rewrite(map[string]string{
+		"/v2/*":                "/$1",
+		"/v2/legacy/*":         "/new/$1.html",
+		"^/docs$":              "/docs/index.html",
+		"/user/*/order/*":      "/users/$1/$2.json",
+		`^/old-(\w+)\.html$`:   "/new/$1.html",
+		"/v2/legacy/app.js.gz": "/app.js",
+	}))
+	assert := assert.New(t)
+
+	for target, want := range map[string]string{
+		"/app.js":              "app",
+		"/v2/app.js":           "app",
+		"/v2/legacy/page":      "page",
+		"/docs":                "docs",
+		"/user/1/order/2":      "order",
+		"/old-page.html":       "page",
+		"/v2/legacy/app.js.gz": "app",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			assert.Equal(want, rec.Body.String(), target)
+		}
+	}
+
+	assert.Panics(func() {
+		New(Filesystem(fsys), Rewrite(map[string]string{"^(": "/"}))
+	})
+}
@@ -0,0 +1,161 @@
+package static
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/goroute/route"
+)
+
+// rootServersSize is the maximum number of servers of the roots returned by
+// RootFunc, beyond which they are all dropped with their caches.
+const rootServersSize = 1000
+
+type (
+	// rootServers are the servers of the roots returned by RootFunc, created
+	// on demand so that each root has its own caches.
+	rootServers struct {
+		mu      sync.Mutex
+		servers map[string]*server
+
+		// Servers being created, by root.
+		pending map[string]*rootServerCall
+	}
+
+	// rootServerCall is the creation of the server of a root, which the
+	// requests for the root wait for.
+	rootServerCall struct {
+		done chan struct{}
+		s    *server
+		err  error
+	}
+)
+
+func newRootServers() *rootServers {
+	return &rootServers{servers: map[string]*server{}, pending: map[string]*rootServerCall{}}
+}
+
+// rootServer returns the server of the root chosen by RootFunc or TenantRoot
+// for the request, or nil if there is none.
+func (s *server) rootServer(c route.Context) (*server, error) {
+	root, err := s.requestRoot(c)
+	if err != nil || root == "" {
+		return nil, err
+	}
+
+	// Servers are created outside of the lock, as it may take long, e.g. to
+	// precompress the files, once for concurrent requests.
+	rss := s.rootServers
+	rss.mu.Lock()
+	if rs := rss.servers[root]; rs != nil {
+		rss.mu.Unlock()
+		return rs, nil
+	}
+	if call := rss.pending[root]; call != nil {
+		rss.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.s, call.err
+		case <-c.Request().Context().Done():
+			return nil, c.Request().Context().Err()
+		}
+	}
+	call := &rootServerCall{done: make(chan struct{})}
+	rss.pending[root] = call
+	rss.mu.Unlock()
+
+	call.s, call.err = s.newRootServer(root)
+	rss.mu.Lock()
+	delete(rss.pending, root)
+	if call.err == nil {
+		if len(rss.servers) >= rootServersSize {
+			rss.servers = map[string]*server{}
+		}
+		rss.servers[root] = call.s
+	}
+	rss.mu.Unlock()
+	close(call.done)
+	return call.s, call.err
+}
+
+// newRootServer returns the server of the root directory.
+func (s *server) newRootServer(root string) (*server, error) {
+	if s.TenantRoot != nil {
+		if err := s.checkTenantRoot(root); err != nil {
+			return nil, err
+		}
+	}
+	opts := s.Options
+	opts.Root, opts.Roots, opts.VHosts, opts.RootFunc, opts.TenantRoot = root, nil, nil, nil, nil
+	if err := validate(opts); err != nil {
+		return nil, err
+	}
+	rs, err := newServer(opts)
+	if err != nil {
+		return nil, err
+	}
+	rs.transfers = s.transfers // Limited for all the roots.
+	rs.throttles = s.throttles
+	rs.changes = s.changes
+	return rs, nil
+}
+
+// requestRoot returns the root directory of the request, or "" if there is
+// none.
+func (s *server) requestRoot(c route.Context) (string, error) {
+	if s.TenantRoot == nil {
+		return s.RootFunc(c), nil
+	}
+	tenant, err := s.TenantRoot(c)
+	if err != nil || tenant == "" {
+		return "", err
+	}
+	name := path.Clean(filepath.ToSlash(tenant))
+	if path.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, "../") || strings.ContainsRune(name, 0) {
+		return "", route.NewHTTPError(http.StatusNotFound)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(name)), nil
+}
+
+// checkTenantRoot returns an error if the root directory of a tenant doesn't
+// exist or, through symlinks, isn't in Root.
+func (s *server) checkTenantRoot(root string) error {
+	var (
+		fi  fs.FileInfo
+		err error
+	)
+	if s.Filesystem != nil {
+		fi, err = fs.Stat(s.Filesystem, fsPath(filepath.ToSlash(root)))
+	} else {
+		fi, err = os.Stat(root)
+	}
+	if err != nil || !fi.IsDir() {
+		return route.NewHTTPError(http.StatusNotFound)
+	}
+	if s.Filesystem == nil && !s.FollowSymlinks {
+		base, real := realPath(s.Root), realPath(root)
+		if real != base && !strings.HasPrefix(real, base+string(filepath.Separator)) {
+			return route.NewHTTPError(http.StatusNotFound)
+		}
+	}
+	return nil
+}
+
+// invalidate removes the named file, or the content of the named directory,
+// from the caches of every root.
+func (rs *rootServers) invalidate(name string) {
+	rs.mu.Lock()
+	servers := make([]*server, 0, len(rs.servers))
+	for _, s := range rs.servers {
+		servers = append(servers, s)
+	}
+	rs.mu.Unlock()
+	for _, s := range servers {
+		s.invalidate(name)
+	}
+}
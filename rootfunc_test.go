@@ -0,0 +1,110 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootFunc(t *testing.T) {
+	dirs := map[string]string{}
+	for _, name := range []string{"default", "a", "b"} {
+		dirs[name] = t.TempDir()
+		os.WriteFile(filepath.Join(dirs[name], "app.js"), []byte(name), 0o644)
+	}
+	mw, h := NewHandle(Root(dirs["default"]), Cache(1<<20, 0, 0), RootFunc(func(c route.Context) string {
+		return dirs[c.Request().Header.Get("X-Build")]
+	}))
+	assert := assert.New(t)
+
+	get := func(build string) string {
+		return serve(t, mw, http.MethodGet, "/app.js", "X-Build", build).Body.String()
+	}
+	// Cached per root.
+	for i := 0; i < 2; i++ {
+		assert.Equal("a", get("a"))
+		assert.Equal("b", get("b"))
+		assert.Equal("default", get(""))
+	}
+
+	// Invalidated for every root.
+	os.WriteFile(filepath.Join(dirs["b"], "app.js"), []byte("new"), 0o644)
+	assert.Equal("b", get("b"))
+	h.InvalidateAll()
+	assert.Equal("new", get("b"))
+	assert.Equal("a", get("a"))
+
+	// Created once for concurrent requests.
+	dirs["c"] = t.TempDir()
+	os.WriteFile(filepath.Join(dirs["c"], "app.js"), []byte("c"), 0o644)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal("c", get("c"))
+		}()
+	}
+	wg.Wait()
+	assert.Len(h.s.rootServers.servers, 3)
+	assert.Empty(h.s.rootServers.pending)
+
+	// Invalid roots are rejected.
+	dirs["missing"] = filepath.Join(dirs["a"], "missing")
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("X-Build", "missing")
+	err := mw(mux.NewContext(req, httptest.NewRecorder()), route.NotFoundHandler)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "static: root:")
+	}
+	assert.Len(h.s.rootServers.servers, 3)
+}
+
+func TestTenantRoot(t *testing.T) {
+	base, outside := t.TempDir(), t.TempDir()
+	for _, tenant := range []string{"acme", "globex"} {
+		os.Mkdir(filepath.Join(base, tenant), 0o755)
+		os.WriteFile(filepath.Join(base, tenant, "logo.svg"), []byte(tenant), 0o644)
+	}
+	os.WriteFile(filepath.Join(base, "logo.svg"), []byte("base"), 0o644)
+	os.WriteFile(filepath.Join(outside, "logo.svg"), []byte("outside"), 0o644)
+	os.Symlink(outside, filepath.Join(base, "link"))
+	mw := New(Root(base), TenantRoot(func(c route.Context) (string, error) {
+		tenant, _ := c.Get("tenant").(string)
+		return tenant, nil
+	}))
+	get := func(tenant string) (int, string) {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/logo.svg", nil)
+		rec := httptest.NewRecorder()
+		c := mux.NewContext(req, rec)
+		c.Set("tenant", tenant)
+		if err := mw(c, route.NotFoundHandler); err != nil {
+			return err.(*route.HTTPError).Code, ""
+		}
+		return rec.Code, rec.Body.String()
+	}
+
+	assert := assert.New(t)
+	code, body := get("acme")
+	assert.Equal(http.StatusOK, code)
+	assert.Equal("acme", body)
+	_, body = get("globex")
+	assert.Equal("globex", body)
+	for _, tenant := range []string{"", "..", "../" + filepath.Base(outside), "/etc", "acme/../..", "link", "missing"} {
+		code, _ = get(tenant)
+		assert.Equal(http.StatusNotFound, code, tenant)
+	}
+
+	_, err := NewWithError(Root(base), Roots(base, outside), TenantRoot(func(route.Context) (string, error) {
+		return "", nil
+	}))
+	assert.EqualError(err, "static: TenantRoot requires a single Root directory")
+}
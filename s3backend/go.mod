@@ -0,0 +1,24 @@
+module github.com/goroute/static/s3backend
+
+go 1.19
+
+require (
+	github.com/goroute/route v0.0.0-20190718071306-63785885e8a5
+	github.com/goroute/static v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.4.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/bmatcuk/doublestar/v4 v4.8.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/fsnotify/fsnotify v1.7.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/yuin/goldmark v1.4.12 // indirect
+	golang.org/x/net v0.11.0 // indirect
+	golang.org/x/sys v0.9.0 // indirect
+	golang.org/x/text v0.10.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+replace github.com/goroute/static => ../
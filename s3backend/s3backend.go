@@ -0,0 +1,311 @@
+// Package s3backend serves the objects of an Amazon S3 bucket, or of an S3
+// compatible store such as MinIO, as a file system for the Static middleware.
+//
+//	mux.Use(static.New(static.Backend(s3backend.New("my-bucket"))))
+//
+// Object keys are file paths, their slashes separating directories listed
+// from common prefixes. Files are read with ranged GET requests and keep the
+// entity tags of their objects.
+package s3backend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// FS is a file system of the objects of an S3 bucket. It implements
+	// fs.StatFS and fs.ReadDirFS.
+	FS struct {
+		bucket   string
+		prefix   string
+		region   string
+		endpoint string
+		creds    credentials
+		client   *http.Client
+	}
+
+	// Option configures an FS.
+	Option func(*FS)
+
+	credentials struct {
+		accessKeyID     string
+		secretAccessKey string
+		sessionToken    string
+	}
+)
+
+// New returns the file system of the bucket. The region and credentials
+// default to the AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables. Requests are anonymous without
+// credentials, e.g. for public buckets.
+func New(bucket string, options ...Option) *FS {
+	f := &FS{
+		bucket: bucket,
+		region: os.Getenv("AWS_REGION"),
+		creds: credentials{
+			accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		client: http.DefaultClient,
+	}
+	if f.region == "" {
+		f.region = "us-east-1"
+	}
+	for _, o := range options {
+		o(f)
+	}
+	return f
+}
+
+// Region sets the region of the bucket.
+func Region(region string) Option {
+	return func(f *FS) {
+		f.region = region
+	}
+}
+
+// Endpoint sets the URL of an S3 compatible store, e.g.
+// "http://localhost:9000", addressing buckets in the path.
+func Endpoint(endpoint string) Option {
+	return func(f *FS) {
+		f.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// Prefix sets the key prefix of the root directory, e.g. "public/".
+func Prefix(prefix string) Option {
+	return func(f *FS) {
+		f.prefix = strings.Trim(prefix, "/")
+	}
+}
+
+// Credentials sets the credentials signing requests.
+func Credentials(accessKeyID, secretAccessKey, sessionToken string) Option {
+	return func(f *FS) {
+		f.creds = credentials{accessKeyID, secretAccessKey, sessionToken}
+	}
+}
+
+// HTTPClient sets the client sending requests.
+// Default value http.DefaultClient.
+func HTTPClient(client *http.Client) Option {
+	return func(f *FS) {
+		f.client = client
+	}
+}
+
+// Open opens the named file or directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	fi, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &dir{fs: f, name: name, info: fi}, nil
+	}
+	return &object{fs: f, key: f.key(name), info: fi.(*fileInfo)}, nil
+}
+
+// Stat returns the FileInfo of the named file or directory.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *FS) stat(op, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+
+	res, err := f.do(http.MethodHead, f.key(name), nil, nil)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+		return objectInfo(path.Base(name), res), nil
+	case http.StatusNotFound:
+	default:
+		return nil, &fs.PathError{Op: op, Path: name, Err: statusError(res)}
+	}
+
+	// Directories are the common prefixes of keys.
+	l, err := f.list(f.key(name)+"/", "", 1)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(l.Contents) == 0 && len(l.CommonPrefixes) == 0 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return &fileInfo{name: path.Base(name), dir: true}, nil
+}
+
+// ReadDir reads the named directory, returning its entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	prefix := f.key(name) + "/"
+	if prefix == "/" {
+		prefix = ""
+	}
+
+	var entries []fs.DirEntry
+	found := name == "."
+	token := ""
+	for {
+		l, err := f.list(prefix, token, 0)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for _, p := range l.CommonPrefixes {
+			entries = append(entries, &fileInfo{name: strings.TrimSuffix(p.Prefix[len(prefix):], "/"), dir: true})
+		}
+		for _, o := range l.Contents {
+			found = true
+			if o.Key == prefix { // Directory marker
+				continue
+			}
+			entries = append(entries, &fileInfo{
+				name:    o.Key[len(prefix):],
+				size:    o.Size,
+				modTime: o.LastModified,
+				etag:    o.ETag,
+			})
+		}
+		found = found || len(l.CommonPrefixes) > 0
+		if !l.IsTruncated || l.NextContinuationToken == "" {
+			break
+		}
+		token = l.NextContinuationToken
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// key returns the object key of the named file.
+func (f *FS) key(name string) string {
+	if name == "." {
+		return f.prefix
+	}
+	if f.prefix == "" {
+		return name
+	}
+	return f.prefix + "/" + name
+}
+
+// listResult is the result of a ListObjectsV2 request.
+type listResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key          string
+		LastModified time.Time
+		ETag         string
+		Size         int64
+	}
+	CommonPrefixes []struct {
+		Prefix string
+	}
+}
+
+// list lists the keys starting with prefix, grouping the keys of
+// subdirectories into common prefixes.
+func (f *FS) list(prefix, token string, maxKeys int) (*listResult, error) {
+	query := url.Values{"list-type": {"2"}, "delimiter": {"/"}, "prefix": {prefix}}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	res, err := f.do(http.MethodGet, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res)
+	}
+	l := new(listResult)
+	if err = xml.NewDecoder(res.Body).Decode(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// do sends a request for the object key, or the bucket if key is empty.
+func (f *FS) do(method, key string, query url.Values, header http.Header) (*http.Response, error) {
+	u := f.url(key)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if f.creds.accessKeyID != "" {
+		f.sign(req, time.Now())
+	}
+	return f.client.Do(req)
+}
+
+// url returns the URL of the object key, or of the bucket if key is empty.
+func (f *FS) url(key string) *url.URL {
+	u := &url.URL{Scheme: "https", Host: f.bucket + ".s3." + f.region + ".amazonaws.com", Path: "/" + key}
+	if f.endpoint != "" {
+		if e, err := url.Parse(f.endpoint); err == nil {
+			u.Scheme, u.Host = e.Scheme, e.Host
+			u.Path = e.Path + "/" + f.bucket
+			if key != "" {
+				u.Path += "/" + key
+			}
+		}
+	}
+	u.RawPath = escapePath(u.Path)
+	return u
+}
+
+// statusError returns the error of an unexpected response.
+func statusError(res *http.Response) error {
+	if res.StatusCode == http.StatusNotFound {
+		return fs.ErrNotExist
+	}
+	if res.StatusCode == http.StatusForbidden {
+		return fs.ErrPermission
+	}
+	return fmt.Errorf("s3backend: %s %s: %s", res.Request.Method, res.Request.URL.Path, res.Status)
+}
+
+// objectInfo returns the FileInfo of the object from the response to a HEAD
+// or GET request.
+func objectInfo(name string, res *http.Response) *fileInfo {
+	size, _ := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return &fileInfo{name: name, size: size, modTime: modTime, etag: res.Header.Get("ETag")}
+}
+
+var (
+	_ fs.StatFS    = (*FS)(nil)
+	_ fs.ReadDirFS = (*FS)(nil)
+	_ io.Seeker    = (*object)(nil)
+)
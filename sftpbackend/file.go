@@ -0,0 +1,71 @@
+package sftpbackend
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/pkg/sftp"
+)
+
+type (
+	// fileInfo is the FileInfo of a file named as in the file system.
+	fileInfo struct {
+		os.FileInfo
+		name string
+	}
+
+	// file is an open remote file.
+	file struct {
+		*sftp.File
+		info fs.FileInfo
+	}
+
+	// dir is an open remote directory.
+	dir struct {
+		fs      *FS
+		name    string
+		info    fs.FileInfo
+		entries []fs.DirEntry
+		read    bool
+	}
+)
+
+func (fi *fileInfo) Name() string { return fi.name }
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error { return nil }
+
+// ReadDir returns the next n entries of the directory, or all of them if n
+// <= 0, read at once from the host.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
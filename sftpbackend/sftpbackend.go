@@ -0,0 +1,284 @@
+// Package sftpbackend serves the files of a remote host over SFTP as a file
+// system for the Static middleware.
+//
+//	config := &ssh.ClientConfig{
+//		User:            "assets",
+//		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+//		HostKeyCallback: ssh.FixedHostKey(hostKey),
+//	}
+//	mux.Use(static.New(static.Backend(sftpbackend.New(
+//		"files.example.com:22", config,
+//		sftpbackend.Root("/srv/assets"),
+//	))))
+//
+// Requests are spread over a pool of SSH connections, opened on demand and
+// reopened when they break. The metadata of files is cached for a short time
+// to spare a round trip to the host for each conditional request.
+package sftpbackend
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+type (
+	// FS is a file system of the files of a remote host. It implements
+	// fs.StatFS and fs.ReadDirFS.
+	FS struct {
+		root string
+		dial func() (*sftp.Client, error)
+		ttl  time.Duration
+
+		// Pool of connections, opened on demand.
+		mu    sync.Mutex
+		conns []*sftp.Client
+		next  int
+
+		statMu sync.Mutex
+		stats  map[string]statEntry
+	}
+
+	// Option configures an FS.
+	Option func(*FS)
+
+	// statEntry is the cached result of a stat.
+	statEntry struct {
+		fi      fs.FileInfo
+		err     error
+		expires time.Time
+	}
+)
+
+// statCacheSize is the maximum number of cached stats, beyond which the
+// cache is emptied.
+const statCacheSize = 10000
+
+// New returns the file system of the files of the host at addr, e.g.
+// "files.example.com:22", connecting with the config.
+func New(addr string, config *ssh.ClientConfig, options ...Option) *FS {
+	f := &FS{
+		root: ".",
+		dial: func() (*sftp.Client, error) {
+			conn, err := ssh.Dial("tcp", addr, config)
+			if err != nil {
+				return nil, err
+			}
+			client, err := sftp.NewClient(conn)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			go func() {
+				// The SSH connection outlives the SFTP session otherwise.
+				client.Wait()
+				conn.Close()
+			}()
+			return client, nil
+		},
+		ttl:   5 * time.Second,
+		conns: make([]*sftp.Client, 4),
+		stats: map[string]statEntry{},
+	}
+	for _, o := range options {
+		o(f)
+	}
+	return f
+}
+
+// Root sets the directory of the host whose files are served.
+// Default value ".", the home directory of the user.
+func Root(dir string) Option {
+	return func(f *FS) {
+		f.root = dir
+	}
+}
+
+// PoolSize sets the number of connections to the host.
+// Default value 4.
+func PoolSize(n int) Option {
+	return func(f *FS) {
+		if n < 1 {
+			n = 1
+		}
+		f.conns = make([]*sftp.Client, n)
+	}
+}
+
+// StatCacheTTL sets the time for which the metadata of files is cached.
+// Default value 5 seconds. 0 disables the cache.
+func StatCacheTTL(ttl time.Duration) Option {
+	return func(f *FS) {
+		f.ttl = ttl
+	}
+}
+
+// Dial sets the function connecting to the host, e.g. through a jump host,
+// instead of the address and config of New.
+func Dial(dial func() (*sftp.Client, error)) Option {
+	return func(f *FS) {
+		f.dial = dial
+	}
+}
+
+// Open opens the named file or directory.
+func (f *FS) Open(name string) (fs.File, error) {
+	fi, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &dir{fs: f, name: name, info: fi}, nil
+	}
+
+	client, slot, err := f.conn()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	sf, err := client.Open(f.remotePath(name))
+	if err != nil {
+		f.release(slot, client, err)
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{File: sf, info: fi}, nil
+}
+
+// Stat returns the FileInfo of the named file or directory.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *FS) stat(op, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if f.ttl > 0 {
+		f.statMu.Lock()
+		e, ok := f.stats[name]
+		f.statMu.Unlock()
+		if ok && time.Now().Before(e.expires) {
+			if e.err != nil {
+				return nil, &fs.PathError{Op: op, Path: name, Err: e.err}
+			}
+			return e.fi, nil
+		}
+	}
+
+	client, slot, err := f.conn()
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	fi, err := client.Stat(f.remotePath(name))
+	f.release(slot, client, err)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if err == nil {
+		fi = &fileInfo{FileInfo: fi, name: path.Base(name)}
+	}
+	f.cacheStat(name, fi, err)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return fi, nil
+}
+
+// cacheStat caches the result of the stat of the named file, if enabled.
+// Only missing files are cached among errors.
+func (f *FS) cacheStat(name string, fi fs.FileInfo, err error) {
+	if f.ttl <= 0 {
+		return
+	}
+	f.statMu.Lock()
+	defer f.statMu.Unlock()
+	if len(f.stats) >= statCacheSize {
+		f.stats = map[string]statEntry{}
+	}
+	f.stats[name] = statEntry{fi, err, time.Now().Add(f.ttl)}
+}
+
+// ReadDir returns the entries of the named directory, sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	client, slot, err := f.conn()
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	infos, err := client.ReadDir(f.remotePath(name))
+	f.release(slot, client, err)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, fi := range infos {
+		entries[i] = fs.FileInfoToDirEntry(fi)
+		f.cacheStat(path.Join(name, fi.Name()), fi, nil)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// Close closes the connections to the host.
+func (f *FS) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var err error
+	for i, client := range f.conns {
+		if client != nil {
+			if e := client.Close(); err == nil {
+				err = e
+			}
+			f.conns[i] = nil
+		}
+	}
+	return err
+}
+
+// remotePath returns the path on the host of the named file.
+func (f *FS) remotePath(name string) string {
+	return path.Join(f.root, name)
+}
+
+// conn returns the next connection of the pool and its slot, connecting if
+// needed.
+func (f *FS) conn() (*sftp.Client, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	slot := f.next
+	f.next = (f.next + 1) % len(f.conns)
+	if f.conns[slot] == nil {
+		client, err := f.dial()
+		if err != nil {
+			return nil, slot, err
+		}
+		f.conns[slot] = client
+	}
+	return f.conns[slot], slot, nil
+}
+
+// release closes the connection of the slot if the error of a request shows
+// it is broken, so that it is reopened on its next use.
+func (f *FS) release(slot int, client *sftp.Client, err error) {
+	var status *sftp.StatusError
+	if err == nil || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) || errors.As(err, &status) {
+		return
+	}
+	f.mu.Lock()
+	if f.conns[slot] == client {
+		f.conns[slot] = nil
+	}
+	f.mu.Unlock()
+	client.Close()
+}
@@ -0,0 +1,158 @@
+package sftpbackend
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/goroute/static"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestFS returns the file system of a temporary directory served by an
+// in-process SFTP server, and the number of connections dialed.
+func newTestFS(t *testing.T, options ...Option) (*FS, string, *int32) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"index.html":    "<h1>Hello</h1>",
+		"app.js":        "console.log('app')",
+		"docs/guide.md": "# Guide",
+	} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var dials int32
+	dial := func() (*sftp.Client, error) {
+		atomic.AddInt32(&dials, 1)
+		c1, c2 := net.Pipe()
+		srv, err := sftp.NewServer(c1)
+		if err != nil {
+			return nil, err
+		}
+		go srv.Serve()
+		return sftp.NewClientPipe(c2, c2)
+	}
+	fsys := New("", nil, append([]Option{Root(dir), Dial(dial)}, options...)...)
+	t.Cleanup(func() { fsys.Close() })
+	return fsys, dir, &dials
+}
+
+func TestFS(t *testing.T) {
+	fsys, dir, dials := newTestFS(t, PoolSize(2))
+	var _ static.BackendFS = fsys
+
+	assert := assert.New(t)
+	fi, err := fsys.Stat("docs")
+	if assert.NoError(err) {
+		assert.True(fi.IsDir())
+		assert.Equal("docs", fi.Name())
+	}
+	fi, err = fsys.Stat("docs/guide.md")
+	if assert.NoError(err) {
+		assert.False(fi.IsDir())
+		assert.Equal("guide.md", fi.Name())
+		assert.Equal(int64(7), fi.Size())
+	}
+	_, err = fsys.Stat("missing.js")
+	assert.True(errors.Is(err, fs.ErrNotExist))
+	_, err = fsys.Stat("../etc/passwd")
+	assert.True(errors.Is(err, fs.ErrInvalid))
+
+	f, err := fsys.Open("app.js")
+	if assert.NoError(err) {
+		rs := f.(io.ReadSeeker)
+		rs.Seek(8, io.SeekStart)
+		buf := make([]byte, 3)
+		io.ReadFull(rs, buf)
+		assert.Equal("log", string(buf))
+		assert.NoError(f.Close())
+	}
+
+	entries, err := fsys.ReadDir(".")
+	if assert.NoError(err) && assert.Len(entries, 3) {
+		assert.Equal("app.js", entries[0].Name())
+		assert.True(entries[1].IsDir())
+	}
+	d, err := fsys.Open("docs")
+	if assert.NoError(err) {
+		list, err := d.(fs.ReadDirFile).ReadDir(-1)
+		assert.NoError(err)
+		assert.Len(list, 1)
+	}
+	assert.Equal(int32(2), atomic.LoadInt32(dials))
+
+	// Metadata is cached.
+	assert.NoError(os.WriteFile(filepath.Join(dir, "app.js"), []byte("changed"), 0644))
+	fi, err = fsys.Stat("app.js")
+	if assert.NoError(err) {
+		assert.Equal(int64(18), fi.Size())
+	}
+}
+
+func TestStatCacheTTL(t *testing.T) {
+	fsys, dir, _ := newTestFS(t, StatCacheTTL(0))
+	assert := assert.New(t)
+	_, err := fsys.Stat("new.txt")
+	assert.True(errors.Is(err, fs.ErrNotExist))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644))
+	_, err = fsys.Stat("new.txt")
+	assert.NoError(err)
+}
+
+func TestReconnect(t *testing.T) {
+	fsys, _, dials := newTestFS(t, PoolSize(1), StatCacheTTL(0))
+	assert := assert.New(t)
+	_, err := fsys.Stat("app.js")
+	assert.NoError(err)
+
+	// Broken connections are reopened on their next use.
+	fsys.conns[0].Close()
+	_, err = fsys.Stat("app.js")
+	assert.Error(err)
+	_, err = fsys.Stat("app.js")
+	assert.NoError(err)
+	assert.Equal(int32(2), atomic.LoadInt32(dials))
+}
+
+func TestStatic(t *testing.T) {
+	fsys, _, _ := newTestFS(t)
+	mw := static.New(static.Backend(fsys), static.Browse(true))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("<h1>Hello</h1>", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("Range", "bytes=8-10")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusPartialContent, rec.Code)
+		assert.Equal("log", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/docs/", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), "guide.md")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/missing.js", nil)
+	rec = httptest.NewRecorder()
+	assert.Error(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+}
@@ -1,8 +1,15 @@
 package static
 
 import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
@@ -10,8 +17,11 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/goroute/route"
+	"golang.org/x/net/webdav"
 )
 
 type (
@@ -20,34 +30,674 @@ type (
 		// Skipper defines a function to skip middleware.
 		Skipper route.Skipper
 
-		// Root directory from where the static content is served.
+		// Root directory from where the static content is served, or a ZIP or
+		// tar archive, e.g. "assets.zip" or "assets.tar.gz", served without
+		// extraction. When Filesystem is set, Root is resolved inside it.
 		// Required.
 		Root string `yaml:"root"`
 
+		// Root directories overlaid in order, the first one containing a file
+		// serving it. Overrides Root.
+		// Optional. Default value nil.
+		Roots []string `yaml:"roots"`
+
+		// Directories served at URL path prefixes in place of the files of
+		// Root, like the nginx alias directive, e.g. "/media":
+		// "/var/lib/media". When Filesystem is set, they are resolved inside
+		// it.
+		// Optional. Default value nil.
+		Aliases map[string]string `yaml:"aliases"`
+
+		// Root directories by Host header value, e.g. "a.example.com":
+		// "/srv/a". Patterns may contain wildcards, e.g. "*.example.com", and
+		// apply when no host matches exactly. Other hosts are served from
+		// Root.
+		// Optional. Default value nil.
+		VHosts map[string]string `yaml:"vhosts"`
+
+		// RootFunc returns the root directory of each request, e.g. to serve
+		// a new build of the assets to some clients or the directory of a
+		// tenant. Each root is served with its own caches, and isn't watched.
+		// Empty roots are served from Root and VHosts. When Filesystem is
+		// set, roots are resolved inside it.
+		// Optional. Default value nil.
+		RootFunc func(route.Context) string `yaml:"-"`
+
+		// TenantRoot returns the tenant of each request, e.g. from a value
+		// set on the context by an authentication middleware, whose files
+		// are served from the subdirectory of the same name of Root, with its
+		// own caches. Tenants escaping Root, including through symlinks
+		// unless FollowSymlinks is set, aren't found, like tenants without a
+		// directory. Requests without a tenant are passed to the next
+		// handler.
+		// Optional. Default value nil.
+		TenantRoot func(route.Context) (string, error) `yaml:"-"`
+
+		// Filesystem from where the static content is served, e.g. a backend
+		// such as s3backend. The FileInfo of its files may provide their
+		// entity tag with an `ETag() string` method.
+		// Optional. Default value is the OS filesystem.
+		Filesystem fs.FS `yaml:"-"`
+
+		// Path of the only file served, from Root or Filesystem, e.g.
+		// "favicon.ico", whatever the request path, with the same headers and
+		// conditional requests as other files. Routes such as "/favicon.ico"
+		// can be served without exposing the directory of the file.
+		// Optional. Default value "", which serves the request paths.
+		File string `yaml:"file"`
+
+		// URL path prefix removed from request paths before resolving them
+		// against Root, e.g. "/assets" to serve "/assets/app.js" from
+		// "app.js". Requests outside of it are passed to the next handler.
+		// Overrides the wildcard parameter of group routes, e.g. `/static*`.
+		// Optional. Default value "".
+		StripPrefix string `yaml:"strip_prefix"`
+
+		// Maximum length in bytes of escaped request paths, beyond which
+		// requests are answered with 414 URI Too Long.
+		// Optional. Default value 4096. 0 is unlimited.
+		MaxPathLength int `yaml:"max_path_length"`
+
+		// Maximum number of directories of request paths, beyond which
+		// requests are answered with 404 Not Found.
+		// Optional. Default value 64. 0 is unlimited.
+		MaxPathDepth int `yaml:"max_path_depth"`
+
+		// Rewrites of request paths before resolving them against Root, e.g.
+		// "/v2/*": "/$1" or "^/docs$": "/docs/index.html". Patterns starting
+		// with "^" are regular expressions, others match whole paths with "*"
+		// wildcards captured as $1, $2, etc. The longest matching pattern
+		// applies.
+		// Optional. Default value nil.
+		Rewrite map[string]string `yaml:"rewrite"`
+
+		// Candidates tried in order to serve requests, like the nginx
+		// try_files directive, e.g. "$uri", "$uri/", "$uri.html" and
+		// "/index.html", where "$uri" is the request path. Candidates ending
+		// with a slash match directories, others files. The last candidate is
+		// served when none matches, or may be a status code, e.g. "=404".
+		// Optional. Default value nil, which serves the request path.
+		TryFiles []string `yaml:"try_files"`
+
+		// Serve "file.html" at the extensionless path "/file" when there is no
+		// "file", as static site generators expect.
+		// Optional. Default value false.
+		CleanURLs bool `yaml:"clean_urls"`
+
+		// Redirect requests for HTML files, e.g. "/file.html", to their clean
+		// URL, e.g. "/file", with CleanURLs.
+		// Optional. Default value false.
+		RedirectCleanURLs bool `yaml:"redirect_clean_urls"`
+
+		// Name of the language variants of files served in place of them to
+		// clients accepting their language, with the file name without
+		// extension "{base}", the language tag "{lang}" and the extension
+		// "{ext}", e.g. "{base}.{lang}{ext}" to serve "page.de.html" for
+		// "page.html" with "Accept-Language: de".
+		// Optional. Default value "", which disables language variants.
+		LanguageVariants string `yaml:"language_variants"`
+
+		// Serve the AVIF or WebP variant of JPEG, PNG and GIF images, e.g.
+		// "photo.jpg.avif" or "photo.jpg.webp" for "photo.jpg", to clients
+		// accepting their format.
+		// Optional. Default value false.
+		ImageVariants bool `yaml:"image_variants"`
+
+		// Resize JPEG, PNG and GIF images requested with the "w" and "h"
+		// query parameters, in pixels, and the "fit" query parameter:
+		// "contain", the default, to fit them in the box, "cover" to crop them
+		// to it, or "fill" to stretch them to it, e.g.
+		// "?w=300&h=200&fit=cover". Images are never enlarged, and GIF ones
+		// are resized to PNG. Directory listings show thumbnails of images.
+		// Optional. Default value false.
+		ImageResize bool `yaml:"image_resize"`
+
+		// Allowed sizes of resized images, e.g. "300x200", or "300x" and
+		// "x200" for a width or height only. The thumbnails of directory
+		// listings are always allowed.
+		// Optional. Default value nil, which allows any size up to 4096
+		// pixels.
+		ImageSizes []string `yaml:"image_sizes"`
+
+		// Directory where resized images are cached.
+		// Optional. Default value "", which caches up to 32 MiB of them in
+		// memory.
+		ImageCacheDir string `yaml:"image_cache_dir"`
+
 		// Index file for serving a directory.
 		// Optional. Default value "index.html".
 		Index string `yaml:"index"`
 
+		// Index file candidates tried in order when Index is not found, e.g.
+		// "index.htm" or "default.html".
+		// Optional. Default value nil.
+		Indexes []string `yaml:"indexes"`
+
 		// Enable HTML5 mode by forwarding all not-found requests to root so that
 		// SPA (single-page application) can handle the routing.
 		// Optional. Default value false.
 		HTML5 bool `yaml:"html5"`
 
-		// Enable directory browsing.
+		// Redirect requests for directories without a trailing slash to the
+		// URL with it, so that relative links of index pages work.
+		// Optional. Default value true.
+		RedirectDirSlash bool `yaml:"redirect_dir_slash"`
+
+		// Redirect requests for files with a trailing slash to the URL
+		// without it.
+		// Optional. Default value false.
+		RedirectFileSlash bool `yaml:"redirect_file_slash"`
+
+		// Redirect requests for index files, e.g. "/dir/index.html", to their
+		// directory URL, e.g. "/dir/", like http.ServeFile.
+		// Optional. Default value false.
+		RedirectIndex bool `yaml:"redirect_index"`
+
+		// URL path prefixes never forwarded to root in HTML5 mode, e.g. "/api".
+		// Optional. Default value nil.
+		HTML5Exclude []string `yaml:"html5_exclude"`
+
+		// File served with status 404 when a file is not found, e.g. "404.html".
+		// Optional. Default value "".
+		NotFoundFile string `yaml:"not_found_file"`
+
+		// Render Markdown files, ".md" and ".markdown", to HTML pages. Their
+		// source is still sent as a download, see DownloadParam.
+		// Optional. Default value false.
+		RenderMarkdown bool `yaml:"render_markdown"`
+
+		// Renderer of Markdown files.
+		// Optional. Default value renders GitHub Flavored Markdown without raw
+		// HTML.
+		MarkdownRenderer MarkdownRenderer `yaml:"-"`
+
+		// Enable directory browsing. Directories are downloaded as archives
+		// with the "archive" query parameter, "zip" or "tar.gz".
 		// Optional. Default value false.
 		Browse bool `yaml:"browse"`
+
+		// Show the README file of directories, e.g. "README.md" rendered with
+		// MarkdownRenderer, below their listing.
+		// Optional. Default value false.
+		BrowseReadme bool `yaml:"browse_readme"`
+
+		// Serve the root over WebDAV, so that file managers can mount it.
+		// PROPFIND, MKCOL, PUT, DELETE, COPY, MOVE and locking requests are
+		// handled by the middleware, GET and HEAD ones served as usual. Writes
+		// require Root to be a directory of the OS filesystem, without Roots
+		// or Aliases.
+		// Optional. Default value false.
+		WebDAV bool `yaml:"webdav"`
+
+		// Only allow reading over WebDAV, with PROPFIND, GET and HEAD requests.
+		// Optional. Default value false.
+		WebDAVReadOnly bool `yaml:"webdav_read_only"`
+
+		// Accept uploads of files to listed directories, posted as multipart
+		// forms, and show an upload form in their listing. Authorize is called
+		// with the path of each uploaded file first, and is required. Uploads
+		// require Root to be a directory of the OS filesystem, without Roots or
+		// Aliases.
+		// Optional. Default value false.
+		Upload bool `yaml:"upload"`
+
+		// Maximum size in bytes of uploaded files.
+		// Optional. Default value 33554432 (32 MiB). 0 is unlimited.
+		UploadMaxSize int64 `yaml:"upload_max_size"`
+
+		// Extensions of the only files which can be uploaded, e.g. ".jpg".
+		// Optional. Default value nil, which allows any file.
+		UploadExtensions []string `yaml:"upload_extensions"`
+
+		// Replace existing files with uploaded ones. Otherwise uploads of
+		// existing files fail with status 409.
+		// Optional. Default value false.
+		UploadOverwrite bool `yaml:"upload_overwrite"`
+
+		// Delete files and empty directories with DELETE requests. Authorize
+		// is called with their path first, and is required. Deletes require
+		// Root to be a directory of the OS filesystem, without Roots or
+		// Aliases.
+		// Optional. Default value false.
+		AllowDelete bool `yaml:"allow_delete"`
+
+		// Move files and directories with POST requests whose "move" query
+		// parameter is their destination, relative to their directory unless
+		// absolute, e.g. "?move=new.txt" or "?move=/archive/old.txt".
+		// Authorize is called with their path and their destination first, and
+		// is required. Moves require Root to be a directory of the OS filesystem, without
+		// Roots or Aliases.
+		// Optional. Default value false.
+		AllowRename bool `yaml:"allow_rename"`
+
+		// Answer requests for files and directories with methods other than
+		// GET, HEAD, OPTIONS and the enabled upload, delete and move ones with
+		// status 405 and the Allow header, instead of serving them.
+		// Optional. Default value false.
+		RestrictMethods bool `yaml:"restrict_methods"`
+
+		// Reload the HTML pages served in browsers when files of the Root
+		// directories, Roots or Aliases change, for development. A script
+		// injected into the pages listens to the event stream of changes at
+		// "/__livereload", which the middleware must receive.
+		// Optional. Default value false.
+		LiveReload bool `yaml:"live_reload"`
+
+		// URL path of the stream of server-sent events of the files of the
+		// Root directories, Roots or Aliases created, modified and deleted,
+		// e.g. "/__events". Each event is a JSON object with the "type" of
+		// event, "create", "modify" or "delete", and the URL "path" of the
+		// file. Streams are authorized as listings.
+		// Optional. Default value "", which disables the stream.
+		Events string `yaml:"events"`
+
+		// Content-Security-Policy header of HTML files, served with a new
+		// random nonce for each request in place of the "__NONCE__"
+		// placeholders of the policy and of their content, e.g.
+		// "script-src 'nonce-__NONCE__' 'strict-dynamic'" for
+		// `<script nonce="__NONCE__">` elements. Such files are never cached.
+		// Optional. Default value "".
+		CSPNonce string `yaml:"csp_nonce"`
+
+		// Values of the variables of HTML files for each request, served in
+		// place of their "%NAME%" placeholders, e.g. "%API_URL%". The values
+		// aren't escaped. "%CSP_NONCE%" is the nonce of CSPNonce, if set.
+		// Placeholders of other variables are kept. Such files are never
+		// cached.
+		// Optional. Default value nil.
+		InjectVars func(route.Context) map[string]string `yaml:"-"`
+
+		// Template of directory listings, executed with a DirListing.
+		// Optional. Default value is the built-in template.
+		BrowseTemplate *template.Template `yaml:"-"`
+
+		// BrowseAuth authorizes directory listings, not the files listed.
+		// Directories are handled as if browsing were disabled when it returns
+		// false.
+		// Optional. Default value nil, which allows every listing.
+		BrowseAuth func(route.Context) (bool, error) `yaml:"-"`
+
+		// Secret token enabling directory listings, like BrowseAuth, only for
+		// requests with it in the "browse_token" query parameter, the
+		// X-Browse-Token header or the cookie set from the query parameter,
+		// e.g. to inspect production trees without exposing their listings.
+		// Optional. Default value "", which doesn't require a token.
+		BrowseToken string `yaml:"browse_token"`
+
+		// Ignore hidden files and directories, whose names start with a dot,
+		// except "/.well-known/".
+		// Optional. Default value true.
+		IgnoreHidden bool `yaml:"ignore_hidden"`
+
+		// Glob patterns of files and directories which are neither served nor
+		// listed, e.g. "**/*.map" or "**/secrets/**". Patterns without a slash
+		// match the file name, others the path from the root.
+		// Optional. Default value nil.
+		Exclude []string `yaml:"exclude"`
+
+		// Glob patterns of sensitive files and directories which are neither
+		// served nor listed, as for Exclude, protecting secrets when Root is a
+		// project directory. They are matched case-insensitively, as names
+		// of case-insensitive filesystems may differ in case. Empty to
+		// disable.
+		// Optional. Default value is environment files, keys, certificates,
+		// databases, backup files and VCS directories.
+		Denylist []string `yaml:"denylist"`
+
+		// Glob patterns of the only files which are served and listed, e.g.
+		// "*.{html,css,js}". Directories are not filtered.
+		// Optional. Default value nil, which includes all files.
+		Include []string `yaml:"include"`
+
+		// Follow symlinks pointing outside of Root. Only applies to the OS
+		// filesystem.
+		// Optional. Default value false.
+		FollowSymlinks bool `yaml:"follow_symlinks"`
+
+		// Resolve request paths case-insensitively when no file matches them
+		// exactly, e.g. "/Logo.PNG" to "logo.png", for sites migrated from
+		// case-insensitive filesystems. The names of the entries of
+		// directories are cached until invalidated.
+		// Optional. Default value false.
+		CaseInsensitive bool `yaml:"case_insensitive"`
+
+		// Unicode normalization form of request paths, "NFC" or "NFD", so that
+		// names with composed or decomposed characters match however clients
+		// encode them. Paths are kept as requested when only they match a
+		// file, e.g. for files named in the other form. Empty to disable.
+		// Optional. Default value "NFC".
+		UnicodeNormalization string `yaml:"unicode_normalization"`
+
+		// Maximum size in bytes of served files, beyond which requests are
+		// answered with 403 Forbidden instead of streaming them.
+		// Optional. Default value 0, which is unlimited.
+		MaxFileSize int64 `yaml:"max_file_size"`
+
+		// Status of the responses for files which can't be read for lack of
+		// permission, e.g. 404 to hide their existence.
+		// Optional. Default value 403. 0 returns the error as is.
+		PermissionDeniedStatus int `yaml:"permission_denied_status"`
+
+		// Authorize is called with the path from the root of each file before
+		// serving it, or uploading it, in which case the FileInfo is nil unless
+		// it replaces a file. Its errors are returned by the middleware, e.g.
+		// route.ErrForbidden.
+		// Optional. Default value nil.
+		Authorize func(c route.Context, path string, fi os.FileInfo) error `yaml:"-"`
+
+		// Serve tree views of directories, listing their entries recursively,
+		// with the "tree" query parameter, e.g. "?tree=1".
+		// Optional. Default value false.
+		BrowseTree bool `yaml:"browse_tree"`
+
+		// Number of levels of directories of tree views.
+		// Optional. Default value 3.
+		BrowseTreeDepth int `yaml:"browse_tree_depth"`
+
+		// Number of entries per page of directory listings, which can be
+		// changed with the "per_page" query parameter. Pages are selected with
+		// the "page" query parameter.
+		// Optional. Default value 0, which lists all the entries.
+		BrowsePerPage int `yaml:"browse_per_page"`
+
+		// Maximum size in bytes of directory listings sent with a
+		// Content-Length header, for which HEAD requests and ranges are
+		// answered. Larger listings are streamed with chunked encoding.
+		// Optional. Default value 4194304 (4 MiB). 0 is unlimited.
+		BrowseBufferSize int64 `yaml:"browse_buffer_size"`
+
+		// Maximum number of entries of directory listings, the first ones in
+		// sort order. Listings beyond it show how many entries were left
+		// out, and JSON listings are objects with the "entries", a
+		// "truncated" flag and the "count" of all the entries, instead of
+		// arrays.
+		// Optional. Default value 0, which lists all the entries.
+		BrowseMaxEntries int `yaml:"browse_max_entries"`
+
+		// Layout of modification times in directory listings, see time.Format.
+		// Optional. Default value "2006-01-02 15:04:05".
+		BrowseTimeFormat string `yaml:"browse_time_format"`
+
+		// Time zone of modification times in directory listings.
+		// Optional. Default value is the local time zone.
+		BrowseTimeLocation *time.Location `yaml:"-"`
+
+		// Cache-Control rules for served files. The first matching rule wins.
+		// Optional. Default value nil.
+		CacheControl []CacheRule `yaml:"cache_control"`
+
+		// Response header rules for served files. Every matching rule applies,
+		// later rules overriding the headers of earlier ones.
+		// Optional. Default value nil.
+		Headers []HeaderRule `yaml:"headers"`
+
+		// Preload rules of entry-point pages, adding a Link header preloading
+		// each critical asset of the pages matching any of their patterns,
+		// e.g. `</app.js>; rel=preload; as=script`.
+		// Optional. Default value nil.
+		Preload []PreloadRule `yaml:"preload"`
+
+		// Send the Link headers of Preload in a 103 Early Hints response
+		// before the page, so that clients fetch the assets meanwhile.
+		// Optional. Default value false.
+		EarlyHints bool `yaml:"early_hints"`
+
+		// URLs of the assets pushed with the pages by path, e.g.
+		// "/index.html": {"/app.js", "/app.css"}, over HTTP/2 connections
+		// supporting server push. Other clients get Link headers preloading
+		// them instead.
+		// Optional. Default value nil.
+		Push map[string][]string `yaml:"push"`
+
+		// Serve precompressed "file.br" and "file.gz" sidecars in place of
+		// "file" to clients accepting the encoding.
+		// Optional. Default value false.
+		Precompressed bool `yaml:"precompressed"`
+
+		// Generate brotli and gzip variants of the files of CompressTypes
+		// when the middleware is created, so that no request waits for their
+		// compression. Files changed since are sent uncompressed, unless
+		// Compress is set.
+		// Optional. Default value false.
+		Precompress bool `yaml:"precompress"`
+
+		// Directory where the variants generated with Precompress are stored,
+		// named by the SHA-256 of the content of their file, so that they are
+		// reused on restarts.
+		// Optional. Default value "", which keeps them in memory.
+		PrecompressDir string `yaml:"precompress_dir"`
+
+		// Glob patterns of files always sent as attachments, prompting clients
+		// to save them.
+		// Optional. Default value nil.
+		ForceDownload []string `yaml:"force_download"`
+
+		// Content-Type by file extension, overriding or extending the system
+		// MIME types, e.g. ".wasm": "application/wasm".
+		// Optional. Default value nil.
+		MIMETypes map[string]string `yaml:"mime_types"`
+
+		// Send "X-Content-Type-Options: nosniff" and files of unknown
+		// extensions as "application/octet-stream" instead of sniffing their
+		// type from their content.
+		// Optional. Default value false.
+		NoSniff bool `yaml:"nosniff"`
+
+		// Charset appended to text types without one, e.g. "utf-8".
+		// Optional. Default value "".
+		DefaultCharset string `yaml:"default_charset"`
+
+		// Origins allowed to fetch files cross-origin, e.g.
+		// "https://example.com", or "*" for any. Preflight requests for files
+		// are answered by the middleware.
+		// Optional. Default value nil, which disables CORS.
+		CORSOrigins []string `yaml:"cors_origins"`
+
+		// Time for which clients may cache preflight responses.
+		// Optional. Default value 0, which omits Access-Control-Max-Age.
+		CORSMaxAge time.Duration `yaml:"cors_max_age"`
+
+		// Query parameter sending a file as an attachment, e.g. "?download=1".
+		// Optional. Default value "download". An empty name disables it.
+		DownloadParam string `yaml:"download_param"`
+
+		// Compress responses on the fly with brotli or gzip, directory
+		// listings included.
+		// Optional. Default value false.
+		Compress bool `yaml:"compress"`
+
+		// Minimum size in bytes of compressed responses.
+		// Optional. Default value 1024.
+		CompressMinSize int64 `yaml:"compress_min_size"`
+
+		// MIME type prefixes of compressed responses.
+		// Optional. Default value is text, script, JSON, XML, SVG and font types.
+		CompressTypes []string `yaml:"compress_types"`
+
+		// Disable range requests, always sending the whole content.
+		// Optional. Default value false.
+		DisableRange bool `yaml:"disable_range"`
+
+		// Maximum number of ranges of a request, beyond which the whole
+		// content is sent, so that requests for many small or overlapping
+		// ranges can't amplify the response. Requests for several ranges are
+		// answered with multipart/byteranges responses.
+		// Optional. Default value 0, which is unlimited.
+		MaxRanges int `yaml:"max_ranges"`
+
+		// Maximum rate in bytes per second at which the responses of each
+		// connection are sent, together.
+		// Optional. Default value 0, which is unlimited.
+		ThrottleRate int64 `yaml:"throttle_rate"`
+
+		// Number of bytes sent at once before throttling applies.
+		// Optional. Default value is ThrottleRate.
+		ThrottleBurst int64 `yaml:"throttle_burst"`
+
+		// Maximum number of files sent simultaneously. Other requests wait for
+		// TransferWait, then fail with status 503.
+		// Optional. Default value 0, which is unlimited.
+		MaxTransfers int `yaml:"max_transfers"`
+
+		// Time requests wait for a transfer when MaxTransfers are in progress.
+		// Optional. Default value 0, which fails them immediately.
+		TransferWait time.Duration `yaml:"transfer_wait"`
+
+		// Metrics collects the metrics of each request served. Requests
+		// handled by the next handler are not collected.
+		// Optional. Default value nil.
+		Metrics Collector `yaml:"-"`
+
+		// OnServe is called after each request served, e.g. to log it.
+		// Requests handled by the next handler are not reported.
+		// Optional. Default value nil.
+		OnServe func(ServeEvent) `yaml:"-"`
+
+		// Number of times the operations of the filesystem are retried after
+		// transient errors, e.g. too many open files, waiting IORetryBackoff
+		// before the first retry and twice as long before each next one.
+		// Transient errors which aren't retried are answered with status 503.
+		// Optional. Default value 0.
+		IORetries int `yaml:"io_retries"`
+
+		// Time waited before retrying an operation of the filesystem.
+		// Optional. Default value 10 milliseconds.
+		IORetryBackoff time.Duration `yaml:"io_retry_backoff"`
+
+		// Number of consecutive transient errors, once retried, after which
+		// the filesystem is considered unhealthy: requests are answered with
+		// status 503 and a Retry-After header for IOBreakerCooldown, then
+		// the filesystem is tried again.
+		// Optional. Default value 0, which disables the circuit breaker.
+		IOBreakerThreshold int `yaml:"io_breaker_threshold"`
+
+		// Time for which requests are rejected once the filesystem is
+		// considered unhealthy.
+		// Optional. Default value 5 seconds.
+		IOBreakerCooldown time.Duration `yaml:"io_breaker_cooldown"`
+
+		// OnIOError is called with each transient error of the filesystem,
+		// e.g. to log it.
+		// Optional. Default value nil.
+		OnIOError func(IOEvent) `yaml:"-"`
+
+		// ErrorHandler is called with the errors of the middleware, e.g. to
+		// log unexpected ones or map them to other statuses, and returns the
+		// error passed to the mux, nil once it answered the request. Errors
+		// returned by the next handler aren't passed to it.
+		// Optional. Default value nil.
+		ErrorHandler func(c route.Context, err error) error `yaml:"-"`
+
+		// Maximum size in bytes of the in-memory cache of file contents. Files
+		// taking more than a quarter of it aren't cached.
+		// Optional. Default value 0, which disables the cache.
+		CacheMaxBytes int64 `yaml:"cache_max_bytes"`
+
+		// Maximum number of files in the in-memory cache.
+		// Optional. Default value 0, which is unlimited.
+		CacheMaxEntries int `yaml:"cache_max_entries"`
+
+		// Time after which cached files are read again.
+		// Optional. Default value 0, which caches files until evicted.
+		CacheTTL time.Duration `yaml:"cache_ttl"`
+
+		// Time for which file metadata is cached, saving a stat per request.
+		// Optional. Default value 0, which disables the cache.
+		StatCacheTTL time.Duration `yaml:"stat_cache_ttl"`
+
+		// Serve files at their fingerprinted paths, e.g. "/app.js" at
+		// "/app.3f9a2b1c.js" where "3f9a2b1c" starts the SHA-256 digest of its
+		// content, with far-future caching. See Handle.Manifest.
+		// Optional. Default value false.
+		Fingerprint bool `yaml:"fingerprint"`
+
+		// Vite or webpack manifest file mapping logical asset names to hashed
+		// file names, e.g. ".vite/manifest.json", served at the logical names.
+		// See Handle.ResolveAsset.
+		// Optional. Default value "".
+		ManifestFile string `yaml:"manifest"`
+	}
+
+	// CacheRule sets the Cache-Control header of files matching any of its
+	// patterns.
+	CacheRule struct {
+		// Glob patterns, e.g. "*.js" or "/assets/*". Patterns without a slash
+		// match the file name, others the path from the root.
+		Patterns []string `yaml:"patterns"`
+
+		// Cache-Control value, e.g. "public, max-age=31536000, immutable".
+		Value string `yaml:"value"`
 	}
+
+	// HeaderRule sets response headers of files matching any of its patterns.
+	HeaderRule struct {
+		// Glob patterns, as for CacheRule.
+		Patterns []string `yaml:"patterns"`
+
+		// Headers by name, e.g. "Cross-Origin-Embedder-Policy": "require-corp".
+		Values map[string]string `yaml:"values"`
+	}
+
+	// PreloadRule preloads assets with the pages matching any of its
+	// patterns.
+	PreloadRule struct {
+		// Glob patterns, as for CacheRule, e.g. "/index.html".
+		Patterns []string `yaml:"patterns"`
+
+		// URLs of the assets, e.g. "/app.js" or "/fonts/inter.woff2".
+		Assets []string `yaml:"assets"`
+	}
+)
+
+// DefaultDenylist is the default value of Options.Denylist.
+var DefaultDenylist = []string{
+	".env", ".env.*", "*.pem", "*.key", "*.p12", "*.pfx", "id_rsa", "id_ed25519",
+	"*.sqlite", "*.sqlite3", "*.db",
+	"*~", "*.bak", "*.old", "*.orig", "*.swp",
+	".git", ".svn", ".hg", ".bzr", "cvs",
+}
+
+// Headers not defined by route.
+const (
+	headerCacheControl = "Cache-Control"
+	headerETag         = "ETag"
+	headerRange        = "Range"
+	headerAcceptRanges = "Accept-Ranges"
 )
 
 type Option func(*Options)
 
 func GetDefaultOptions() Options {
 	return Options{
-		Skipper: route.DefaultSkipper,
-		Root:    ".",
-		Index:   "index.html",
-		HTML5:   false,
-		Browse:  false,
+		Skipper:                route.DefaultSkipper,
+		Root:                   ".",
+		Index:                  "index.html",
+		MaxPathLength:          4096,
+		MaxPathDepth:           64,
+		HTML5:                  false,
+		RedirectDirSlash:       true,
+		Browse:                 false,
+		BrowseTimeFormat:       "2006-01-02 15:04:05",
+		BrowseTreeDepth:        3,
+		BrowseBufferSize:       4 << 20,
+		UploadMaxSize:          32 << 20,
+		IgnoreHidden:           true,
+		Denylist:               DefaultDenylist,
+		UnicodeNormalization:   "NFC",
+		DownloadParam:          "download",
+		CompressMinSize:        1024,
+		CompressTypes:          defaultCompressTypes,
+		IORetryBackoff:         10 * time.Millisecond,
+		IOBreakerCooldown:      5 * time.Second,
+		PermissionDeniedStatus: http.StatusForbidden,
+	}
+}
+
+// WithOptions replaces all the options with opts, e.g. built from
+// GetDefaultOptions or OptionsFromYAML. Options after it override them.
+func WithOptions(opts Options) Option {
+	return func(o *Options) {
+		*o = opts
 	}
 }
 
@@ -63,227 +713,1884 @@ func Root(root string) Option {
 	}
 }
 
-func Index(index string) Option {
+func Roots(roots ...string) Option {
 	return func(o *Options) {
-		o.Index = index
+		o.Roots = roots
 	}
 }
 
-func HTML5(html5 bool) Option {
+// Alias serves the directory dir at the URL path prefix.
+func Alias(prefix, dir string) Option {
 	return func(o *Options) {
-		o.HTML5 = html5
+		if o.Aliases == nil {
+			o.Aliases = map[string]string{}
+		}
+		o.Aliases[prefix] = dir
 	}
 }
 
-func Browse(browse bool) Option {
+// VHost serves hosts matching the patterns from their root directory.
+func VHost(roots map[string]string) Option {
 	return func(o *Options) {
-		o.Browse = browse
+		o.VHosts = roots
 	}
 }
 
-const html = `
-<!DOCTYPE html>
-<html lang="en">
-<head>
-  <meta charset="UTF-8">
-  <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <meta http-equiv="X-UA-Compatible" content="ie=edge">
-  <title>{{ .Name }}</title>
-  <style>
-    body {
-			font-family: Menlo, Consolas, monospace;
-			padding: 48px;
-		}
-		header {
-			padding: 4px 16px;
-			font-size: 24px;
-		}
-    ul {
-			list-style-type: none;
-			margin: 0;
-    	padding: 20px 0 0 0;
-			display: flex;
-			flex-wrap: wrap;
-    }
-    li {
-			width: 300px;
-			padding: 16px;
-		}
-		li a {
-			display: block;
-			overflow: hidden;
-			white-space: nowrap;
-			text-overflow: ellipsis;
-			text-decoration: none;
-			transition: opacity 0.25s;
-		}
-		li span {
-			color: #707070;
-			font-size: 12px;
-		}
-		li a:hover {
-			opacity: 0.50;
-		}
-		.dir {
-			color: #E91E63;
-		}
-		.file {
-			color: #673AB7;
-		}
-  </style>
-</head>
-<body>
-	<header>
-		{{ .Name }}
-	</header>
-	<ul>
-		{{ range .Files }}
-		<li>
-		{{ if .Dir }}
-			{{ $name := print .Name "/" }}
-			<a class="dir" href="{{ $name }}">{{ $name }}</a>
-			{{ else }}
-			<a class="file" href="{{ .Name }}">{{ .Name }}</a>
-			<span>{{ .Size }}</span>
-		{{ end }}
-		</li>
-		{{ end }}
-  </ul>
-</body>
-</html>
-`
+// RootFunc sets the function returning the root directory of each request.
+func RootFunc(root func(route.Context) string) Option {
+	return func(o *Options) {
+		o.RootFunc = root
+	}
+}
 
-// New returns a Static middleware.
-func New(options ...Option) route.MiddlewareFunc {
-	// Apply options.
-	opts := GetDefaultOptions()
-	for _, opt := range options {
-		opt(&opts)
+// TenantRoot sets the function returning the tenant of each request, served
+// from its subdirectory of Root.
+func TenantRoot(tenant func(route.Context) (string, error)) Option {
+	return func(o *Options) {
+		o.TenantRoot = tenant
 	}
+}
 
-	// Index template
-	t, err := template.New("index").Parse(html)
-	if err != nil {
-		panic(fmt.Sprintf("static: %v", err))
+func Filesystem(fsys fs.FS) Option {
+	return func(o *Options) {
+		o.Filesystem = fsys
 	}
+}
 
-	return func(c route.Context, next route.HandlerFunc) (err error) {
-		if opts.Skipper(c) {
-			return next(c)
-		}
+// File serves only the file at the path for every request, e.g.
+// `mux.GET("/favicon.ico", route.NotFoundHandler, static.New(static.File("assets/favicon.ico")))`.
+func File(name string) Option {
+	return func(o *Options) {
+		o.File = name
+	}
+}
 
-		p := c.Request().URL.Path
-		if strings.HasSuffix(c.Path(), "*") { // When serving from a group, e.g. `/static*`.
-			p = c.Param("*")
-		}
-		p, err = url.PathUnescape(p)
-		if err != nil {
-			return
-		}
-		name := filepath.Join(opts.Root, path.Clean("/"+p)) // "/"+ for security
+// Backend serves the content of a storage backend other than the OS
+// filesystem, e.g. `Backend(s3backend.New("bucket"))`. Backends should
+// implement BackendFS, other filesystems are read with the io/fs functions. It
+// is the same as Filesystem.
+func Backend(backend fs.FS) Option {
+	return Filesystem(backend)
+}
 
-		fi, err := os.Stat(name)
-		if err != nil {
-			if os.IsNotExist(err) {
-				if err = next(c); err != nil {
-					if he, ok := err.(*route.HTTPError); ok {
-						if opts.HTML5 && he.Code == http.StatusNotFound {
-							return c.File(filepath.Join(opts.Root, opts.Index))
-						}
-					}
-					return
-				}
-			}
-			return
-		}
+// Embed serves the content embedded in fsys under the subdir directory, so
+// that the request path "/a.txt" maps to "subdir/a.txt".
+func Embed(fsys embed.FS, subdir string) Option {
+	return func(o *Options) {
+		o.Filesystem = fsys
+		o.Root = subdir
+	}
+}
 
-		if fi.IsDir() {
-			index := filepath.Join(name, opts.Index)
-			fi, err = os.Stat(index)
+// StripPrefix serves only requests under prefix, removing it from their
+// path.
+func StripPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.StripPrefix = prefix
+	}
+}
 
-			if err != nil {
-				if opts.Browse {
-					return listDir(t, name, c.Response())
-				}
-				if os.IsNotExist(err) {
-					return next(c)
-				}
-				return
-			}
+// MaxPathLength limits the length of request paths.
+func MaxPathLength(n int) Option {
+	return func(o *Options) {
+		o.MaxPathLength = n
+	}
+}
 
-			return c.File(index)
-		}
+// MaxPathDepth limits the number of directories of request paths.
+func MaxPathDepth(n int) Option {
+	return func(o *Options) {
+		o.MaxPathDepth = n
+	}
+}
 
-		return c.File(name)
+// Rewrite rewrites request paths matching the patterns.
+func Rewrite(rules map[string]string) Option {
+	return func(o *Options) {
+		o.Rewrite = rules
 	}
 }
 
-func listDir(t *template.Template, name string, res *route.Response) (err error) {
-	file, err := os.Open(name)
-	if err != nil {
-		return
+// TryFiles serves the first of the candidates found.
+func TryFiles(candidates ...string) Option {
+	return func(o *Options) {
+		o.TryFiles = candidates
 	}
-	files, err := file.Readdir(-1)
-	if err != nil {
-		return
+}
+
+// CleanURLs serves HTML files at their extensionless path.
+func CleanURLs(clean bool) Option {
+	return func(o *Options) {
+		o.CleanURLs = clean
+	}
+}
+
+// RedirectCleanURLs redirects requests for HTML files to their clean URL.
+func RedirectCleanURLs(redirect bool) Option {
+	return func(o *Options) {
+		o.RedirectCleanURLs = redirect
+	}
+}
+
+// LanguageVariants serves the variants of files named after the pattern in
+// the language preferred by clients.
+func LanguageVariants(pattern string) Option {
+	return func(o *Options) {
+		o.LanguageVariants = pattern
 	}
+}
 
-	// Create directory index.
-	res.Header().Set(route.HeaderContentType, route.MIMETextHTMLCharsetUTF8)
-	data := struct {
-		Name  string
-		Files []interface{}
-	}{
-		Name: name,
+// ImageVariants serves the AVIF or WebP variants of images.
+func ImageVariants(variants bool) Option {
+	return func(o *Options) {
+		o.ImageVariants = variants
 	}
-	for _, f := range files {
-		data.Files = append(data.Files, struct {
-			Name string
-			Dir  bool
-			Size string
-		}{f.Name(), f.IsDir(), formatFileSize(f.Size())})
+}
+
+// ImageResize enables resizing images with query parameters.
+func ImageResize(resize bool) Option {
+	return func(o *Options) {
+		o.ImageResize = resize
 	}
-	return t.Execute(res, data)
 }
 
-const (
-	_ = 1.0 << (10 * iota) // Ignore first value by assigning to blank identifier.
-	KB
-	MB
-	GB
-	TB
-	PB
-	EB
-)
+// ImageSizes restricts resized images to the sizes.
+func ImageSizes(sizes ...string) Option {
+	return func(o *Options) {
+		o.ImageSizes = sizes
+	}
+}
+
+// ImageCacheDir caches resized images in the directory.
+func ImageCacheDir(dir string) Option {
+	return func(o *Options) {
+		o.ImageCacheDir = dir
+	}
+}
+
+// Index sets the index file for serving a directory and the candidates tried
+// in order when it is not found.
+func Index(index string, candidates ...string) Option {
+	return func(o *Options) {
+		o.Index = index
+		o.Indexes = candidates
+	}
+}
+
+func HTML5(html5 bool) Option {
+	return func(o *Options) {
+		o.HTML5 = html5
+	}
+}
+
+func RedirectDirSlash(redirect bool) Option {
+	return func(o *Options) {
+		o.RedirectDirSlash = redirect
+	}
+}
+
+func RedirectFileSlash(redirect bool) Option {
+	return func(o *Options) {
+		o.RedirectFileSlash = redirect
+	}
+}
+
+func RedirectIndex(redirect bool) Option {
+	return func(o *Options) {
+		o.RedirectIndex = redirect
+	}
+}
+
+func HTML5Exclude(prefixes ...string) Option {
+	return func(o *Options) {
+		o.HTML5Exclude = append(o.HTML5Exclude, prefixes...)
+	}
+}
 
-func formatFileSize(b int64) string {
-	multiple := ""
-	value := float64(b)
-
-	switch {
-	case b >= EB:
-		value /= EB
-		multiple = "EB"
-	case b >= PB:
-		value /= PB
-		multiple = "PB"
-	case b >= TB:
-		value /= TB
-		multiple = "TB"
-	case b >= GB:
-		value /= GB
-		multiple = "GB"
-	case b >= MB:
-		value /= MB
-		multiple = "MB"
-	case b >= KB:
-		value /= KB
-		multiple = "KB"
-	case b == 0:
-		return "0"
-	default:
-		return strconv.FormatInt(b, 10) + "B"
-	}
-
-	return fmt.Sprintf("%.2f%s", value, multiple)
+func NotFoundFile(file string) Option {
+	return func(o *Options) {
+		o.NotFoundFile = file
+	}
+}
+
+func RenderMarkdown(render bool) Option {
+	return func(o *Options) {
+		o.RenderMarkdown = render
+	}
+}
+
+// Markdown renders Markdown files to HTML pages with the renderer.
+func Markdown(renderer MarkdownRenderer) Option {
+	return func(o *Options) {
+		o.RenderMarkdown = true
+		o.MarkdownRenderer = renderer
+	}
+}
+
+func Browse(browse bool) Option {
+	return func(o *Options) {
+		o.Browse = browse
+	}
+}
+
+func BrowseReadme(readme bool) Option {
+	return func(o *Options) {
+		o.BrowseReadme = readme
+	}
+}
+
+// WebDAV enables WebDAV, reading and writing the root.
+func WebDAV(enable bool) Option {
+	return func(o *Options) {
+		o.WebDAV = enable
+	}
+}
+
+// WebDAVReadOnly enables read-only WebDAV.
+func WebDAVReadOnly(readOnly bool) Option {
+	return func(o *Options) {
+		o.WebDAV = o.WebDAV || readOnly
+		o.WebDAVReadOnly = readOnly
+	}
+}
+
+// Upload enables uploads to listed directories.
+func Upload(upload bool) Option {
+	return func(o *Options) {
+		o.Upload = upload
+	}
+}
+
+// UploadMaxSize sets the maximum size of uploaded files.
+func UploadMaxSize(size int64) Option {
+	return func(o *Options) {
+		o.UploadMaxSize = size
+	}
+}
+
+// UploadExtensions restricts uploads to files with one of the extensions.
+func UploadExtensions(exts ...string) Option {
+	return func(o *Options) {
+		o.UploadExtensions = exts
+	}
+}
+
+// UploadOverwrite allows uploads to replace existing files.
+func UploadOverwrite(overwrite bool) Option {
+	return func(o *Options) {
+		o.UploadOverwrite = overwrite
+	}
+}
+
+// AllowDelete enables deleting files.
+func AllowDelete(allow bool) Option {
+	return func(o *Options) {
+		o.AllowDelete = allow
+	}
+}
+
+// AllowRename enables moving files.
+func AllowRename(allow bool) Option {
+	return func(o *Options) {
+		o.AllowRename = allow
+	}
+}
+
+// LiveReload reloads the served HTML pages on file changes.
+func LiveReload(reload bool) Option {
+	return func(o *Options) {
+		o.LiveReload = reload
+	}
+}
+
+// Events serves the stream of file events at the URL path p.
+func Events(p string) Option {
+	return func(o *Options) {
+		o.Events = p
+	}
+}
+
+// CSPNonce sets the Content-Security-Policy of HTML files, with a random nonce
+// for each request in place of the "__NONCE__" placeholders of the policy and
+// of the files.
+func CSPNonce(policy string) Option {
+	return func(o *Options) {
+		o.CSPNonce = policy
+	}
+}
+
+// InjectVars substitutes the "%NAME%" placeholders of HTML files with the
+// values of the variables returned by vars for each request.
+func InjectVars(vars func(route.Context) map[string]string) Option {
+	return func(o *Options) {
+		o.InjectVars = vars
+	}
+}
+
+// RestrictMethods answers requests with other methods than the allowed ones
+// with status 405.
+func RestrictMethods(restrict bool) Option {
+	return func(o *Options) {
+		o.RestrictMethods = restrict
+	}
+}
+
+func IgnoreHidden(ignore bool) Option {
+	return func(o *Options) {
+		o.IgnoreHidden = ignore
+	}
+}
+
+func Exclude(patterns ...string) Option {
+	return func(o *Options) {
+		o.Exclude = append(o.Exclude, patterns...)
+	}
+}
+
+// Denylist sets the patterns of sensitive files, replacing DefaultDenylist.
+// Denylist() serves them.
+func Denylist(patterns ...string) Option {
+	return func(o *Options) {
+		o.Denylist = patterns
+	}
+}
+
+func Include(patterns ...string) Option {
+	return func(o *Options) {
+		o.Include = append(o.Include, patterns...)
+	}
+}
+
+func FollowSymlinks(follow bool) Option {
+	return func(o *Options) {
+		o.FollowSymlinks = follow
+	}
+}
+
+// CaseInsensitive resolves request paths case-insensitively.
+func CaseInsensitive(insensitive bool) Option {
+	return func(o *Options) {
+		o.CaseInsensitive = insensitive
+	}
+}
+
+// UnicodeNormalization normalizes request paths to the form, "NFC" or "NFD".
+func UnicodeNormalization(form string) Option {
+	return func(o *Options) {
+		o.UnicodeNormalization = form
+	}
+}
+
+// MaxFileSize sets the maximum size of served files.
+func MaxFileSize(size int64) Option {
+	return func(o *Options) {
+		o.MaxFileSize = size
+	}
+}
+
+// PermissionDeniedStatus sets the status of the responses for files which
+// can't be read for lack of permission.
+func PermissionDeniedStatus(code int) Option {
+	return func(o *Options) {
+		o.PermissionDeniedStatus = code
+	}
+}
+
+func Authorize(authorize func(c route.Context, path string, fi os.FileInfo) error) Option {
+	return func(o *Options) {
+		o.Authorize = authorize
+	}
+}
+
+func BrowseAuth(auth func(route.Context) (bool, error)) Option {
+	return func(o *Options) {
+		o.BrowseAuth = auth
+	}
+}
+
+// BrowseToken enables directory listings only for requests with the token.
+func BrowseToken(token string) Option {
+	return func(o *Options) {
+		o.BrowseToken = token
+	}
+}
+
+func BrowseTemplate(t *template.Template) Option {
+	return func(o *Options) {
+		o.BrowseTemplate = t
+	}
+}
+
+func BrowseTree(tree bool) Option {
+	return func(o *Options) {
+		o.BrowseTree = tree
+	}
+}
+
+func BrowseTreeDepth(depth int) Option {
+	return func(o *Options) {
+		o.BrowseTreeDepth = depth
+	}
+}
+
+func BrowsePerPage(perPage int) Option {
+	return func(o *Options) {
+		o.BrowsePerPage = perPage
+	}
+}
+
+// BrowseBufferSize sets the maximum size of the listings sent with a
+// Content-Length header.
+func BrowseBufferSize(size int64) Option {
+	return func(o *Options) {
+		o.BrowseBufferSize = size
+	}
+}
+
+// BrowseMaxEntries limits the number of entries of directory listings.
+func BrowseMaxEntries(n int) Option {
+	return func(o *Options) {
+		o.BrowseMaxEntries = n
+	}
+}
+
+func BrowseTimeFormat(layout string) Option {
+	return func(o *Options) {
+		o.BrowseTimeFormat = layout
+	}
+}
+
+func BrowseTimeLocation(loc *time.Location) Option {
+	return func(o *Options) {
+		o.BrowseTimeLocation = loc
+	}
+}
+
+func CacheControl(rules ...CacheRule) Option {
+	return func(o *Options) {
+		o.CacheControl = append(o.CacheControl, rules...)
+	}
+}
+
+func Headers(rules ...HeaderRule) Option {
+	return func(o *Options) {
+		o.Headers = append(o.Headers, rules...)
+	}
+}
+
+// Preload preloads the assets of the rules with the matching pages.
+func Preload(rules ...PreloadRule) Option {
+	return func(o *Options) {
+		o.Preload = append(o.Preload, rules...)
+	}
+}
+
+// EarlyHints sends the preloaded assets in 103 Early Hints responses.
+func EarlyHints(hints bool) Option {
+	return func(o *Options) {
+		o.EarlyHints = hints
+	}
+}
+
+// Push pushes the assets with the pages by path over HTTP/2.
+func Push(assets map[string][]string) Option {
+	return func(o *Options) {
+		o.Push = assets
+	}
+}
+
+func Precompressed(precompressed bool) Option {
+	return func(o *Options) {
+		o.Precompressed = precompressed
+	}
+}
+
+// Precompress generates compressed variants of the files when the middleware
+// is created.
+func Precompress(precompress bool) Option {
+	return func(o *Options) {
+		o.Precompress = precompress
+	}
+}
+
+// PrecompressDir sets the directory where the variants generated with
+// Precompress are stored.
+func PrecompressDir(dir string) Option {
+	return func(o *Options) {
+		o.PrecompressDir = dir
+	}
+}
+
+func MIMETypes(types map[string]string) Option {
+	return func(o *Options) {
+		o.MIMETypes = types
+	}
+}
+
+func NoSniff(nosniff bool) Option {
+	return func(o *Options) {
+		o.NoSniff = nosniff
+	}
+}
+
+func DefaultCharset(charset string) Option {
+	return func(o *Options) {
+		o.DefaultCharset = charset
+	}
+}
+
+func CORS(origins []string, maxAge time.Duration) Option {
+	return func(o *Options) {
+		o.CORSOrigins = origins
+		o.CORSMaxAge = maxAge
+	}
+}
+
+func ForceDownload(patterns ...string) Option {
+	return func(o *Options) {
+		o.ForceDownload = append(o.ForceDownload, patterns...)
+	}
+}
+
+func DownloadParam(name string) Option {
+	return func(o *Options) {
+		o.DownloadParam = name
+	}
+}
+
+func Compress(compress bool) Option {
+	return func(o *Options) {
+		o.Compress = compress
+	}
+}
+
+func CompressMinSize(size int64) Option {
+	return func(o *Options) {
+		o.CompressMinSize = size
+	}
+}
+
+func CompressTypes(types ...string) Option {
+	return func(o *Options) {
+		o.CompressTypes = types
+	}
+}
+
+func DisableRange(disable bool) Option {
+	return func(o *Options) {
+		o.DisableRange = disable
+	}
+}
+
+// MaxRanges limits the number of ranges of a request.
+func MaxRanges(n int) Option {
+	return func(o *Options) {
+		o.MaxRanges = n
+	}
+}
+
+// Throttle limits the rate at which the responses of each connection are
+// sent, with a token bucket refilled at bytesPerSec up to burst bytes.
+func Throttle(bytesPerSec, burst int64) Option {
+	return func(o *Options) {
+		o.ThrottleRate = bytesPerSec
+		o.ThrottleBurst = burst
+	}
+}
+
+func MaxTransfers(n int, wait time.Duration) Option {
+	return func(o *Options) {
+		o.MaxTransfers = n
+		o.TransferWait = wait
+	}
+}
+
+func Metrics(collector Collector) Option {
+	return func(o *Options) {
+		o.Metrics = collector
+	}
+}
+
+func OnServe(fn func(ServeEvent)) Option {
+	return func(o *Options) {
+		o.OnServe = fn
+	}
+}
+
+// IORetry sets the number of retries of the operations of the filesystem
+// after transient errors, and the time waited before the first one.
+func IORetry(retries int, backoff time.Duration) Option {
+	return func(o *Options) {
+		o.IORetries = retries
+		o.IORetryBackoff = backoff
+	}
+}
+
+// IOBreaker sets the number of consecutive transient errors after which
+// requests are rejected for the cooldown.
+func IOBreaker(threshold int, cooldown time.Duration) Option {
+	return func(o *Options) {
+		o.IOBreakerThreshold = threshold
+		o.IOBreakerCooldown = cooldown
+	}
+}
+
+// OnIOError sets the function called with each transient error of the
+// filesystem.
+func OnIOError(fn func(IOEvent)) Option {
+	return func(o *Options) {
+		o.OnIOError = fn
+	}
+}
+
+// ErrorHandler sets the function called with the errors of the middleware.
+func ErrorHandler(handler func(c route.Context, err error) error) Option {
+	return func(o *Options) {
+		o.ErrorHandler = handler
+	}
+}
+
+func StatCache(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.StatCacheTTL = ttl
+	}
+}
+
+func Fingerprint(fingerprint bool) Option {
+	return func(o *Options) {
+		o.Fingerprint = fingerprint
+	}
+}
+
+func Manifest(file string) Option {
+	return func(o *Options) {
+		o.ManifestFile = file
+	}
+}
+
+// Cache keeps the contents of small files in an in-memory LRU cache of at most
+// maxBytes and maxEntries files, read again after ttl.
+func Cache(maxBytes int64, maxEntries int, ttl time.Duration) Option {
+	return func(o *Options) {
+		o.CacheMaxBytes = maxBytes
+		o.CacheMaxEntries = maxEntries
+		o.CacheTTL = ttl
+	}
+}
+
+// New returns a Static middleware.
+func New(options ...Option) route.MiddlewareFunc {
+	mw, _ := NewHandle(options...)
+	return mw
+}
+
+// NewWithError returns a Static middleware, or an error describing the
+// options if they are invalid, e.g. if Root doesn't exist.
+func NewWithError(options ...Option) (route.MiddlewareFunc, error) {
+	s, err := newHandle(options)
+	if err != nil {
+		return nil, err
+	}
+	return s.serve, nil
+}
+
+// NewHandle returns a Static middleware and the handle controlling it. It
+// panics if the options are invalid.
+func NewHandle(options ...Option) (route.MiddlewareFunc, *Handle) {
+	s, err := newHandle(options)
+	if err != nil {
+		panic(err.Error())
+	}
+	return s.serve, &Handle{s}
+}
+
+// newHandle returns the server of the options.
+func newHandle(options []Option) (*server, error) {
+	// Apply options.
+	opts := GetDefaultOptions()
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.Skipper == nil { // Options built without GetDefaultOptions.
+		opts.Skipper = route.DefaultSkipper
+	}
+	if err := validate(opts); err != nil {
+		return nil, err
+	}
+
+	s, err := newServer(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range vhostPatterns(opts.VHosts) {
+		vopts := opts
+		vopts.Root, vopts.Roots, vopts.VHosts, vopts.RootFunc = opts.VHosts[host], nil, nil, nil
+		vs, err := newServer(vopts)
+		if err != nil {
+			return nil, err
+		}
+		vs.transfers = s.transfers // Limited for all the hosts.
+		vs.throttles = s.throttles
+		s.vhosts = append(s.vhosts, vhost{host, vs})
+	}
+	if opts.RootFunc != nil || opts.TenantRoot != nil {
+		s.rootServers = newRootServers()
+	}
+	if opts.LiveReload || opts.Events != "" {
+		s.changes = newBroadcaster()
+		for _, vs := range vhostServers(s) {
+			vs.changes = s.changes
+		}
+		w, err := s.watch(s.changes.publish)
+		if err != nil {
+			return nil, fmt.Errorf("static: %v", err)
+		}
+		s.watcher = w
+	}
+	return s, nil
+}
+
+// newServer returns the server of the options.
+func newServer(opts Options) (*server, error) {
+	// Filesystem
+	roots := opts.Roots
+	if len(roots) == 0 {
+		roots = []string{opts.Root}
+	}
+	fsys, err := overlayRootFS(opts.Filesystem, roots)
+	if err != nil {
+		return nil, fmt.Errorf("static: %v", err)
+	}
+	aliases, err := newAliases(opts.Filesystem, opts.Aliases, opts.FollowSymlinks)
+	if err != nil {
+		return nil, fmt.Errorf("static: %v", err)
+	}
+	if len(aliases) > 0 {
+		fsys = aliasFS{fsys, aliases}
+	}
+
+	// Index template
+	t := opts.BrowseTemplate
+	if t == nil {
+		if t, err = template.New("index").Parse(html); err != nil {
+			return nil, fmt.Errorf("static: %v", err)
+		}
+	}
+
+	s := &server{Options: opts, fsys: asBackend(fsys), tmpl: t, aliases: aliases}
+	if h := newIOHealth(opts); h != nil {
+		s.fsys = healthFS{s.fsys, h, context.Background()}
+	}
+	if s.rewrites, err = compileRewrites(opts.Rewrite); err != nil {
+		return nil, fmt.Errorf("static: %v", err)
+	}
+	s.denylist = make([]string, len(opts.Denylist))
+	for i, pattern := range opts.Denylist {
+		s.denylist[i] = strings.ToLower(pattern)
+	}
+	s.mimeTypes = make(map[string]string, len(opts.MIMETypes))
+	for ext, ctype := range opts.MIMETypes {
+		s.mimeTypes["."+strings.TrimPrefix(strings.ToLower(ext), ".")] = ctype
+	}
+	if opts.CacheMaxBytes > 0 {
+		s.cache = newCache(opts.CacheMaxBytes, opts.CacheMaxEntries, opts.CacheTTL)
+	}
+	if opts.StatCacheTTL > 0 {
+		s.statCache = newStatCache(opts.StatCacheTTL)
+	}
+	if opts.ImageResize && opts.ImageCacheDir == "" {
+		s.images = newCache(imageCacheSize, 0, 0)
+	}
+	if opts.CaseInsensitive {
+		s.caseIndex = &caseIndex{dirs: map[string]map[string]string{}}
+	}
+	if opts.InjectVars != nil {
+		s.templates = &htmlTemplates{templates: map[string]*htmlTemplate{}}
+	}
+	if (opts.RenderMarkdown || opts.BrowseReadme) && opts.MarkdownRenderer == nil {
+		s.MarkdownRenderer = newGoldmarkRenderer()
+	}
+	if opts.MaxTransfers > 0 {
+		s.transfers = make(chan struct{}, opts.MaxTransfers)
+	}
+	if opts.ThrottleRate > 0 {
+		s.throttles = newThrottles(opts.ThrottleRate, opts.ThrottleBurst)
+	}
+	if opts.WebDAV {
+		s.davLocks = webdav.NewMemLS()
+	}
+	if opts.WebDAV && !opts.WebDAVReadOnly || opts.Upload || opts.AllowDelete || opts.AllowRename {
+		if opts.Filesystem != nil || len(roots) != 1 || archiveRoot(nil, roots[0]) || len(aliases) > 0 {
+			return nil, errors.New("static: writes require a single Root directory")
+		}
+		s.writeDir = roots[0]
+	}
+	if opts.Filesystem == nil && !opts.FollowSymlinks {
+		for _, root := range roots {
+			if !archiveRoot(nil, root) {
+				s.roots = append(s.roots, realPath(root))
+			}
+		}
+	}
+	if opts.Precompress {
+		if err := s.precompress(); err != nil {
+			return nil, fmt.Errorf("static: precompress: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Handle controls a Static middleware.
+type Handle struct {
+	s *server
+}
+
+// Invalidate removes the file at the request path p, or the content of the
+// directory at p, from the caches of every virtual host.
+func (h *Handle) Invalidate(p string) {
+	h.s.invalidate(fsPath(p))
+}
+
+// InvalidateAll empties the caches.
+func (h *Handle) InvalidateAll() {
+	h.Invalidate("/")
+}
+
+// Close stops watching the files for LiveReload and Events, ending the
+// event streams, and empties the caches. The middleware must not be used
+// afterwards.
+func (h *Handle) Close() error {
+	return h.s.close()
+}
+
+// close releases the watcher and the caches of the server.
+func (s *server) close() error {
+	s.invalidate(".")
+	if s.changes != nil {
+		s.changes.close()
+	}
+	if s.watcher != nil {
+		return s.watcher.Close()
+	}
+	return nil
+}
+
+// invalidate removes the named file, or the content of the named directory,
+// from the caches.
+func (s *server) invalidate(name string) {
+	for _, vh := range s.vhosts {
+		vh.s.invalidate(name)
+	}
+	if s.rootServers != nil {
+		s.rootServers.invalidate(name)
+	}
+	if s.cache != nil {
+		s.cache.invalidate(name)
+	}
+	if s.statCache != nil {
+		s.statCache.invalidate(name)
+	}
+	if s.images != nil {
+		s.images.invalidate(name)
+	}
+	if s.templates != nil {
+		s.templates.invalidate(name)
+	}
+	if s.caseIndex != nil {
+		s.caseIndex.invalidate(name)
+	}
+}
+
+// server is the state of a Static middleware.
+type server struct {
+	Options
+	fsys BackendFS
+	tmpl *template.Template
+
+	// Cache of file contents, if enabled.
+	cache *cache
+
+	// Cache of file metadata, if enabled.
+	statCache *statCache
+
+	// Cache of resized images, if in memory.
+	images *cache
+
+	// Parsed HTML templates, if variables are injected.
+	templates *htmlTemplates
+
+	// Names of directory entries by lower case name, if paths are
+	// case-insensitive.
+	caseIndex *caseIndex
+
+	// Compressed variants of the files, if precompressed.
+	precompressed *precompressedFiles
+
+	// Denylist patterns in lower case.
+	denylist []string
+
+	// Rewrite rules, in order.
+	rewrites []rewriteRule
+
+	// MIME types by lower case extension with a leading dot.
+	mimeTypes map[string]string
+
+	// Fingerprints of files.
+	digests digests
+
+	// Asset manifest, if any.
+	manifest assetManifest
+
+	// Semaphore of the transfers in progress, if limited.
+	transfers chan struct{}
+
+	// Token buckets of the connections, if throttled.
+	throttles *throttles
+
+	// File events of every virtual host, if live reload or events are
+	// enabled.
+	changes *broadcaster
+
+	// Watcher publishing the file events, if live reload or events are
+	// enabled.
+	watcher io.Closer
+
+	// Directory written to with WebDAV, uploads, deletes and moves, if
+	// writable.
+	writeDir string
+
+	// Locks of WebDAV requests.
+	davLocks webdav.LockSystem
+
+	// Real paths of the root directories whose symlinks must not escape
+	// them, if any.
+	roots []string
+
+	// Directories served at path prefixes, longest prefix first.
+	aliases []alias
+
+	// Servers of the virtual hosts, in order of precedence.
+	vhosts []vhost
+
+	// Servers of the roots returned by RootFunc, if set.
+	rootServers *rootServers
+}
+
+func (s *server) serve(c route.Context, next route.HandlerFunc) (err error) {
+	if s.Skipper(c) {
+		return next(c)
+	}
+	if s.RootFunc != nil || s.TenantRoot != nil {
+		rs, err := s.rootServer(c)
+		if err != nil {
+			return err
+		}
+		if rs != nil {
+			return rs.serve(c, next)
+		}
+		if s.TenantRoot != nil {
+			return next(c)
+		}
+	}
+	if vs := s.vhost(c.Request().Host); vs != nil {
+		return vs.serve(c, next)
+	}
+	if s.MaxPathLength > 0 && len(c.Request().URL.EscapedPath()) > s.MaxPathLength {
+		return route.NewHTTPError(http.StatusRequestURITooLong)
+	}
+	switch p := c.Request().URL.Path; {
+	case s.LiveReload && p == liveReloadPath:
+		return s.serveLiveReload(c)
+	case s.Events != "" && p == s.Events:
+		return s.serveEvents(c, next)
+	}
+
+	var hit bool
+	if s.Metrics != nil || s.OnServe != nil {
+		start, handler, passed := time.Now(), next, false
+		next = func(c route.Context) error {
+			err := handler(c)
+			// Not found files are still served by the middleware.
+			he, ok := err.(*route.HTTPError)
+			passed = !ok || he.Code != http.StatusNotFound
+			return err
+		}
+		defer func() {
+			if !passed {
+				s.observe(c, time.Since(start), hit, err)
+			}
+		}()
+	}
+
+	if s.ErrorHandler != nil {
+		handler, nextErr := next, error(nil)
+		next = func(c route.Context) error {
+			nextErr = handler(c)
+			return nextErr
+		}
+		defer func() {
+			if err != nil && (nextErr == nil || !errors.Is(err, nextErr)) {
+				err = s.ErrorHandler(c, err)
+			}
+		}()
+	}
+	defer func() {
+		err = s.statusError(c, err)
+	}()
+
+	if s.throttles != nil {
+		res := c.Response()
+		w, bucket := res.Writer, s.throttles.acquire(c.Request())
+		res.Writer = &throttleWriter{w, c.Request().Context(), bucket}
+		defer func() {
+			res.Writer = w
+			bucket.release()
+		}()
+	}
+
+	if s.File != "" {
+		name := fsPath(filepath.ToSlash(s.File))
+		if e := s.cachedEntry(c, name); e != nil {
+			hit = true
+			return s.serveCached(c, e)
+		}
+		return s.serveOnlyFile(c, name, next)
+	}
+
+	p := c.Request().URL.Path
+	if s.StripPrefix != "" {
+		prefix := strings.TrimSuffix(s.StripPrefix, "/")
+		if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+			return next(c)
+		}
+		p = strings.TrimPrefix(p, prefix)
+	} else if strings.HasSuffix(c.Path(), "*") { // When serving from a group, e.g. `/static*`.
+		p = c.Param("*")
+	}
+	p, err = url.PathUnescape(p)
+	if err != nil {
+		return
+	}
+	p = s.rewrite(p)
+	name, err := resolveName(p)
+	if err != nil {
+		return route.NewHTTPError(http.StatusNotFound)
+	}
+	if s.MaxPathDepth > 0 && strings.Count(name, "/") > s.MaxPathDepth {
+		return route.NewHTTPError(http.StatusNotFound)
+	}
+	if s.isDAV(c.Request()) {
+		return s.serveDAV(c, p, next)
+	}
+	if s.manages(c.Request()) {
+		return s.manage(c, name, next)
+	}
+	if r := c.Request(); r.Method == http.MethodOptions && !s.isPreflight(r) {
+		return s.options(c, name, next)
+	}
+	ctx := c.Request().Context()
+	name = s.canonical(ctx, name)
+
+	// Directory indexes are cached with a trailing slash so that requests
+	// without it are redirected.
+	key := name
+	if name != "." && strings.HasSuffix(c.Request().URL.Path, "/") {
+		key += "/"
+	}
+	if s.LanguageVariants != "" {
+		c.Response().Header().Add(route.HeaderVary, headerAcceptLanguage)
+		// Variants are cached by accepted languages, in the "directory" of
+		// the file so that they are invalidated with it.
+		key += "/@" + strings.Replace(c.Request().Header.Get(headerAcceptLanguage), "/", "", -1)
+	}
+	if s.ImageVariants && hasImageVariants(name) {
+		c.Response().Header().Add(route.HeaderVary, route.HeaderAccept)
+		key += "/@" + strings.Join(acceptedImageFormats(c.Request()), ",")
+	}
+	if e := s.cachedEntry(c, key); e != nil {
+		hit = true
+		return s.serveCached(c, e)
+	}
+
+	if len(s.TryFiles) > 0 {
+		var code int
+		if name, code = s.tryFiles(ctx, p); code == http.StatusNotFound {
+			return s.notFound(c, next)
+		} else if code != 0 {
+			return route.NewHTTPError(code)
+		}
+	}
+
+	fi, err := s.stat(ctx, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			if s.CleanURLs && path.Ext(name) == "" {
+				if fi, err := s.stat(ctx, name+".html"); err == nil && !fi.IsDir() {
+					if err := s.checkMethod(c, fi); err != nil {
+						return err
+					}
+					return s.send(c, name, name+".html", fi)
+				}
+			}
+			if s.Fingerprint {
+				if original, fi, ok := s.resolveFingerprint(ctx, name); ok {
+					if err := s.checkMethod(c, fi); err != nil {
+						return err
+					}
+					c.Response().Header().Set(headerCacheControl, fingerprintCacheControl)
+					return s.send(c, name, original, fi)
+				}
+			}
+			if file, ok := s.resolveAsset(name); ok {
+				if fi, err := s.stat(ctx, file); err == nil && !fi.IsDir() {
+					if err := s.checkMethod(c, fi); err != nil {
+						return err
+					}
+					return s.send(c, name, file, fi)
+				}
+			}
+			return s.notFound(c, next)
+		}
+		return
+	}
+	if err = s.checkMethod(c, fi); err != nil {
+		return
+	}
+
+	if urlPath := c.Request().URL.Path; fi.IsDir() != strings.HasSuffix(urlPath, "/") {
+		if fi.IsDir() && s.RedirectDirSlash {
+			return redirect(c, urlPath+"/")
+		}
+		if !fi.IsDir() && s.RedirectFileSlash && urlPath != "/" {
+			return redirect(c, strings.TrimRight(urlPath, "/"))
+		}
+	}
+	if s.CleanURLs && s.RedirectCleanURLs && !fi.IsDir() && path.Ext(name) == ".html" && !s.isIndex(path.Base(name)) {
+		if urlPath := c.Request().URL.Path; strings.HasSuffix(urlPath, ".html") {
+			if _, err := s.stat(ctx, strings.TrimSuffix(name, ".html")); errors.Is(err, fs.ErrNotExist) {
+				return redirect(c, strings.TrimSuffix(urlPath, ".html"))
+			}
+		}
+	}
+	if s.RedirectIndex && !fi.IsDir() && s.isIndex(path.Base(name)) {
+		if urlPath := c.Request().URL.Path; strings.HasSuffix(urlPath, "/"+path.Base(name)) {
+			return redirect(c, strings.TrimSuffix(urlPath, path.Base(name)))
+		}
+	}
+
+	if fi.IsDir() {
+		var index string
+		if index, fi, err = s.findIndex(ctx, name); err != nil {
+			if s.Browse {
+				var ok bool
+				if ok, err = s.browseAllowed(c); err != nil {
+					return
+				}
+				if ok {
+					if s.Upload && c.Request().Method == http.MethodPost {
+						return s.upload(c, name)
+					}
+					if format := c.QueryParam("archive"); format != "" {
+						return s.downloadDir(c, name, format)
+					}
+					if s.BrowseTree && c.QueryParam("tree") != "" {
+						return s.treeDir(c, name)
+					}
+					return s.listDir(c, name)
+				}
+				err = &fs.PathError{Op: "stat", Path: index, Err: fs.ErrNotExist}
+			}
+			if errors.Is(err, fs.ErrNotExist) {
+				return s.notFound(c, next)
+			}
+			return
+		}
+
+		return s.send(c, key, index, fi)
+	}
+
+	return s.send(c, name, name, fi)
+}
+
+// serveOnlyFile sends the named File of the server.
+func (s *server) serveOnlyFile(c route.Context, name string, next route.HandlerFunc) error {
+	fi, err := s.stat(c.Request().Context(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.notFound(c, next)
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("static: %s is a directory", s.File)
+	}
+	if err := s.checkMethod(c, fi); err != nil {
+		return err
+	}
+	return s.send(c, name, name, fi)
+}
+
+// redirect permanently redirects the request to the URL path p, keeping the
+// query string.
+func redirect(c route.Context, p string) error {
+	// Never redirect to a protocol-relative URL such as "//example.com".
+	u := url.URL{Path: "/" + strings.TrimLeft(p, "/"), RawQuery: c.Request().URL.RawQuery}
+	return c.Redirect(http.StatusMovedPermanently, u.String())
+}
+
+// isIndex reports whether the file name is the name of index files.
+func (s *server) isIndex(name string) bool {
+	if name == s.Index {
+		return true
+	}
+	for _, index := range s.Indexes {
+		if name == index {
+			return true
+		}
+	}
+	return false
+}
+
+// findIndex returns the first index file candidate found in the named
+// directory.
+func (s *server) findIndex(ctx context.Context, name string) (index string, fi fs.FileInfo, err error) {
+	for _, candidate := range append([]string{s.Index}, s.Indexes...) {
+		index = path.Join(name, candidate)
+		if fi, err = s.stat(ctx, index); !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+	}
+	return
+}
+
+// send sends the named file requested as key, caching it if enabled.
+func (s *server) send(c route.Context, key, name string, fi fs.FileInfo) error {
+	if s.isPreflight(c.Request()) {
+		return s.preflight(c)
+	}
+	if s.LanguageVariants != "" {
+		if variant, vfi, lang := s.languageVariant(c.Request(), name); lang != "" {
+			name, fi = variant, vfi
+		}
+	}
+	if s.ImageVariants && hasImageVariants(name) {
+		if variant, vfi, ok := s.imageVariant(c.Request(), name); ok {
+			name, fi = variant, vfi
+		}
+	}
+	if s.tooLarge(fi) {
+		return route.NewHTTPError(http.StatusForbidden, "file too large")
+	}
+	if s.rendersMarkdown(c, name) {
+		return s.renderMarkdown(c, name, fi)
+	}
+	if s.resizes(c, name) {
+		return s.serveResized(c, name, fi)
+	}
+	if s.rewritesHTML(c, name) {
+		return s.serveHTML(c, name, fi)
+	}
+	if c.Request().Method == http.MethodHead {
+		if ok, err := s.serveHead(c, name, fi); ok {
+			return err
+		}
+	}
+	release, err := s.acquireTransfer(c)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if s.cache != nil {
+		e, err := s.loadEntry(key, name, fi)
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			s.cache.put(e)
+			return s.serveEntry(c, e)
+		}
+	}
+	return s.serveFile(c, name)
+}
+
+// cachedEntry returns the cached file requested as key, if it can be sent as
+// is.
+func (s *server) cachedEntry(c route.Context, key string) *cacheEntry {
+	if s.cache == nil {
+		return nil
+	}
+	e := s.cache.get(key)
+	if e == nil || s.rendersMarkdown(c, e.name) || s.resizes(c, e.name) || s.rewritesHTML(c, e.name) {
+		return nil
+	}
+	return e
+}
+
+// serveCached answers the request with the cached file.
+func (s *server) serveCached(c route.Context, e *cacheEntry) error {
+	if err := s.checkMethod(c, e.fi); err != nil {
+		return err
+	}
+	if s.isPreflight(c.Request()) {
+		return s.preflight(c)
+	}
+	release, err := s.acquireTransfer(c)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return s.serveEntry(c, e)
+}
+
+// serveEntry sends the content of a cached file.
+func (s *server) serveEntry(c route.Context, e *cacheEntry) error {
+	if s.OnServe != nil {
+		c.Set(servedFileKey, "/"+e.name)
+	}
+	if s.Authorize != nil {
+		if err := s.Authorize(c, "/"+e.name, e.fi); err != nil {
+			return err
+		}
+	}
+	header := c.Response().Header()
+	content, tag := e.data, e.etag
+	if s.Compress || s.Precompressed || s.Precompress {
+		header.Add(route.HeaderVary, route.HeaderAcceptEncoding)
+		accept := c.Request().Header.Get(route.HeaderAcceptEncoding)
+		for _, pe := range precompressedEncodings {
+			if b, ok := e.encoded[pe.encoding]; ok && acceptsEncoding(accept, pe.encoding) {
+				header.Set(route.HeaderContentEncoding, pe.encoding)
+				content, tag = b, encodedETag(e.etag, pe.encoding)
+				break
+			}
+		}
+	}
+	if s.Fingerprint && e.key != e.name {
+		if original, _, ok := unfingerprinted(e.key); ok && original == e.name {
+			header.Set(headerCacheControl, fingerprintCacheControl)
+		}
+	}
+	header.Set(route.HeaderContentType, e.ctype)
+	s.setHeaders(c, e.name, tag)
+	s.serveContent(c, c.Request(), e.name, e.modTime, bytes.NewReader(content))
+	return nil
+}
+
+// notFound hands a request for a missing file to next, answering the not
+// found errors it returns with the HTML5 index or the not found file.
+func (s *server) notFound(c route.Context, next route.HandlerFunc) error {
+	err := next(c)
+	if he, ok := err.(*route.HTTPError); !ok || he.Code != http.StatusNotFound {
+		return err
+	}
+	if s.HTML5 && !s.html5Excluded(c.Request().URL.Path) {
+		name := fsPath(s.Index)
+		if s.rewritesHTML(c, name) {
+			if fi, err := s.stat(c.Request().Context(), name); err == nil {
+				return s.serveHTML(c, name, fi)
+			}
+		}
+		return s.serveFile(c, name)
+	}
+	if s.NotFoundFile != "" {
+		if b, e := fs.ReadFile(s.fsys, fsPath(s.NotFoundFile)); e == nil {
+			return c.Blob(http.StatusNotFound, s.contentType(s.NotFoundFile, bytes.NewReader(b)), b)
+		}
+	}
+	return err
+}
+
+// html5Excluded reports whether the request path is under one of the HTML5
+// exclusion prefixes.
+func (s *server) html5Excluded(p string) bool {
+	for _, prefix := range s.HTML5Exclude {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// stat returns the FileInfo of the named file. Files which must not be served
+// don't exist.
+func (s *server) stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	if s.statCache != nil {
+		return s.statCache.stat(name, func(name string) (fs.FileInfo, error) {
+			return s.statFile(ctx, name)
+		})
+	}
+	return s.statFile(ctx, name)
+}
+
+func (s *server) statFile(ctx context.Context, name string) (fs.FileInfo, error) {
+	if s.excluded(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	fi, err := s.backend(ctx).Stat(name)
+	if err == nil && (!fi.IsDir() && !s.included(name) || s.escapes(name)) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return fi, err
+}
+
+// tooLarge reports whether the file is larger than MaxFileSize.
+func (s *server) tooLarge(fi fs.FileInfo) bool {
+	return s.MaxFileSize > 0 && !fi.IsDir() && fi.Size() > s.MaxFileSize
+}
+
+// open opens the named file unless it is a symlink escaping the root.
+func (s *server) open(ctx context.Context, name string) (fs.File, error) {
+	if s.escapes(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return s.backend(ctx).Open(name)
+}
+
+// escapes reports whether the named file resolves outside of the root through
+// symlinks.
+func (s *server) escapes(name string) bool {
+	roots := s.roots
+	for _, a := range s.aliases {
+		if rel, ok := a.rel(name); ok {
+			roots, name = nil, rel
+			if a.root != "" {
+				roots = []string{a.root}
+			}
+			break
+		}
+	}
+	for _, root := range roots {
+		resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Served from the next root, if any.
+		}
+		if err != nil {
+			return true
+		}
+		rel, err := filepath.Rel(root, resolved)
+		return err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	return false
+}
+
+// realPath returns the absolute path of dir with symlinks evaluated.
+func realPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.Clean(dir)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// visible reports whether the named file or directory may be served or
+// listed.
+func (s *server) visible(name string, dir bool) bool {
+	return !s.excluded(name) && (dir || s.included(name))
+}
+
+// excluded reports whether the named file or one of its parent directories is
+// hidden or excluded.
+func (s *server) excluded(name string) bool {
+	for p := name; p != "." && p != "/"; p = path.Dir(p) {
+		// "/.well-known/" is reserved for site metadata (RFC 8615).
+		if elem := path.Base(p); s.IgnoreHidden && strings.HasPrefix(elem, ".") && elem != ".well-known" {
+			return true
+		}
+		if matchAny(s.Exclude, p) || matchAny(s.denylist, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the named file matches the include patterns, if
+// any.
+func (s *server) included(name string) bool {
+	return len(s.Include) == 0 || matchAny(s.Include, name)
+}
+
+// rootFS returns the filesystem rooted at root, or of the archive root.
+// Without a filesystem the OS filesystem is used.
+func rootFS(fsys fs.FS, root string) (fs.FS, error) {
+	if archiveRoot(fsys, root) {
+		return openArchive(fsys, root)
+	}
+	if fsys == nil {
+		return DirBackend(root), nil
+	}
+	return fs.Sub(fsys, fsPath(filepath.ToSlash(root)))
+}
+
+// overlayRootFS returns the overlay of the filesystems rooted at each of
+// roots, in order.
+func overlayRootFS(fsys fs.FS, roots []string) (fs.FS, error) {
+	if len(roots) == 1 {
+		return rootFS(fsys, roots[0])
+	}
+	o := make(overlayFS, len(roots))
+	for i, root := range roots {
+		var err error
+		if o[i], err = rootFS(fsys, root); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// fsPath converts a request path to an io/fs path, which is unrooted and
+// never contains "..".
+func fsPath(p string) string {
+	name := strings.TrimPrefix(path.Clean("/"+p), "/") // "/"+ for security
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// canonical returns the name of the file matching the named file with
+// UnicodeNormalization and CaseInsensitive.
+func (s *server) canonical(ctx context.Context, name string) string {
+	if s.UnicodeNormalization != "" {
+		name = s.normalize(ctx, name)
+	}
+	if s.CaseInsensitive {
+		name = s.resolveCase(ctx, name)
+	}
+	return name
+}
+
+// statusError returns the HTTP error answering the errors of the filesystem:
+// status 503 for transient errors and PermissionDeniedStatus when permission
+// is denied.
+func (s *server) statusError(c route.Context, err error) error {
+	if s.PermissionDeniedStatus != 0 && errors.Is(err, fs.ErrPermission) {
+		return route.NewHTTPError(s.PermissionDeniedStatus)
+	}
+	return unavailable(c, err)
+}
+
+// serveFile sends the content of the named file.
+func (s *server) serveFile(c route.Context, name string) (err error) {
+	f, err := s.open(c.Request().Context(), name)
+	if errors.Is(err, fs.ErrPermission) {
+		return
+	}
+	if err != nil {
+		return route.NotFoundHandler(c)
+	}
+	defer func() {
+		f.Close()
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+	if s.tooLarge(fi) {
+		return route.NewHTTPError(http.StatusForbidden, "file too large")
+	}
+	if s.OnServe != nil {
+		c.Set(servedFileKey, "/"+name)
+	}
+	if s.Authorize != nil {
+		if err = s.Authorize(c, "/"+name, fi); err != nil {
+			return
+		}
+	}
+
+	header := c.Response().Header()
+	if s.Precompressed {
+		header.Add(route.HeaderVary, route.HeaderAcceptEncoding)
+		if cf, cfi, encoding := s.openPrecompressed(c.Request(), name); cf != nil {
+			header.Set(route.HeaderContentType, s.contentType(name, f))
+			header.Set(route.HeaderContentEncoding, encoding)
+			f.Close()
+			f, fi = cf, cfi
+		}
+	}
+	var content io.ReadSeeker
+	tag := etag(fi)
+	if s.precompressed != nil && header.Get(route.HeaderContentEncoding) == "" {
+		if !s.Precompressed {
+			header.Add(route.HeaderVary, route.HeaderAcceptEncoding)
+		}
+		if pf, encoding := s.precompressed.open(c.Request(), name, fi); pf != nil {
+			defer pf.Close()
+			header.Set(route.HeaderContentType, s.contentType(name, f))
+			header.Set(route.HeaderContentEncoding, encoding)
+			content, tag = pf, encodedETag(tag, encoding)
+		}
+	}
+	if content == nil {
+		var ok bool
+		if content, ok = f.(io.ReadSeeker); !ok {
+			b, err := io.ReadAll(f)
+			if err != nil {
+				return err
+			}
+			content = bytes.NewReader(b)
+		}
+	}
+	if header.Get(route.HeaderContentType) == "" {
+		header.Set(route.HeaderContentType, s.contentType(name, content))
+		if _, err = content.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+	}
+
+	r := c.Request()
+	if s.Compress && header.Get(route.HeaderContentEncoding) == "" {
+		if s.compressible(header.Get(route.HeaderContentType), fi.Size()) {
+			if done := compress(c); done != nil {
+				defer done()
+				// Ranges of the compressed body can't be served.
+				r = withoutRange(r)
+				tag = encodedETag(tag, c.Response().Writer.(*compressWriter).encoding)
+			}
+		}
+	}
+
+	s.setHeaders(c, name, tag)
+	// ServeContent sets Last-Modified and answers conditional requests,
+	// checking If-None-Match before If-Modified-Since (RFC 7232, section 6).
+	s.serveContent(c, r, fi.Name(), fi.ModTime(), content)
+	return
+}
+
+// setHeaders sets the headers of the response sending the named file with
+// the entity tag.
+func (s *server) setHeaders(c route.Context, name, tag string) {
+	header := c.Response().Header()
+	s.setCORSHeaders(c)
+	if s.NoSniff {
+		header.Set(route.HeaderXContentTypeOptions, "nosniff")
+	}
+	if cc := cacheControl(s.CacheControl, name); cc != "" {
+		header.Set(headerCacheControl, cc)
+	}
+	for _, r := range s.Headers {
+		if matchAny(r.Patterns, name) {
+			for k, v := range r.Values {
+				header.Set(k, v)
+			}
+		}
+	}
+	if tag != "" {
+		header.Set(headerETag, tag)
+	}
+	if s.download(c, name) {
+		header.Set(route.HeaderContentDisposition, attachment(path.Base(name)))
+	}
+	s.setPreloadHeaders(c, name)
+}
+
+// download reports whether the named file is sent as an attachment.
+func (s *server) download(c route.Context, name string) bool {
+	if matchAny(s.ForceDownload, name) {
+		return true
+	}
+	if s.DownloadParam == "" {
+		return false
+	}
+	values, ok := c.QueryParams()[s.DownloadParam]
+	return ok && values[0] != "0" && values[0] != "false"
+}
+
+// attachment returns the Content-Disposition value of an attachment with the
+// file name, encoded as described in RFC 6266 and RFC 5987 for non-ASCII
+// names.
+func attachment(filename string) string {
+	var ascii, encoded strings.Builder
+	for _, b := range []byte(filename) {
+		switch {
+		case b >= 0x7f || b < 0x20 || b == '"' || b == '\\':
+			ascii.WriteByte('_')
+		default:
+			ascii.WriteByte(b)
+		}
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	v := `attachment; filename="` + ascii.String() + `"`
+	if ascii.String() != filename {
+		v += "; filename*=UTF-8''" + encoded.String()
+	}
+	return v
+}
+
+// isAttrChar reports whether b is an attr-char of RFC 5987.
+func isAttrChar(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' ||
+		strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+// serveContent sends content with http.ServeContent, which answers range
+// requests unless they are disabled.
+func (s *server) serveContent(c route.Context, r *http.Request, name string, modtime time.Time, content io.ReadSeeker) {
+	res := c.Response()
+	if s.DisableRange {
+		r = withoutRange(r)
+		res.Before(func() {
+			res.Header().Set(headerAcceptRanges, "none")
+		})
+	} else if s.MaxRanges > 0 && countRanges(r.Header.Get(headerRange)) > s.MaxRanges {
+		r = withoutRange(r)
+	}
+	http.ServeContent(res, r, name, modtime, content)
+}
+
+// countRanges returns the number of ranges of the Range header value.
+func countRanges(v string) int {
+	if v == "" {
+		return 0
+	}
+	return strings.Count(v, ",") + 1
+}
+
+// withoutRange returns r without its Range header.
+func withoutRange(r *http.Request) *http.Request {
+	if r.Header.Get(headerRange) == "" {
+		return r
+	}
+	r = r.Clone(r.Context())
+	r.Header.Del(headerRange)
+	return r
+}
+
+// precompressedEncodings are the sidecar encodings in order of preference.
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// openPrecompressed opens the preferred sidecar of the named file accepted by
+// the client. It returns a nil file if there is none.
+func (s *server) openPrecompressed(r *http.Request, name string) (fs.File, fs.FileInfo, string) {
+	accept := r.Header.Get(route.HeaderAcceptEncoding)
+	for _, pe := range precompressedEncodings {
+		if !acceptsEncoding(accept, pe.encoding) {
+			continue
+		}
+		f, err := s.open(r.Context(), name+pe.ext)
+		if err != nil {
+			continue
+		}
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			return f, fi, pe.encoding
+		}
+		f.Close()
+	}
+	return nil, nil, ""
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value accepts
+// encoding with a non-zero quality.
+func acceptsEncoding(accept, encoding string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		if coding := strings.TrimSpace(params[0]); coding != encoding && coding != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		return q > 0
+	}
+	return false
+}
+
+// contentType returns the MIME type of the named file from its extension,
+// falling back to sniffing its content unless disabled.
+func (s *server) contentType(name string, f io.Reader) string {
+	ctype := s.mimeType(name)
+	if ctype == "" {
+		if s.NoSniff {
+			return route.MIMEOctetStream
+		}
+		var buf [512]byte
+		n, _ := io.ReadFull(f, buf[:])
+		ctype = http.DetectContentType(buf[:n])
+	}
+	if s.DefaultCharset != "" && strings.HasPrefix(ctype, "text/") && !strings.Contains(ctype, "charset=") {
+		ctype += "; charset=" + s.DefaultCharset
+	}
+	return ctype
+}
+
+// mimeType returns the MIME type of the named file from its extension, or "".
+func (s *server) mimeType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ctype, ok := s.mimeTypes[ext]; ok {
+		return ctype
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// etag returns the entity tag of a file provided by its FileInfo or, by
+// default, a weak one derived from its size and modification time. It
+// returns "" if the modification time is unknown.
+func etag(fi fs.FileInfo) string {
+	if e, ok := fi.(interface{ ETag() string }); ok && e.ETag() != "" {
+		return e.ETag()
+	}
+	if fi.ModTime().IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(`W/"%x-%x"`, fi.Size(), fi.ModTime().UnixNano())
+}
+
+// encodedETag returns the entity tag of the variant of a file in the content
+// encoding, which differs from the tag of the file as its content does.
+func encodedETag(tag, encoding string) string {
+	if tag == "" {
+		return ""
+	}
+	return strings.TrimSuffix(tag, `"`) + "-" + encoding + `"`
+}
+
+// cacheControl returns the Cache-Control value of the first rule matching
+// name.
+func cacheControl(rules []CacheRule, name string) string {
+	for _, r := range rules {
+		if matchAny(r.Patterns, name) {
+			return r.Value
+		}
+	}
+	return ""
+}
+
+// match reports whether the file name matches the doublestar glob pattern.
+// Patterns without a slash are matched against the base name, e.g.
+// "*.{js,css}", others against the whole path, e.g. "/assets/**".
+func match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	} else {
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	ok, _ := doublestar.Match(pattern, name)
+	return ok
+}
+
+// matchAny reports whether the file name matches any of the patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, name) {
+			return true
+		}
+	}
+	return false
 }
@@ -1,14 +1,53 @@
 package static
 
 import (
+	"compress/gzip"
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/goroute/route"
 	"github.com/stretchr/testify/assert"
 )
 
+//go:embed testdata
+var testdata embed.FS
+
+// serve sends a request to the middleware, with the header fields given as
+// name-value pairs, and returns the response. The status of an HTTP error
+// returned by the middleware is recorded as the response's; other errors fail
+// the test.
+func serve(t *testing.T, mw route.MiddlewareFunc, method, target string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	if err := mw(mux.NewContext(req, rec), route.NotFoundHandler); err != nil {
+		if he, ok := err.(*route.HTTPError); ok {
+			rec.Code = he.Code
+		} else {
+			t.Error(err)
+		}
+	}
+	return rec
+}
+
 func TestStatic(t *testing.T) {
 	mux := route.NewServeMux()
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -89,3 +128,953 @@ func TestStaticBrowse(t *testing.T) {
 		assert.Contains(rec.Body.String(), "Hello")
 	}
 }
+
+func TestStaticFilesystem(t *testing.T) {
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/hello.txt", nil)
+	rec := httptest.NewRecorder()
+	c := mux.NewContext(req, rec)
+
+	mw := New(
+		Root("public"),
+		Filesystem(fstest.MapFS{
+			"public/hello.txt": {Data: []byte("Hello, fs")},
+		}),
+	)
+
+	assert := assert.New(t)
+	if assert.NoError(mw(c, route.NotFoundHandler)) {
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("Hello, fs", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/../public/none", nil)
+	rec = httptest.NewRecorder()
+	c = mux.NewContext(req, rec)
+	he := mw(c, route.NotFoundHandler).(*route.HTTPError)
+	assert.Equal(http.StatusNotFound, he.Code)
+}
+
+func TestStaticEmbed(t *testing.T) {
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/random", nil)
+	rec := httptest.NewRecorder()
+	c := mux.NewContext(req, rec)
+
+	mw := New(Embed(testdata, "testdata"), HTML5(true), Browse(true))
+
+	assert := assert.New(t)
+	if assert.NoError(mw(c, route.NotFoundHandler)) {
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Contains(rec.Body.String(), "Route")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/browse/", nil)
+	rec = httptest.NewRecorder()
+	c = mux.NewContext(req, rec)
+	if assert.NoError(mw(c, route.NotFoundHandler)) {
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Contains(rec.Body.String(), "file1.txt")
+		assert.Contains(rec.Body.String(), "/browse")
+	}
+}
+
+func TestStaticCacheControl(t *testing.T) {
+	mw := New(
+		Root("testdata"),
+		CacheControl(
+			CacheRule{Patterns: []string{"/images/*"}, Value: "public, max-age=31536000, immutable"},
+			CacheRule{Patterns: []string{"*.html", "*.htm"}, Value: "no-cache"},
+		),
+	)
+
+	assert := assert.New(t)
+	for path, want := range map[string]string{
+		"/images/walle.png": "public, max-age=31536000, immutable",
+		"/":                 "no-cache",
+		"/browse/file1.txt": "",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		c := mux.NewContext(req, rec)
+		if assert.NoError(mw(c, route.NotFoundHandler)) {
+			assert.Equal(want, rec.Header().Get("Cache-Control"), path)
+		}
+	}
+}
+
+func TestStaticConditional(t *testing.T) {
+	mw := New(Root("testdata"))
+	const path = "/browse/file1.txt"
+
+	assert := assert.New(t)
+	rec := serve(t, mw, http.MethodGet, path)
+	assert.Equal(http.StatusOK, rec.Code)
+	lastModified := rec.Header().Get(route.HeaderLastModified)
+	tag := rec.Header().Get("ETag")
+	assert.NotEmpty(lastModified)
+	assert.NotEmpty(tag)
+
+	assert.Equal(http.StatusNotModified, serve(t, mw, http.MethodGet, path, route.HeaderIfModifiedSince, lastModified).Code)
+	assert.Equal(http.StatusNotModified, serve(t, mw, http.MethodGet, path, "If-None-Match", tag).Code)
+	// If-None-Match takes precedence over If-Modified-Since.
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, path, "If-None-Match", `W/"other"`, route.HeaderIfModifiedSince, lastModified).Code)
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	mw := New(
+		Root("testdata/precompressed"),
+		Precompressed(true),
+	)
+
+	assert := assert.New(t)
+	for encoding, want := range map[string]string{
+		"gzip, deflate, br": "gzip",
+		"gzip;q=0":          "",
+		"":                  "",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+		req.Header.Set(route.HeaderAcceptEncoding, encoding)
+		rec := httptest.NewRecorder()
+		c := mux.NewContext(req, rec)
+		if assert.NoError(mw(c, route.NotFoundHandler)) {
+			assert.Equal(want, rec.Header().Get(route.HeaderContentEncoding), encoding)
+			assert.Contains(rec.Header().Get(route.HeaderContentType), "javascript")
+			assert.Equal(route.HeaderAcceptEncoding, rec.Header().Get(route.HeaderVary))
+		}
+	}
+
+	mw = New(
+		Filesystem(fstest.MapFS{
+			"app.css":    {Data: []byte("body{}")},
+			"app.css.br": {Data: []byte("br")},
+			"app.css.gz": {Data: []byte("gz")},
+		}),
+		Precompressed(true),
+	)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/app.css", nil)
+	req.Header.Set(route.HeaderAcceptEncoding, "gzip, br")
+	rec := httptest.NewRecorder()
+	c := mux.NewContext(req, rec)
+	if assert.NoError(mw(c, route.NotFoundHandler)) {
+		assert.Equal("br", rec.Header().Get(route.HeaderContentEncoding))
+		assert.Equal("br", rec.Body.String())
+	}
+}
+
+func TestStaticCompress(t *testing.T) {
+	text := strings.Repeat("Hello, Route! ", 200)
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"large.txt": {Data: []byte(text)},
+			"small.txt": {Data: []byte("Hello")},
+			"large.png": {Data: []byte(text)},
+		}),
+		Compress(true),
+	)
+	assert := assert.New(t)
+	rec := serve(t, mw, http.MethodGet, "/large.txt", route.HeaderAcceptEncoding, "gzip")
+	if assert.Equal("gzip", rec.Header().Get(route.HeaderContentEncoding)) {
+		assert.Empty(rec.Header().Get(route.HeaderContentLength))
+		assert.Contains(rec.Header().Get(route.HeaderContentType), "text/plain")
+		r, err := gzip.NewReader(rec.Body)
+		if assert.NoError(err) {
+			b, _ := io.ReadAll(r)
+			assert.Equal(text, string(b))
+		}
+	}
+	rec = serve(t, mw, http.MethodGet, "/large.txt", route.HeaderAcceptEncoding, "gzip, br")
+	if assert.Equal("br", rec.Header().Get(route.HeaderContentEncoding)) {
+		b, _ := io.ReadAll(brotli.NewReader(rec.Body))
+		assert.Equal(text, string(b))
+	}
+	for path, encoding := range map[string]string{
+		"/large.txt": "",
+		"/small.txt": "gzip",
+		"/large.png": "gzip",
+	} {
+		rec = serve(t, mw, http.MethodGet, path, route.HeaderAcceptEncoding, encoding)
+		assert.Equal(http.StatusOK, rec.Code, path)
+		assert.Empty(rec.Header().Get(route.HeaderContentEncoding), path)
+	}
+}
+
+func TestStaticRange(t *testing.T) {
+	assert := assert.New(t)
+	mw := New(Root("testdata"), Browse(true))
+	for _, path := range []string{"/browse/file1.txt", "/browse/"} {
+		rec := serve(t, mw, http.MethodGet, path, "Range", "bytes=0-1")
+		assert.Equal(http.StatusPartialContent, rec.Code, path)
+		assert.Equal(2, rec.Body.Len(), path)
+	}
+
+	mw = New(Root("testdata"), Browse(true), DisableRange(true))
+	for _, path := range []string{"/browse/file1.txt", "/browse/"} {
+		rec := serve(t, mw, http.MethodGet, path, "Range", "bytes=0-1")
+		assert.Equal(http.StatusOK, rec.Code, path)
+		assert.Equal("none", rec.Header().Get("Accept-Ranges"), path)
+	}
+}
+
+func TestStaticMultipleRanges(t *testing.T) {
+	fsys := fstest.MapFS{
+		"file.txt": {Data: []byte("0123456789")},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), MaxRanges(2)),
+		New(Filesystem(fsys), MaxRanges(2), Cache(1<<20, 0, 0)),
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodGet, http.MethodHead} {
+			rec := serve(t, mw, method, "/file.txt", "Range", "bytes=0-1,5-6")
+			assert.Equal(http.StatusPartialContent, rec.Code)
+			ctype := rec.Header().Get(route.HeaderContentType)
+			if assert.True(strings.HasPrefix(ctype, "multipart/byteranges; boundary="), ctype) && method == http.MethodGet {
+				r := multipart.NewReader(rec.Body, strings.TrimPrefix(ctype, "multipart/byteranges; boundary="))
+				for _, want := range []string{"01", "56"} {
+					part, err := r.NextPart()
+					if assert.NoError(err) {
+						b, _ := io.ReadAll(part)
+						assert.Equal(want, string(b))
+						assert.Equal("text/plain; charset=utf-8", part.Header.Get(route.HeaderContentType))
+					}
+				}
+			}
+		}
+
+		// Requests for more ranges get the whole content.
+		rec := serve(t, mw, http.MethodGet, "/file.txt", "Range", "bytes=0-0,2-2,4-4")
+		assert.Equal(http.StatusOK, rec.Code)
+		assert.Equal("0123456789", rec.Body.String())
+	}
+}
+
+func TestStaticIgnoreHidden(t *testing.T) {
+	fsys := fstest.MapFS{
+		".env":                     {Data: []byte("SECRET=1")},
+		".git/config":              {Data: []byte("[core]")},
+		".well-known/security.txt": {Data: []byte("Contact: security@example.com")},
+		"file.txt":                 {Data: []byte("Hello")},
+	}
+	assert := assert.New(t)
+	mw := New(Filesystem(fsys), Browse(true))
+	for path, want := range map[string]int{
+		"/.env":                     http.StatusNotFound,
+		"/.git/config":              http.StatusNotFound,
+		"/.git/":                    http.StatusNotFound,
+		"/.well-known/security.txt": http.StatusOK,
+		"/file.txt":                 http.StatusOK,
+	} {
+		assert.Equal(want, serve(t, mw, http.MethodGet, path).Code, path)
+	}
+	body := serve(t, mw, http.MethodGet, "/").Body.String()
+	assert.NotContains(body, ".env")
+	assert.NotContains(body, ".git")
+
+	mw = New(Filesystem(fsys), Browse(true), IgnoreHidden(false), Denylist())
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/.env").Code)
+	assert.Contains(serve(t, mw, http.MethodGet, "/").Body.String(), ".env")
+}
+
+func TestStaticDenylist(t *testing.T) {
+	fsys := fstest.MapFS{
+		".env.local":       {Data: []byte("SECRET=1")},
+		"certs/server.pem": {Data: []byte("cert")},
+		"data/app.sqlite":  {Data: []byte("db")},
+		"index.html~":      {Data: []byte("backup")},
+		"config.bak":       {Data: []byte("backup")},
+		".git/HEAD":        {Data: []byte("ref")},
+		"lib/CVS/Entries":  {Data: []byte("cvs")},
+		"index.html":       {Data: []byte("index")},
+	}
+	assert := assert.New(t)
+	mw := New(Filesystem(fsys), Browse(true), IgnoreHidden(false))
+	for path := range fsys {
+		want := http.StatusNotFound
+		if path == "index.html" {
+			want = http.StatusOK
+		}
+		assert.Equal(want, serve(t, mw, http.MethodGet, "/"+path).Code, path)
+	}
+	assert.NotContains(serve(t, mw, http.MethodGet, "/certs/").Body.String(), "server.pem")
+
+	mw = New(Filesystem(fsys), IgnoreHidden(false), Denylist("*.bak"))
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/config.bak").Code)
+	assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/certs/server.pem").Code)
+
+	// Case-insensitive filesystems serve names in any case.
+	fsys = fstest.MapFS{
+		"KEY.PEM":         {Data: []byte("key")},
+		"db.SQLITE":       {Data: []byte("db")},
+		"backup.BAK":      {Data: []byte("backup")},
+		"lib/Cvs/Entries": {Data: []byte("cvs")},
+	}
+	mw = New(Filesystem(fsys))
+	for path := range fsys {
+		assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/"+path).Code, path)
+	}
+}
+
+func TestStaticExcludeInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":             {Data: []byte("app")},
+		"app.js.map":         {Data: []byte("map")},
+		"lib/vendor.js.map":  {Data: []byte("map")},
+		"secrets/key.txt":    {Data: []byte("key")},
+		"a/secrets/key.txt":  {Data: []byte("key")},
+		"private/readme.txt": {Data: []byte("private")},
+		"index.html":         {Data: []byte("index")},
+	}
+	assert := assert.New(t)
+	mw := New(
+		Filesystem(fsys),
+		Browse(true),
+		Index("none.html"),
+		Exclude("**/*.map", "**/secrets/**", "private"),
+	)
+	for path, want := range map[string]int{
+		"/app.js":             http.StatusOK,
+		"/app.js.map":         http.StatusNotFound,
+		"/lib/vendor.js.map":  http.StatusNotFound,
+		"/secrets/key.txt":    http.StatusNotFound,
+		"/a/secrets/key.txt":  http.StatusNotFound,
+		"/private/readme.txt": http.StatusNotFound,
+		"/private/":           http.StatusNotFound,
+		"/lib/":               http.StatusOK,
+	} {
+		assert.Equal(want, serve(t, mw, http.MethodGet, path).Code, path)
+	}
+	body := serve(t, mw, http.MethodGet, "/").Body.String()
+	assert.NotContains(body, ".map")
+	assert.NotContains(body, "secrets")
+	assert.NotContains(body, "private")
+
+	mw = New(Filesystem(fsys), Browse(true), Index("none.html"), Include("*.{js,html}"))
+	for path, want := range map[string]int{
+		"/app.js":             http.StatusOK,
+		"/index.html":         http.StatusOK,
+		"/app.js.map":         http.StatusNotFound,
+		"/private/readme.txt": http.StatusNotFound,
+	} {
+		assert.Equal(want, serve(t, mw, http.MethodGet, path).Code, path)
+	}
+	body = serve(t, mw, http.MethodGet, "/").Body.String()
+	assert.Contains(body, "private/")
+	assert.NotContains(body, ".map")
+}
+
+func TestStaticSymlinks(t *testing.T) {
+	outside, root := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "file.txt"), []byte("Hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"secret.txt": filepath.Join(outside, "secret.txt"),
+		"outside":    outside,
+		"inside.txt": filepath.Join(root, "file.txt"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Skip("symlinks not supported:", err)
+		}
+	}
+	assert := assert.New(t)
+	mw := New(Root(root), Browse(true))
+	for path, want := range map[string]int{
+		"/secret.txt":         http.StatusNotFound,
+		"/outside/secret.txt": http.StatusNotFound,
+		"/outside/":           http.StatusNotFound,
+		"/inside.txt":         http.StatusOK,
+	} {
+		assert.Equal(want, serve(t, mw, http.MethodGet, path).Code, path)
+	}
+	body := serve(t, mw, http.MethodGet, "/").Body.String()
+	assert.NotContains(body, "secret.txt")
+	assert.Contains(body, "inside.txt")
+
+	mw = New(Root(root), FollowSymlinks(true))
+	rec := serve(t, mw, http.MethodGet, "/outside/secret.txt")
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("secret", rec.Body.String())
+}
+
+func TestStaticHTML5Exclude(t *testing.T) {
+	mw := New(Root("testdata"), HTML5(true), HTML5Exclude("/api/"))
+
+	assert := assert.New(t)
+	for path, want := range map[string]int{
+		"/api":       http.StatusNotFound,
+		"/api/users": http.StatusNotFound,
+		"/apiary":    http.StatusOK,
+		"/users":     http.StatusOK,
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+		if want == http.StatusNotFound {
+			if assert.Error(err, path) {
+				assert.Equal(want, err.(*route.HTTPError).Code, path)
+			}
+		} else if assert.NoError(err, path) {
+			assert.Contains(rec.Body.String(), "Route", path)
+		}
+	}
+}
+
+func TestStaticRoots(t *testing.T) {
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"override/theme.css": {Data: []byte("override")},
+			"dist/theme.css":     {Data: []byte("dist")},
+			"dist/app.js":        {Data: []byte("app")},
+		}),
+		Roots("override", "dist"),
+		Browse(true),
+	)
+	assert := assert.New(t)
+	assert.Equal("override", serve(t, mw, http.MethodGet, "/theme.css").Body.String())
+	assert.Equal("app", serve(t, mw, http.MethodGet, "/app.js").Body.String())
+	body := serve(t, mw, http.MethodGet, "/?format=json").Body.String()
+	assert.Equal(1, strings.Count(body, `"theme.css"`))
+	assert.Contains(body, `"app.js"`)
+}
+
+func TestStaticNotFoundFile(t *testing.T) {
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"404.html":   {Data: []byte("<h1>Not Found</h1>")},
+			"index.html": {Data: []byte("index")},
+		}),
+		NotFoundFile("404.html"),
+	)
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/none", nil)
+	rec := httptest.NewRecorder()
+
+	assert := assert.New(t)
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusNotFound, rec.Code)
+		assert.Equal("<h1>Not Found</h1>", rec.Body.String())
+		assert.Contains(rec.Header().Get(route.HeaderContentType), "text/html")
+	}
+
+	// Routes handled by next are left alone.
+	rec = httptest.NewRecorder()
+	c := mux.NewContext(req, rec)
+	assert.NoError(mw(c, func(c route.Context) error {
+		return c.String(http.StatusOK, "route")
+	}))
+	assert.Equal("route", rec.Body.String())
+}
+
+func TestStaticAuthorize(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users/alice/a.txt": {Data: []byte("alice")},
+		"users/bob/b.txt":   {Data: []byte("bob")},
+	}
+	authorize := func(c route.Context, path string, fi os.FileInfo) error {
+		user := c.Request().Header.Get("X-User")
+		if !strings.HasPrefix(path, "/users/"+user+"/") {
+			return route.ErrForbidden
+		}
+		return nil
+	}
+
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), Authorize(authorize)),
+		New(Filesystem(fsys), Authorize(authorize), Cache(1<<20, 0, 0)),
+	} {
+		for i := 0; i < 2; i++ {
+			for path, want := range map[string]error{
+				"/users/alice/a.txt": nil,
+				"/users/bob/b.txt":   route.ErrForbidden,
+			} {
+				mux := route.NewServeMux()
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("X-User", "alice")
+				rec := httptest.NewRecorder()
+				assert.Equal(want, mw(mux.NewContext(req, rec), route.NotFoundHandler), path)
+			}
+		}
+	}
+}
+
+func TestStaticDownload(t *testing.T) {
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"report.pdf": {Data: []byte("pdf")},
+			"résumé.txt": {Data: []byte("txt")},
+		}),
+		ForceDownload("*.pdf"),
+	)
+	disposition := func(path string) string {
+		return serve(t, mw, http.MethodGet, path).Header().Get(route.HeaderContentDisposition)
+	}
+
+	assert := assert.New(t)
+	assert.Equal(`attachment; filename="report.pdf"`, disposition("/report.pdf"))
+	assert.Equal("", disposition("/r%C3%A9sum%C3%A9.txt"))
+	assert.Equal("", disposition("/r%C3%A9sum%C3%A9.txt?download=0"))
+	assert.Equal(`attachment; filename="r__sum__.txt"; filename*=UTF-8''r%C3%A9sum%C3%A9.txt`,
+		disposition("/r%C3%A9sum%C3%A9.txt?download=1"))
+}
+
+func TestStaticIndexCandidates(t *testing.T) {
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"a/index.htm":    {Data: []byte("a")},
+			"b/default.html": {Data: []byte("b")},
+			"b/index.htm":    {Data: []byte("not b")},
+			"c/index.html":   {Data: []byte("c")},
+			"c/index.htm":    {Data: []byte("not c")},
+		}),
+		Index("index.html", "default.html", "index.htm"),
+	)
+
+	assert := assert.New(t)
+	for path, want := range map[string]string{"/a/": "a", "/b/": "b", "/c/": "c"} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), path) {
+			assert.Equal(want, rec.Body.String(), path)
+		}
+	}
+}
+
+func TestStaticRedirectSlash(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dir/index.html": {Data: []byte("index")},
+		"file.txt":       {Data: []byte("file")},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys)),
+		New(Filesystem(fsys), Cache(1<<20, 0, 0)),
+	} {
+		assert.Equal("index", serve(t, mw, http.MethodGet, "/dir/").Body.String())
+		rec := serve(t, mw, http.MethodGet, "/dir?a=1")
+		assert.Equal(http.StatusMovedPermanently, rec.Code)
+		assert.Equal("/dir/?a=1", rec.Header().Get(route.HeaderLocation))
+		assert.Equal("file", serve(t, mw, http.MethodGet, "/file.txt/").Body.String())
+	}
+
+	rec := serve(t, New(Filesystem(fsys), RedirectDirSlash(false)), http.MethodGet, "/dir")
+	assert.Equal("index", rec.Body.String())
+
+	rec = serve(t, New(Filesystem(fsys), RedirectFileSlash(true)), http.MethodGet, "/file.txt/?a=1")
+	assert.Equal(http.StatusMovedPermanently, rec.Code)
+	assert.Equal("/file.txt?a=1", rec.Header().Get(route.HeaderLocation))
+
+	rec = serve(t, New(Filesystem(fsys)), http.MethodGet, "//dir")
+	assert.Equal("/dir/", rec.Header().Get(route.HeaderLocation))
+}
+
+func TestStaticRedirectIndex(t *testing.T) {
+	mw := New(
+		Filesystem(fstest.MapFS{
+			"index.html":     {Data: []byte("root")},
+			"dir/index.html": {Data: []byte("index")},
+		}),
+		RedirectIndex(true),
+	)
+
+	assert := assert.New(t)
+	for path, want := range map[string]string{
+		"/index.html":       "/",
+		"/dir/index.html?a": "/dir/?a",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal(http.StatusMovedPermanently, rec.Code, path)
+			assert.Equal(want, rec.Header().Get(route.HeaderLocation), path)
+		}
+	}
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/dir/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("index", rec.Body.String())
+	}
+}
+
+func TestStaticHeaders(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.worker.js": {Data: []byte("self.onmessage = null")},
+		"app.js":        {Data: []byte("main()")},
+	}
+	mw := New(Filesystem(fsys), Headers(
+		HeaderRule{Patterns: []string{"*.js"}, Values: map[string]string{"X-Frame-Options": "DENY", "Service-Worker-Allowed": "/"}},
+		HeaderRule{Patterns: []string{"*.worker.js"}, Values: map[string]string{"Cross-Origin-Embedder-Policy": "require-corp", "Service-Worker-Allowed": "/app/"}},
+	))
+
+	assert := assert.New(t)
+	for path, want := range map[string]map[string]string{
+		"/app.worker.js": {"X-Frame-Options": "DENY", "Service-Worker-Allowed": "/app/", "Cross-Origin-Embedder-Policy": "require-corp"},
+		"/app.js":        {"X-Frame-Options": "DENY", "Service-Worker-Allowed": "/", "Cross-Origin-Embedder-Policy": ""},
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			for k, v := range want {
+				assert.Equal(v, rec.Header().Get(k), path+" "+k)
+			}
+		}
+	}
+}
+
+func TestStaticNoSniff(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page":      {Data: []byte("<html><body>hi</body></html>")},
+		"notes.txt": {Data: []byte("hello")},
+		"app.mjs":   {Data: []byte("export {}")},
+	}
+	types := map[string]string{".mjs": "text/javascript"}
+
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), MIMETypes(types), NoSniff(true), DefaultCharset("utf-8")),
+		New(Filesystem(fsys), MIMETypes(types), NoSniff(true), DefaultCharset("utf-8"), Cache(1<<20, 0, 0)),
+	} {
+		for path, want := range map[string]string{
+			"/page":      route.MIMEOctetStream,
+			"/notes.txt": "text/plain; charset=utf-8",
+			"/app.mjs":   "text/javascript; charset=utf-8",
+		} {
+			mux := route.NewServeMux()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			rec := httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+				assert.Equal(want, rec.Header().Get(route.HeaderContentType), path)
+				assert.Equal("nosniff", rec.Header().Get(route.HeaderXContentTypeOptions), path)
+			}
+		}
+	}
+
+	// Sniffed by default
+	mw := New(Filesystem(fsys))
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/page", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("text/html; charset=utf-8", rec.Header().Get(route.HeaderContentType))
+		assert.Empty(rec.Header().Get(route.HeaderXContentTypeOptions))
+	}
+}
+
+func TestStaticMIMETypes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.mjs":     {Data: []byte("export {}")},
+		"app.mjs.gz":  {Data: []byte("gz")},
+		"data.custom": {Data: []byte("{}")},
+	}
+	types := map[string]string{"mjs": "text/javascript", ".CUSTOM": "application/x-custom"}
+
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), MIMETypes(types)),
+		New(Filesystem(fsys), MIMETypes(types), Cache(1<<20, 0, 0)),
+		New(Filesystem(fsys), MIMETypes(types), Precompressed(true)),
+	} {
+		for path, want := range map[string]string{
+			"/app.mjs":     "text/javascript",
+			"/data.custom": "application/x-custom",
+		} {
+			mux := route.NewServeMux()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(route.HeaderAcceptEncoding, "gzip")
+			rec := httptest.NewRecorder()
+			if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+				assert.Equal(want, rec.Header().Get(route.HeaderContentType), path)
+			}
+		}
+	}
+}
+
+func TestStaticStripPrefix(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":       {Data: []byte("app")},
+		"img/logo.svg": {Data: []byte("<svg/>")},
+	}
+	mw := New(Filesystem(fsys), StripPrefix("/assets/"))
+	assert := assert.New(t)
+
+	for target, want := range map[string]string{
+		"/assets/app.js":       "app",
+		"/assets/img/logo.svg": "<svg/>",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal(want, rec.Body.String(), target)
+		}
+	}
+
+	// Outside of the prefix
+	for _, target := range []string{"/app.js", "/assetsapp.js"} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		assert.Equal(route.ErrNotFound, mw(mux.NewContext(req, rec), route.NotFoundHandler), target)
+	}
+
+	// Directory redirect keeps the prefix.
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/assets/img", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusMovedPermanently, rec.Code)
+		assert.Equal("/assets/img/", rec.Header().Get(route.HeaderLocation))
+	}
+}
+
+func TestStaticAliases(t *testing.T) {
+	root, media := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(root, "index.html"), []byte("index"), 0o644)
+	os.MkdirAll(filepath.Join(root, "media"), 0o755)
+	os.WriteFile(filepath.Join(root, "media", "shadowed.txt"), []byte("shadowed"), 0o644)
+	os.WriteFile(filepath.Join(media, "a.txt"), []byte("media"), 0o644)
+	os.MkdirAll(filepath.Join(media, "sub"), 0o755)
+	os.WriteFile(filepath.Join(media, "sub", "b.txt"), []byte("sub"), 0o644)
+	os.Symlink(filepath.Join(root, "index.html"), filepath.Join(media, "escape.html"))
+	mw := New(Root(root), Alias("/media", media), Alias("/media/sub/", filepath.Join(media, "sub")))
+	assert := assert.New(t)
+
+	for target, want := range map[string]string{
+		"/":                "index",
+		"/media/a.txt":     "media",
+		"/media/sub/b.txt": "sub",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			assert.Equal(want, rec.Body.String(), target)
+		}
+	}
+	for _, target := range []string{"/media/shadowed.txt", "/media/escape.html"} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		assert.Equal(route.ErrNotFound, mw(mux.NewContext(req, rec), route.NotFoundHandler), target)
+	}
+}
+
+func TestStaticCleanURLs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"about.html":      {Data: []byte("about")},
+		"docs.html":       {Data: []byte("docs page")},
+		"docs/index.html": {Data: []byte("docs")},
+		"app.js":          {Data: []byte("app")},
+	}
+	assert := assert.New(t)
+
+	mw := New(Filesystem(fsys), CleanURLs(true))
+	for target, want := range map[string]string{
+		"/about":      "about",
+		"/about.html": "about",
+		"/docs/":      "docs",
+		"/app.js":     "app",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			assert.Equal(want, rec.Body.String(), target)
+		}
+	}
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	rec := httptest.NewRecorder()
+	assert.Equal(route.ErrNotFound, mw(mux.NewContext(req, rec), route.NotFoundHandler))
+
+	// Redirects
+	mw = New(Filesystem(fsys), CleanURLs(true), RedirectCleanURLs(true))
+	for target, want := range map[string]string{
+		"/about.html?a=1":  "/about?a=1",
+		"/docs.html":       "", // "/docs" is a directory.
+		"/docs/index.html": "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			assert.Equal(want, rec.Header().Get(route.HeaderLocation), target)
+		}
+	}
+}
+
+func TestStaticPathLimits(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/file.txt": {Data: []byte("Hello")},
+	}
+	assert := assert.New(t)
+	mw := New(Filesystem(fsys), MaxPathLength(20), MaxPathDepth(2))
+	for path, want := range map[string]int{
+		"/a/b/file.txt":         http.StatusOK,
+		"/a/b/c/file.txt":       http.StatusNotFound,
+		"/a/b/../b/file.txt":    http.StatusOK,
+		"/a/b/%66%69%6C%65.txt": http.StatusRequestURITooLong,
+	} {
+		assert.Equal(want, serve(t, mw, http.MethodGet, path).Code, path)
+	}
+	mw = New(Filesystem(fsys))
+	assert.Equal(http.StatusRequestURITooLong, serve(t, mw, http.MethodGet, "/"+strings.Repeat("a", 4096)).Code)
+	assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, strings.Repeat("/a", 65)).Code)
+}
+
+func TestStaticMaxFileSize(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("<p>Hi</p>")},
+		"large.bin":  {Data: make([]byte, 1025)},
+		"small.bin":  {Data: make([]byte, 1024)},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), MaxFileSize(1024)),
+		New(Filesystem(fsys), MaxFileSize(1024), Cache(1<<20, 0, 0)),
+	} {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			assert.Equal(http.StatusForbidden, serve(t, mw, method, "/large.bin").Code, method)
+			assert.Equal(http.StatusOK, serve(t, mw, method, "/small.bin").Code, method)
+			assert.Equal(http.StatusOK, serve(t, mw, method, "/").Code, method)
+		}
+	}
+	assert.Equal(http.StatusOK, serve(t, New(Filesystem(fsys)), http.MethodGet, "/large.bin").Code)
+}
+
+func TestStaticFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/favicon.ico": {Data: []byte("icon"), ModTime: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"assets/secret.txt":  {Data: []byte("secret")},
+	}
+	assert := assert.New(t)
+	for _, mw := range []route.MiddlewareFunc{
+		New(Filesystem(fsys), File("assets/favicon.ico")),
+		New(Filesystem(fsys), File("assets/favicon.ico"), Cache(1<<20, 0, 0)),
+	} {
+		for _, path := range []string{"/favicon.ico", "/assets/secret.txt", "/"} {
+			rec := serve(t, mw, http.MethodGet, path)
+			assert.Equal(http.StatusOK, rec.Code, path)
+			assert.Equal("icon", rec.Body.String(), path)
+			assert.Equal("image/vnd.microsoft.icon", rec.Header().Get(route.HeaderContentType), path)
+		}
+		rec := serve(t, mw, http.MethodGet, "/favicon.ico", route.HeaderIfModifiedSince, "Wed, 01 Jan 2020 00:00:00 GMT")
+		assert.Equal(http.StatusNotModified, rec.Code)
+	}
+
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)
+	rec := httptest.NewRecorder()
+	err := New(Filesystem(fsys), File("missing.ico"))(mux.NewContext(req, rec), route.NotFoundHandler)
+	assert.Equal(http.StatusNotFound, err.(*route.HTTPError).Code)
+}
+
+func TestStaticWithOptions(t *testing.T) {
+	opts := GetDefaultOptions()
+	opts.Root = "testdata"
+	opts.Browse = true
+	mw := New(WithOptions(opts), Index("missing.html"))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/browse/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), "file1.txt")
+	}
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.NotContains(rec.Body.String(), "Route") // Listed, not the index
+	}
+	assert.Equal("index.html", opts.Index)
+
+	// Options built without the defaults.
+	mw, err := NewWithError(WithOptions(Options{
+		Filesystem: fstest.MapFS{"index.html": {Data: []byte("index")}},
+		Index:      "index.html",
+	}))
+	if assert.NoError(err) {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		rec = httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal("index", rec.Body.String())
+		}
+	}
+}
+
+func TestStaticErrorHandler(t *testing.T) {
+	fsys := &flakyFS{FS: fstest.MapFS{"file.txt": {Data: []byte("file")}}}
+	var logged []error
+	mw := New(Filesystem(fsys), ErrorHandler(func(c route.Context, err error) error {
+		logged = append(logged, err)
+		if errors.Is(err, syscall.EMFILE) {
+			return c.String(http.StatusTooManyRequests, "busy")
+		}
+		return err
+	}))
+	get := func(path string, next route.HandlerFunc) (int, error) {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		err := mw(mux.NewContext(req, rec), next)
+		return rec.Code, err
+	}
+
+	assert := assert.New(t)
+	fsys.failing = 1
+	code, err := get("/file.txt", route.NotFoundHandler)
+	assert.NoError(err)
+	assert.Equal(http.StatusTooManyRequests, code)
+	assert.Len(logged, 1)
+
+	// Errors of the next handler are passed as is.
+	_, err = get("/missing.txt", route.NotFoundHandler)
+	assert.Equal(route.ErrNotFound, err)
+	assert.Len(logged, 1)
+}
+
+// deniedFS denies the permission to open the files named "secret".
+type deniedFS struct {
+	fs.FS
+}
+
+func (f deniedFS) Open(name string) (fs.File, error) {
+	if path.Base(name) == "secret" {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.FS.Open(name)
+}
+
+func TestStaticPermissionDenied(t *testing.T) {
+	fsys := deniedFS{fstest.MapFS{"file.txt": {Data: []byte("file")}}}
+	assert := assert.New(t)
+	for _, test := range []struct {
+		options []Option
+		want    int
+	}{
+		{nil, http.StatusForbidden},
+		{[]Option{PermissionDeniedStatus(http.StatusNotFound)}, http.StatusNotFound},
+	} {
+		mw := New(append(test.options, Filesystem(fsys))...)
+		assert.Equal(http.StatusOK, serve(t, mw, http.MethodGet, "/file.txt").Code)
+		assert.Equal(test.want, serve(t, mw, http.MethodGet, "/secret").Code)
+		assert.Equal(http.StatusNotFound, serve(t, mw, http.MethodGet, "/missing").Code)
+	}
+
+	_, err := NewWithError(Filesystem(fsys), PermissionDeniedStatus(http.StatusOK))
+	assert.EqualError(err, "static: invalid PermissionDeniedStatus 200")
+}
@@ -0,0 +1,155 @@
+package static
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// throttles are the token buckets of the connections, refilled at rate
+	// bytes per second up to burst bytes, and shared by their responses so
+	// that keep-alive and concurrent requests are throttled together.
+	throttles struct {
+		rate  int64
+		burst int64
+
+		mu      sync.Mutex
+		buckets map[string]*throttleBucket
+	}
+
+	// throttleBucket is the token bucket of a connection.
+	throttleBucket struct {
+		t    *throttles
+		key  string
+		refs int // Responses in progress, guarded by t.mu.
+
+		mu     sync.Mutex
+		tokens float64
+		last   time.Time
+	}
+
+	// throttleWriter limits the rate at which the body of a response is
+	// written with the token bucket of its connection.
+	throttleWriter struct {
+		http.ResponseWriter
+		ctx    context.Context
+		bucket *throttleBucket
+	}
+)
+
+// throttleBucketsSize is the number of token buckets beyond which those of
+// idle connections are dropped once refilled.
+const throttleBucketsSize = 10000
+
+func newThrottles(rate, burst int64) *throttles {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &throttles{rate: rate, burst: burst, buckets: map[string]*throttleBucket{}}
+}
+
+// acquire returns the token bucket of the connection of the request, which
+// must be released once the response is written.
+func (t *throttles) acquire(r *http.Request) *throttleBucket {
+	key := r.RemoteAddr
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.buckets[key]
+	if b == nil {
+		if len(t.buckets) >= throttleBucketsSize {
+			t.prune()
+		}
+		b = &throttleBucket{t: t, key: key, tokens: float64(t.burst), last: time.Now()}
+		t.buckets[key] = b
+	}
+	b.refs++
+	return b
+}
+
+// prune drops the buckets of idle connections which are refilled, as they
+// are the same as new ones.
+func (t *throttles) prune() {
+	for key, b := range t.buckets {
+		if b.refs > 0 {
+			continue
+		}
+		b.mu.Lock()
+		full := b.refill(time.Now()) >= float64(t.burst)
+		b.mu.Unlock()
+		if full {
+			delete(t.buckets, key)
+		}
+	}
+}
+
+// release ends a response using the bucket.
+func (b *throttleBucket) release() {
+	b.t.mu.Lock()
+	b.refs--
+	b.t.mu.Unlock()
+}
+
+// refill adds the tokens earned since the last refill, returning them.
+func (b *throttleBucket) refill(now time.Time) float64 {
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.t.rate)
+	if b.tokens > float64(b.t.burst) {
+		b.tokens = float64(b.t.burst)
+	}
+	b.last = now
+	return b.tokens
+}
+
+// wait takes n tokens from the bucket, waiting for it to refill if it runs
+// out. It returns early with an error if the request is canceled.
+func (b *throttleBucket) wait(ctx context.Context, n int) error {
+	b.mu.Lock()
+	b.refill(time.Now())
+	b.tokens -= float64(n)
+	tokens := b.tokens
+	b.mu.Unlock()
+	if tokens >= 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(-tokens / float64(b.t.rate) * float64(time.Second)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *throttleWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		chunk := b
+		if int64(len(chunk)) > w.bucket.t.burst {
+			chunk = chunk[:w.bucket.t.burst]
+		}
+		if err = w.bucket.wait(w.ctx, len(chunk)); err != nil {
+			return
+		}
+		m, err := w.ResponseWriter.Write(chunk)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		b = b[m:]
+	}
+	return
+}
+
+func (w *throttleWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *throttleWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
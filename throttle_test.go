@@ -0,0 +1,71 @@
+package static
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 3000)
+	fsys := fstest.MapFS{
+		"big.bin": {Data: data},
+	}
+	mw := New(Filesystem(fsys), Throttle(10000, 1000))
+
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/big.bin", nil)
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		// The first 1000 bytes are the burst, the rest takes 200ms.
+		assert.True(time.Since(start) >= 150*time.Millisecond, time.Since(start).String())
+		assert.Equal(data, rec.Body.Bytes())
+	}
+
+	// Following requests of the connection share its bucket, without burst.
+	req = httptest.NewRequest(http.MethodGet, "/big.bin", nil)
+	rec = httptest.NewRecorder()
+	start = time.Now()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.True(time.Since(start) >= 250*time.Millisecond, time.Since(start).String())
+	}
+
+	// Canceled requests stop waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req = httptest.NewRequest(http.MethodGet, "/big.bin", nil).WithContext(ctx)
+	req.RemoteAddr = "192.0.2.2:1234"
+	rec = httptest.NewRecorder()
+	start = time.Now()
+	mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	assert.True(time.Since(start) < 150*time.Millisecond)
+	assert.Equal(1000, rec.Body.Len())
+}
+
+func TestThrottlesPrune(t *testing.T) {
+	th := newThrottles(1000, 100)
+	idle := th.acquire(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NoError(t, idle.wait(context.Background(), 100))
+	idle.release()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	busy := th.acquire(req)
+	busy.last = time.Now().Add(-time.Hour)
+
+	// Only the buckets of idle connections which are refilled are dropped.
+	th.prune()
+	assert.Len(t, th.buckets, 2)
+	idle.last = time.Now().Add(-time.Second)
+	th.prune()
+	assert.Len(t, th.buckets, 1)
+	assert.Equal(t, busy, th.buckets[req.RemoteAddr])
+}
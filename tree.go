@@ -0,0 +1,152 @@
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+
+	"github.com/goroute/route"
+)
+
+const treeHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>{{ .Name }}</title>
+  <style>
+    body {
+      font-family: Menlo, Consolas, monospace;
+      padding: 48px;
+    }
+    ul {
+      list-style-type: none;
+      padding-left: 20px;
+    }
+    summary {
+      cursor: pointer;
+    }
+    a {
+      text-decoration: none;
+    }
+    .dir {
+      color: #E91E63;
+    }
+    .file {
+      color: #673AB7;
+    }
+    span {
+      color: #707070;
+      font-size: 12px;
+    }
+  </style>
+</head>
+<body>
+  <header>{{ .Name }}</header>
+  {{ template "nodes" .Nodes }}
+</body>
+</html>
+{{ define "nodes" }}
+<ul>
+  {{ range . }}
+  <li>
+    {{ if .Dir }}
+    <details>
+      <summary><a class="dir" href="{{ .Path }}/">{{ .Icon }} {{ .Name }}/</a></summary>
+      {{ template "nodes" .Children }}
+    </details>
+    {{ else }}
+    <a class="file" href="{{ .Path }}">{{ .Icon }} {{ .Name }}</a> <span>{{ .HumanSize }}</span>
+    {{ end }}
+  </li>
+  {{ end }}
+</ul>
+{{ end }}
+`
+
+type (
+	// DirTree is the data of the tree view of a directory.
+	DirTree struct {
+		// URL path of the directory.
+		Name string
+
+		// Entries of the directory, directories first.
+		Nodes []TreeNode
+	}
+
+	// TreeNode is an entry of a tree view, with the entries of directories
+	// down to the depth limit. It is also the element of JSON trees.
+	TreeNode struct {
+		DirEntry
+
+		// URL path of the entry relative to the directory of the tree, e.g.
+		// "./css/site.css".
+		Path string `json:"path"`
+
+		// Entries of directories, nil beyond the depth limit.
+		Children []TreeNode `json:"children,omitempty"`
+	}
+)
+
+var treeTemplate = template.Must(template.New("tree").Parse(treeHTML))
+
+// tree returns the tree of the named directory down to depth levels, at the
+// relative URL path rel.
+func (s *server) tree(ctx context.Context, name, rel string, depth int) ([]TreeNode, error) {
+	nodes := []TreeNode{}
+	err := s.readDir(ctx, name, func(e fs.DirEntry) error {
+		p := path.Join(name, e.Name())
+		if !s.visible(p, e.IsDir()) || e.Type()&fs.ModeSymlink != 0 && s.escapes(p) {
+			return nil
+		}
+		f, err := e.Info()
+		if err != nil {
+			return err
+		}
+		modTime := f.ModTime()
+		if s.BrowseTimeLocation != nil && !modTime.IsZero() {
+			modTime = modTime.In(s.BrowseTimeLocation)
+		}
+		node := TreeNode{
+			DirEntry: DirEntry{f.Name(), f.Size(), modTime, f.IsDir()},
+			Path:     rel + "/" + url.PathEscape(f.Name()),
+		}
+		if f.IsDir() && depth > 1 {
+			if node.Children, err = s.tree(ctx, p, node.Path, depth-1); err != nil {
+				return err
+			}
+		}
+		nodes = append(nodes, node)
+		return nil
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Dir != nodes[j].Dir {
+			return nodes[i].Dir
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes, err
+}
+
+// treeDir sends the tree view of the named directory, as JSON or HTML.
+func (s *server) treeDir(c route.Context, name string) (err error) {
+	nodes, err := s.tree(c.Request().Context(), name, ".", s.BrowseTreeDepth)
+	if err != nil {
+		return
+	}
+
+	header := s.setListingHeaders(c)
+	w := s.newListingWriter(c)
+	if wantsJSON(c) {
+		header.Set(route.HeaderContentType, route.MIMEApplicationJSONCharsetUTF8)
+		err = json.NewEncoder(w).Encode(nodes)
+	} else {
+		header.Set(route.HeaderContentType, route.MIMETextHTMLCharsetUTF8)
+		err = treeTemplate.Execute(w, DirTree{Name: path.Join("/", name), Nodes: nodes})
+	}
+	return w.finish(err)
+}
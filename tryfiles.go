@@ -0,0 +1,31 @@
+package static
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// tryFiles returns the name of the first of TryFiles found for the request
+// path p, or of the last one, resolved like request paths. It returns the
+// status code of a last candidate such as "=404" instead.
+func (s *server) tryFiles(ctx context.Context, p string) (string, int) {
+	p = "/" + strings.TrimPrefix(p, "/")
+	last := len(s.TryFiles) - 1
+	for i, candidate := range s.TryFiles {
+		candidate = strings.Replace(candidate, "$uri", p, -1)
+		if i == last {
+			if strings.HasPrefix(candidate, "=") {
+				if code, err := strconv.Atoi(candidate[1:]); err == nil {
+					return "", code
+				}
+			}
+			return s.canonical(ctx, fsPath(candidate)), 0
+		}
+		name := s.canonical(ctx, fsPath(candidate))
+		if fi, err := s.stat(ctx, name); err == nil && fi.IsDir() == strings.HasSuffix(candidate, "/") {
+			return name, 0
+		}
+	}
+	return s.canonical(ctx, fsPath(p)), 0
+}
@@ -0,0 +1,60 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("spa")},
+		"about.html":      {Data: []byte("about")},
+		"app.js":          {Data: []byte("app")},
+		"docs/index.html": {Data: []byte("docs")},
+		"docs.html":       {Data: []byte("docs page")},
+	}
+	mw := New(Filesystem(fsys), TryFiles("$uri", "$uri/", "$uri.html", "/index.html"))
+	assert := assert.New(t)
+
+	for target, want := range map[string]string{
+		"/app.js":        "app",
+		"/docs/":         "docs",
+		"/about":         "about",
+		"/users/1":       "spa",
+		"/missing.js":    "spa",
+		"/":              "spa",
+		"/about.html":    "about",
+		"/docs/missing/": "spa",
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), target) {
+			assert.Equal(want, rec.Body.String(), target)
+		}
+	}
+
+	// Directories before extensionless files
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusMovedPermanently, rec.Code)
+		assert.Equal("/docs/", rec.Header().Get(route.HeaderLocation))
+	}
+
+	// Status codes
+	mw = New(Filesystem(fsys), TryFiles("$uri", "$uri.html", "=404"))
+	req = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	rec = httptest.NewRecorder()
+	assert.Equal(route.ErrNotFound, mw(mux.NewContext(req, rec), route.NotFoundHandler))
+	mw = New(Filesystem(fsys), TryFiles("$uri", "=403"))
+	req = httptest.NewRequest(http.MethodGet, "/about", nil)
+	rec = httptest.NewRecorder()
+	assert.Equal(route.ErrForbidden, mw(mux.NewContext(req, rec), route.NotFoundHandler))
+}
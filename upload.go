@@ -0,0 +1,145 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/goroute/route"
+)
+
+// upload writes the files of the multipart form posted to the named
+// directory.
+func (s *server) upload(c route.Context, name string) error {
+	r := c.Request()
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return route.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var uploaded []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return route.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		p, err := s.uploadFile(c, name, part.FileName(), part)
+		if err != nil {
+			return err
+		}
+		uploaded = append(uploaded, "/"+p)
+	}
+	if len(uploaded) == 0 {
+		return route.NewHTTPError(http.StatusBadRequest, "no file uploaded")
+	}
+	if wantsJSON(c) {
+		return c.JSON(http.StatusCreated, uploaded)
+	}
+	return c.Redirect(http.StatusSeeOther, r.URL.String())
+}
+
+// uploadFile writes the file named filename by the client to the named
+// directory, returning its path from the root. The file is written aside
+// then renamed, so that it is never served partially.
+func (s *server) uploadFile(c route.Context, dir, filename string, content io.Reader) (string, error) {
+	// Clients may send paths, of which only the base name is kept.
+	filename = path.Base(strings.Replace(filename, `\`, "/", -1))
+	if _, err := resolveName(filename); err != nil || filename == "." || filename == ".." || filename == "/" {
+		return "", route.NewHTTPError(http.StatusBadRequest, "invalid file name")
+	}
+	name := path.Join(dir, filename)
+	if !s.visible(name, false) || s.escapes(dir) {
+		return "", route.ErrForbidden
+	}
+	if !s.uploadAllowed(name) {
+		return "", route.ErrUnsupportedMediaType
+	}
+
+	dst := filepath.Join(s.writeDir, filepath.FromSlash(name))
+	fi, err := os.Lstat(dst)
+	if err == nil && !s.UploadOverwrite {
+		return "", route.NewHTTPError(http.StatusConflict, "file exists")
+	}
+	if err != nil {
+		fi = nil
+	}
+	if err := s.Authorize(c, "/"+name, fi); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name()) // Fails once renamed.
+	if s.UploadMaxSize > 0 {
+		content = io.LimitReader(content, s.UploadMaxSize+1)
+	}
+	n, err := io.Copy(f, content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if s.UploadMaxSize > 0 && n > s.UploadMaxSize {
+		return "", route.ErrStatusRequestEntityTooLarge
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if !s.UploadOverwrite {
+		if err := createNew(f.Name(), dst); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return "", route.NewHTTPError(http.StatusConflict, "file exists")
+			}
+			return "", err
+		}
+	} else if err := os.Rename(f.Name(), dst); err != nil {
+		return "", err
+	}
+	s.invalidate(name)
+	return name, nil
+}
+
+// link is os.Link, replaced in tests.
+var link = os.Link
+
+// createNew moves the file tmp to dst, failing with os.ErrExist if dst was
+// created meanwhile, unlike rename. It links tmp to dst or, on filesystems
+// without hard links, creates dst exclusively then replaces it with tmp.
+func createNew(tmp, dst string) error {
+	err := link(tmp, dst)
+	if err == nil || errors.Is(err, os.ErrExist) {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Rename(tmp, dst)
+}
+
+// uploadAllowed reports whether the extension of the named file is allowed
+// for uploads.
+func (s *server) uploadAllowed(name string) bool {
+	if len(s.UploadExtensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(path.Ext(name))
+	for _, allowed := range s.UploadExtensions {
+		if ext == "."+strings.TrimPrefix(strings.ToLower(allowed), ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package static
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, "drop"), 0o755)
+	os.WriteFile(filepath.Join(root, "drop", "a.txt"), []byte("a"), 0o644)
+	mw := New(Root(root), Browse(true), Upload(true), UploadMaxSize(4), UploadExtensions("txt", ".md"),
+		Authorize(func(c route.Context, p string, fi os.FileInfo) error {
+			if p == "/drop/locked.txt" {
+				return route.ErrForbidden
+			}
+			return nil
+		}))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	upload := func(files map[string]string) (*httptest.ResponseRecorder, error) {
+		body := new(bytes.Buffer)
+		mpw := multipart.NewWriter(body)
+		for name, content := range files {
+			w, _ := mpw.CreateFormFile("file", name)
+			w.Write([]byte(content))
+		}
+		mpw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/drop/", body)
+		req.Header.Set(route.HeaderContentType, mpw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		return rec, mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	}
+
+	// Form
+	req := httptest.NewRequest(http.MethodGet, "/drop/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Contains(rec.Body.String(), `enctype="multipart/form-data"`)
+	}
+
+	// Upload
+	rec, err := upload(map[string]string{`C:\Users\me\b.txt`: "b", "c.md": "c"})
+	if assert.NoError(err) {
+		assert.Equal(http.StatusSeeOther, rec.Code)
+		assert.Equal("/drop/", rec.Header().Get(route.HeaderLocation))
+		b, _ := os.ReadFile(filepath.Join(root, "drop", "b.txt"))
+		assert.Equal("b", string(b))
+		b, _ = os.ReadFile(filepath.Join(root, "drop", "c.md"))
+		assert.Equal("c", string(b))
+	}
+
+	// Existing file
+	_, err = upload(map[string]string{"a.txt": "new"})
+	if assert.IsType(&route.HTTPError{}, err) {
+		assert.Equal(http.StatusConflict, err.(*route.HTTPError).Code)
+	}
+	b, _ := os.ReadFile(filepath.Join(root, "drop", "a.txt"))
+	assert.Equal("a", string(b))
+
+	// Too large
+	_, err = upload(map[string]string{"d.txt": "12345"})
+	assert.Equal(route.ErrStatusRequestEntityTooLarge, err)
+	_, err = os.Stat(filepath.Join(root, "drop", "d.txt"))
+	assert.True(os.IsNotExist(err))
+
+	// Extension not allowed
+	_, err = upload(map[string]string{"e.exe": "e"})
+	assert.Equal(route.ErrUnsupportedMediaType, err)
+
+	// Hidden
+	_, err = upload(map[string]string{".htaccess": "f"})
+	assert.Equal(route.ErrForbidden, err)
+
+	// Not authorized
+	_, err = upload(map[string]string{"locked.txt": "f"})
+	assert.Equal(route.ErrForbidden, err)
+	_, err = os.Stat(filepath.Join(root, "drop", "locked.txt"))
+	assert.True(os.IsNotExist(err))
+
+	// Without hard links
+	defer func(l func(string, string) error) { link = l }(link)
+	link = func(string, string) error { return syscall.EPERM }
+	_, err = upload(map[string]string{"h.txt": "h"})
+	if assert.NoError(err) {
+		b, _ := os.ReadFile(filepath.Join(root, "drop", "h.txt"))
+		assert.Equal("h", string(b))
+	}
+	link = func(_, dst string) error {
+		os.WriteFile(dst, []byte("raced"), 0o644) // Created meanwhile
+		return syscall.EPERM
+	}
+	_, err = upload(map[string]string{"i.txt": "i"})
+	if assert.IsType(&route.HTTPError{}, err) {
+		assert.Equal(http.StatusConflict, err.(*route.HTTPError).Code)
+	}
+	b, _ = os.ReadFile(filepath.Join(root, "drop", "i.txt"))
+	assert.Equal("raced", string(b))
+
+	// Invalid on Windows
+	defer func(w bool) { windows = w }(windows)
+	windows = true
+	for _, name := range []string{"nul.txt", "g.txt:stream", "g.txt."} {
+		_, err = upload(map[string]string{name: "g"})
+		if assert.IsType(&route.HTTPError{}, err, name) {
+			assert.Equal(http.StatusBadRequest, err.(*route.HTTPError).Code, name)
+		}
+	}
+
+	// No leftover
+	entries, _ := os.ReadDir(filepath.Join(root, "drop"))
+	assert.Len(entries, 5)
+}
+
+func TestUploadOverwrite(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	mw := New(Root(root), Browse(true), Upload(true), Authorize(authorizeAll), UploadOverwrite(true), Cache(1<<20, 0, 0))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+	assert.Equal("a", rec.Body.String())
+
+	body := new(bytes.Buffer)
+	mpw := multipart.NewWriter(body)
+	w, _ := mpw.CreateFormFile("file", "a.txt")
+	w.Write([]byte("new"))
+	mpw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/?format=json", body)
+	req.Header.Set(route.HeaderContentType, mpw.FormDataContentType())
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusCreated, rec.Code)
+		assert.JSONEq(`["/a.txt"]`, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+	rec = httptest.NewRecorder()
+	assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+	assert.Equal("new", rec.Body.String())
+}
+
+func TestUploadRequiresRoot(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Filesystem(fstest.MapFS{}), Browse(true), Upload(true), Authorize(authorizeAll))
+	})
+}
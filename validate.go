@@ -0,0 +1,125 @@
+package static
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// validate returns an error describing the first invalid option.
+func validate(opts Options) error {
+	if opts.Filesystem == nil {
+		roots := opts.Roots
+		if len(roots) == 0 {
+			roots = []string{opts.Root}
+		}
+		for _, root := range roots {
+			if err := validateDir("root", root); err != nil {
+				return err
+			}
+		}
+		for _, host := range vhostPatterns(opts.VHosts) {
+			if err := validateDir("root of "+host, opts.VHosts[host]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.TenantRoot != nil {
+		if opts.RootFunc != nil {
+			return errors.New("static: TenantRoot conflicts with RootFunc")
+		}
+		if len(opts.Roots) > 0 || len(opts.VHosts) > 0 {
+			return errors.New("static: TenantRoot requires a single Root directory")
+		}
+	}
+
+	for _, index := range append([]string{opts.Index}, opts.Indexes...) {
+		if index == "" || index == "." || index == ".." || strings.ContainsAny(index, `/\`) {
+			return fmt.Errorf("static: invalid index name %q", index)
+		}
+	}
+
+	if opts.File != "" {
+		for _, mode := range []struct {
+			name    string
+			enabled bool
+		}{
+			{"Browse", opts.Browse},
+			{"HTML5", opts.HTML5},
+			{"WebDAV", opts.WebDAV},
+			{"Upload", opts.Upload},
+			{"TryFiles", len(opts.TryFiles) > 0},
+			{"LiveReload", opts.LiveReload},
+		} {
+			if mode.enabled {
+				return fmt.Errorf("static: File serves a single file, it conflicts with %s", mode.name)
+			}
+		}
+	}
+	if opts.Authorize == nil {
+		for _, mode := range []struct {
+			name    string
+			enabled bool
+		}{
+			{"Upload", opts.Upload},
+			{"AllowDelete", opts.AllowDelete},
+			{"AllowRename", opts.AllowRename},
+		} {
+			if mode.enabled {
+				return fmt.Errorf("static: %s requires Authorize", mode.name)
+			}
+		}
+	}
+	if opts.StripPrefix != "" && !strings.HasPrefix(opts.StripPrefix, "/") {
+		return fmt.Errorf("static: StripPrefix %q must start with a slash", opts.StripPrefix)
+	}
+	if opts.Events != "" {
+		if !strings.HasPrefix(opts.Events, "/") {
+			return fmt.Errorf("static: Events path %q must start with a slash", opts.Events)
+		}
+		if opts.LiveReload && opts.Events == liveReloadPath {
+			return fmt.Errorf("static: Events path %q is the live reload path", opts.Events)
+		}
+	}
+	if code := opts.PermissionDeniedStatus; code != 0 && (code < 400 || code > 599) {
+		return fmt.Errorf("static: invalid PermissionDeniedStatus %d", code)
+	}
+	switch opts.UnicodeNormalization {
+	case "", "NFC", "NFD":
+	default:
+		return fmt.Errorf("static: invalid Unicode normalization form %q", opts.UnicodeNormalization)
+	}
+	for _, limit := range []struct {
+		name  string
+		value int64
+	}{
+		{"MaxRanges", int64(opts.MaxRanges)},
+		{"MaxPathLength", int64(opts.MaxPathLength)},
+		{"MaxPathDepth", int64(opts.MaxPathDepth)},
+		{"MaxFileSize", opts.MaxFileSize},
+		{"UploadMaxSize", opts.UploadMaxSize},
+		{"CacheMaxBytes", opts.CacheMaxBytes},
+		{"IORetries", int64(opts.IORetries)},
+		{"IOBreakerThreshold", int64(opts.IOBreakerThreshold)},
+	} {
+		if limit.value < 0 {
+			return fmt.Errorf("static: negative %s %d", limit.name, limit.value)
+		}
+	}
+	return nil
+}
+
+// validateDir returns an error if the root directory dir doesn't exist. Roots
+// may also be archive files.
+func validateDir(what, dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("static: %s: %w", what, err)
+	}
+	if !fi.IsDir() && !(fi.Mode().IsRegular() && isArchive(dir)) {
+		return fmt.Errorf("static: %s %s is not a directory", what, dir)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package static
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithError(t *testing.T) {
+	assert := assert.New(t)
+	mw, err := NewWithError(Root("testdata"))
+	assert.NoError(err)
+	assert.NotNil(mw)
+	_, err = NewWithError(Filesystem(fstest.MapFS{}), Root("missing"))
+	assert.NoError(err)
+
+	for _, test := range []struct {
+		options []Option
+		err     string
+	}{
+		{[]Option{Root("missing")}, "static: root: stat missing: no such file or directory"},
+		{[]Option{Root("testdata/index.html")}, "static: root testdata/index.html is not a directory"},
+		{[]Option{Roots("testdata", "missing")}, "static: root: stat missing: no such file or directory"},
+		{[]Option{Root("testdata"), VHost(map[string]string{"a.example.com": "missing"})}, "static: root of a.example.com: stat missing: no such file or directory"},
+		{[]Option{Root("testdata"), Index("")}, `static: invalid index name ""`},
+		{[]Option{Root("testdata"), Index("index.html", "../index.html")}, `static: invalid index name "../index.html"`},
+		{[]Option{Root("testdata"), File("index.html"), Browse(true)}, "static: File serves a single file, it conflicts with Browse"},
+		{[]Option{Root("testdata"), StripPrefix("assets")}, `static: StripPrefix "assets" must start with a slash`},
+		{[]Option{Root("testdata"), LiveReload(true), Events(liveReloadPath)}, `static: Events path "/__livereload" is the live reload path`},
+		{[]Option{Root("testdata"), UnicodeNormalization("nfkc")}, `static: invalid Unicode normalization form "nfkc"`},
+		{[]Option{Root("testdata"), MaxFileSize(-1)}, "static: negative MaxFileSize -1"},
+		{[]Option{Root("testdata"), Upload(true)}, "static: Upload requires Authorize"},
+		{[]Option{Root("testdata"), AllowDelete(true)}, "static: AllowDelete requires Authorize"},
+		{[]Option{Root("testdata"), AllowRename(true)}, "static: AllowRename requires Authorize"},
+		{[]Option{Root("testdata"), Rewrite(map[string]string{"^(": "/"})}, ""},
+	} {
+		mw, err := NewWithError(test.options...)
+		if assert.Error(err, test.err) {
+			if test.err != "" {
+				assert.Equal(test.err, err.Error())
+			}
+			assert.Nil(mw)
+			assert.Panics(func() { New(test.options...) }, test.err)
+		}
+	}
+}
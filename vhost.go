@@ -0,0 +1,52 @@
+package static
+
+import (
+	"net"
+	"path"
+	"sort"
+	"strings"
+)
+
+// vhost is the server of the hosts matching a pattern.
+type vhost struct {
+	pattern string
+	s       *server
+}
+
+// vhostPatterns returns the host patterns of vhosts in order of precedence:
+// exact hosts, then wildcard patterns, longest first.
+func vhostPatterns(vhosts map[string]string) []string {
+	patterns := make([]string, 0, len(vhosts))
+	for pattern := range vhosts {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		wi, wj := strings.Contains(patterns[i], "*"), strings.Contains(patterns[j], "*")
+		if wi != wj {
+			return wj
+		}
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
+
+// vhost returns the server of the Host header value, or nil if it is served
+// from Root.
+func (s *server) vhost(host string) *server {
+	if len(s.vhosts) == 0 {
+		return nil
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, vh := range s.vhosts {
+		if ok, _ := path.Match(strings.ToLower(vh.pattern), host); ok {
+			return vh.s
+		}
+	}
+	return nil
+}
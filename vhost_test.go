@@ -0,0 +1,45 @@
+package static
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVHost(t *testing.T) {
+	dirs := map[string]string{}
+	for _, name := range []string{"default", "a", "wildcard", "b"} {
+		dirs[name] = t.TempDir()
+		os.WriteFile(filepath.Join(dirs[name], "index.html"), []byte(name), 0o644)
+	}
+	mw, h := NewHandle(Root(dirs["default"]), Cache(1<<20, 0, 0), VHost(map[string]string{
+		"a.example.com":   dirs["a"],
+		"*.example.com":   dirs["wildcard"],
+		"b.*.example.com": dirs["b"],
+	}))
+	assert := assert.New(t)
+
+	get := func(host string) string {
+		return serve(t, mw, http.MethodGet, "http://"+host+"/").Body.String()
+	}
+	for host, want := range map[string]string{
+		"a.example.com":          "a",
+		"A.Example.com:8080":     "a",
+		"c.example.com":          "wildcard",
+		"b.eu.example.com":       "b",
+		"example.com":            "default",
+		"localhost:8080":         "default",
+		"a.example.com.evil.com": "default",
+	} {
+		assert.Equal(want, get(host), host)
+	}
+
+	// Invalidated for every host.
+	os.WriteFile(filepath.Join(dirs["a"], "index.html"), []byte("new"), 0o644)
+	assert.Equal("a", get("a.example.com"))
+	h.InvalidateAll()
+	assert.Equal("new", get("a.example.com"))
+}
@@ -0,0 +1,53 @@
+package static
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/goroute/route"
+)
+
+// warmEncodings are the encodings accepted by warming requests, so that the
+// compressed variants of the files are cached too.
+const warmEncodings = "br, gzip"
+
+// Warm requests the files at the request paths, e.g. "/" or "/app.js", to
+// load them and their compressed variants in the enabled caches before the
+// server receives traffic. Every path is requested, the error of the first
+// one failing being returned.
+func (h *Handle) Warm(paths ...string) error {
+	mux := route.NewServeMux()
+	var first error
+	for _, p := range paths {
+		req, err := http.NewRequest(http.MethodGet, p, nil)
+		if err == nil {
+			req.Header.Set(route.HeaderAcceptEncoding, warmEncodings)
+			w := &discardWriter{header: http.Header{}, code: http.StatusOK}
+			if err = h.s.serve(mux.NewContext(req, w), route.NotFoundHandler); err == nil && w.code != http.StatusOK {
+				err = route.NewHTTPError(w.code)
+			}
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("static: warm %s: %v", p, err)
+		}
+	}
+	return first
+}
+
+// discardWriter is a response writer discarding the body.
+type discardWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(code int) {
+	w.code = code
+}
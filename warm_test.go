@@ -0,0 +1,37 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarm(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "index.html"), []byte("index"), 0o644)
+	os.WriteFile(filepath.Join(root, "app.js"), []byte(strings.Repeat("warm();\n", 1000)), 0o644)
+	mw, h := NewHandle(Root(root), Cache(1<<20, 0, 0), Compress(true))
+
+	assert := assert.New(t)
+	assert.NoError(h.Warm("/", "/app.js"))
+	assert.EqualError(h.Warm("/missing.js", "/app.js"), "static: warm /missing.js: code=404, message=Not Found")
+
+	// Served from the cache once removed.
+	os.Remove(filepath.Join(root, "index.html"))
+	os.Remove(filepath.Join(root, "app.js"))
+	for path, encoding := range map[string]string{"/": "", "/app.js": "gzip"} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(route.HeaderAcceptEncoding, "gzip")
+		rec := httptest.NewRecorder()
+		assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler), path)
+		assert.Equal(http.StatusOK, rec.Code, path)
+		assert.Equal(encoding, rec.Header().Get(route.HeaderContentEncoding), path)
+	}
+}
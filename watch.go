@@ -0,0 +1,203 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch invalidates the cached files of the Root directories, Roots, Aliases
+// and VHosts when they change on disk, until the returned watcher is closed.
+// It is meant for development servers, as every subdirectory is watched.
+// Filesystems and archives aren't watched.
+func (h *Handle) Watch() (io.Closer, error) {
+	return h.s.watch(nil)
+}
+
+// watch starts watching the directories of the server and its virtual hosts,
+// calling changed, if not nil, with the event of each changed file after
+// invalidating it.
+func (s *server) watch(changed func(fileEvent)) (*watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{fw: fw, dirs: map[string][]watchedDir{}, onChange: changed, done: make(chan struct{})}
+	for _, s := range append([]*server{s}, vhostServers(s)...) {
+		if err := w.addServer(s); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+	if len(w.dirs) == 0 {
+		fw.Close()
+		return nil, errors.New("static: no directory to watch")
+	}
+	go w.run()
+	return w, nil
+}
+
+// vhostServers returns the servers of the virtual hosts of s.
+func vhostServers(s *server) []*server {
+	servers := make([]*server, len(s.vhosts))
+	for i, vh := range s.vhosts {
+		servers[i] = vh.s
+	}
+	return servers
+}
+
+// watcher invalidates the caches of servers when their files change.
+type watcher struct {
+	fw *fsnotify.Watcher
+
+	// Servers and names of the watched OS directories, only used by run
+	// once started.
+	dirs map[string][]watchedDir
+
+	// Called with the event of each changed file, if not nil.
+	onChange func(fileEvent)
+
+	done chan struct{}
+	once sync.Once
+}
+
+// watchedDir is the name of a watched OS directory in the files of a server.
+type watchedDir struct {
+	s    *server
+	name string
+}
+
+// addServer watches the OS directories of the server.
+func (w *watcher) addServer(s *server) error {
+	if s.Filesystem != nil {
+		return nil
+	}
+	roots := s.Roots
+	if len(roots) == 0 {
+		roots = []string{s.Root}
+	}
+	for _, root := range roots {
+		if archiveRoot(nil, root) {
+			continue
+		}
+		if err := w.add(s, root, "."); err != nil {
+			return err
+		}
+	}
+	for prefix, dir := range s.Aliases {
+		if archiveRoot(nil, dir) {
+			continue
+		}
+		if err := w.add(s, dir, fsPath(prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add watches the OS directory dir, the named directory of the server, and
+// its subdirectories.
+func (w *watcher) add(s *server, dir, name string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p != dir && errors.Is(err, fs.ErrNotExist) {
+				return nil // Removed meanwhile
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if err := w.fw.Add(p); err != nil {
+			return err
+		}
+		w.dirs[p] = append(w.dirs[p], watchedDir{s, path.Join(name, filepath.ToSlash(rel))})
+		return nil
+	})
+}
+
+// run invalidates the changed files until the watcher is closed.
+func (w *watcher) run() {
+	for {
+		select {
+		case ev, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			w.changed(ev)
+		case _, ok := <-w.fw.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost, e.g. on overflows.
+			invalidated := map[*server]bool{}
+			for _, watched := range w.dirs {
+				for _, wd := range watched {
+					if invalidated[wd.s] {
+						continue
+					}
+					invalidated[wd.s] = true
+					wd.s.invalidate(".")
+					if w.onChange != nil {
+						w.onChange(fileEvent{wd.s, eventModify, "/"})
+					}
+				}
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// changed invalidates the file of the event, and watches it if it is a new
+// directory.
+func (w *watcher) changed(ev fsnotify.Event) {
+	watched := w.dirs[filepath.Dir(ev.Name)]
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		delete(w.dirs, ev.Name)
+	}
+
+	for _, wd := range watched {
+		name := path.Join(wd.name, filepath.Base(ev.Name))
+		wd.s.invalidate(name)
+		if ev.Has(fsnotify.Create) {
+			if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+				w.add(wd.s, ev.Name, name)
+				wd.s.invalidate(name) // Changed before being watched
+			}
+		}
+		if typ := eventType(ev.Op); typ != "" && w.onChange != nil {
+			w.onChange(fileEvent{wd.s, typ, "/" + name})
+		}
+	}
+}
+
+// Close stops watching.
+func (w *watcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return w.fw.Close()
+}
+
+// eventType returns the type of the file event of the operation, or "" for
+// changes of permissions.
+func eventType(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Create):
+		return eventCreate
+	case op.Has(fsnotify.Remove), op.Has(fsnotify.Rename):
+		return eventDelete
+	case op.Has(fsnotify.Write):
+		return eventModify
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package static
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleWatch(t *testing.T) {
+	dir := t.TempDir()
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("v1"), 0644))
+	mw, h := NewHandle(Root(dir), Cache(1<<20, 0, 0), StatCache(time.Hour))
+	get := func(target string) string {
+		return serve(t, mw, http.MethodGet, target).Body.String()
+	}
+	eventually := func(target, want string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for get(target) != want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Equal(want, get(target), target)
+	}
+
+	w, err := h.Watch()
+	if !assert.NoError(err) {
+		return
+	}
+	defer w.Close()
+	assert.Equal("v1", get("/a.txt"))
+	assert.NotEqual("v1", get("/sub/b.txt"))
+
+	assert.NoError(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("v2"), 0644))
+	eventually("/a.txt", "v2")
+
+	// New directories are watched.
+	assert.NoError(os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b1"), 0644))
+	eventually("/sub/b.txt", "b1")
+	assert.NoError(os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b2"), 0644))
+	eventually("/sub/b.txt", "b2")
+
+	assert.NoError(w.Close())
+	_, h = NewHandle(Filesystem(os.DirFS(dir)))
+	_, err = h.Watch()
+	assert.Error(err)
+}
@@ -0,0 +1,252 @@
+package static
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/goroute/route"
+	"golang.org/x/net/webdav"
+)
+
+// davMethods are the methods handled by WebDAV, other than GET and HEAD which
+// are served as usual.
+var davMethods = map[string]bool{
+	http.MethodOptions: true,
+	route.PROPFIND:     true,
+	"PROPPATCH":        true,
+	"MKCOL":            true,
+	"COPY":             true,
+	"MOVE":             true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	"LOCK":             true,
+	"UNLOCK":           true,
+}
+
+// davReadMethods are the methods allowed in read-only WebDAV mode.
+const davReadMethods = "OPTIONS, GET, HEAD, PROPFIND"
+
+// isDAV reports whether the request is a WebDAV request handled by the
+// middleware.
+func (s *server) isDAV(r *http.Request) bool {
+	return s.WebDAV && davMethods[r.Method] && !s.isPreflight(r)
+}
+
+// serveDAV handles the WebDAV request for the file at the request path p.
+func (s *server) serveDAV(c route.Context, p string, next route.HandlerFunc) error {
+	if ok, err := s.browseAllowed(c); err != nil {
+		return err
+	} else if !ok {
+		return next(c)
+	}
+	r := c.Request()
+	if s.WebDAVReadOnly {
+		switch r.Method {
+		case http.MethodOptions:
+			header := c.Response().Header()
+			header.Set(route.HeaderAllow, davReadMethods)
+			header.Set("DAV", "1")
+			return c.NoContent(http.StatusOK)
+		case route.PROPFIND:
+		default:
+			c.Response().Header().Set(route.HeaderAllow, davReadMethods)
+			return route.ErrMethodNotAllowed
+		}
+	}
+
+	// Mounted in a group, e.g. at `/dav*`, the handler strips the group
+	// prefix from the request and Destination paths.
+	prefix := strings.TrimSuffix(strings.TrimSuffix(r.URL.Path, strings.TrimPrefix(p, "/")), "/")
+	if r.Method == "COPY" || r.Method == "MOVE" {
+		if u, err := parseDestination(r, prefix); err == nil {
+			if _, err := resolveName(u); err != nil {
+				return route.NewHTTPError(http.StatusBadRequest, "invalid destination")
+			}
+		}
+	}
+	h := &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: davFS{s},
+		LockSystem: s.davLocks,
+	}
+	h.ServeHTTP(c.Response(), r)
+
+	if r.Method != route.PROPFIND && r.Method != http.MethodOptions {
+		s.invalidate(fsPath(p))
+		if u, err := parseDestination(r, prefix); err == nil {
+			s.invalidate(fsPath(u))
+		}
+	}
+	return nil
+}
+
+// parseDestination returns the path from the root of the Destination of COPY
+// and MOVE requests.
+func parseDestination(r *http.Request, prefix string) (string, error) {
+	dst := r.Header.Get("Destination")
+	if dst == "" {
+		return "", errors.New("no destination")
+	}
+	u, err := r.URL.Parse(dst)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", errors.New("destination outside of the prefix")
+	}
+	return strings.TrimPrefix(u.Path, prefix), nil
+}
+
+// davFS is the filesystem of WebDAV requests. Files are read like served
+// ones and written to the write directory, if any.
+type davFS struct {
+	s *server
+}
+
+// writable returns the directory written to with the named file, which must
+// be a valid name, visible and not escape the root through symlinks.
+func (d davFS) writable(name string) (webdav.Dir, error) {
+	name, err := resolveName(name)
+	if err != nil {
+		return "", os.ErrNotExist
+	}
+	if d.s.writeDir == "" || !d.s.visible(name, true) || d.s.escapes(name) || d.s.escapes(path.Dir(name)) {
+		return "", os.ErrPermission
+	}
+	return webdav.Dir(d.s.writeDir), nil
+}
+
+func (d davFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	dir, err := d.writable(name)
+	if err != nil {
+		return err
+	}
+	return dir.Mkdir(ctx, name, perm)
+}
+
+func (d davFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		dir, err := d.writable(name)
+		if err != nil {
+			return nil, err
+		}
+		return dir.OpenFile(ctx, name, flag, perm)
+	}
+	fi, err := d.Stat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := d.s.open(ctx, fsPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return &davFile{File: f, s: d.s, ctx: ctx, name: fsPath(name), fi: fi}, nil
+}
+
+func (d davFS) RemoveAll(ctx context.Context, name string) error {
+	if fsPath(name) == "." {
+		return os.ErrPermission
+	}
+	dir, err := d.writable(name)
+	if err != nil {
+		return err
+	}
+	return dir.RemoveAll(ctx, name)
+}
+
+func (d davFS) Rename(ctx context.Context, oldName, newName string) error {
+	dir, err := d.writable(oldName)
+	if err != nil {
+		return err
+	}
+	if _, err := d.writable(newName); err != nil {
+		return err
+	}
+	return dir.Rename(ctx, oldName, newName)
+}
+
+func (d davFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fi, err := d.s.stat(ctx, fsPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return davInfo{fi, d.s, fsPath(name)}, nil
+}
+
+// davFile is a file read with WebDAV.
+type davFile struct {
+	fs.File
+	s    *server
+	ctx  context.Context
+	name string
+	fi   fs.FileInfo
+}
+
+func (f *davFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errors.New("not implemented")}
+}
+
+// Readdir returns the visible entries of the directory, all of them whatever
+// count.
+func (f *davFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.fi.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errors.New("not a directory")}
+	}
+	var infos []os.FileInfo
+	err := f.s.readDir(f.ctx, f.name, func(e fs.DirEntry) error {
+		p := path.Join(f.name, e.Name())
+		if !f.s.visible(p, e.IsDir()) || e.Type()&fs.ModeSymlink != 0 && f.s.escapes(p) {
+			return nil
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		infos = append(infos, davInfo{fi, f.s, p})
+		return nil
+	})
+	return infos, err
+}
+
+func (f *davFile) Stat() (os.FileInfo, error) {
+	return f.fi, nil
+}
+
+func (f *davFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// davInfo is the FileInfo of a file read with WebDAV, whose properties are
+// those of the file served.
+type davInfo struct {
+	fs.FileInfo
+	s    *server
+	name string
+}
+
+// ETag returns the entity tag of the file, as served.
+func (fi davInfo) ETag(ctx context.Context) (string, error) {
+	return etag(fi.FileInfo), nil
+}
+
+// ContentType returns the Content-Type of the file, as served.
+func (fi davInfo) ContentType(ctx context.Context) (string, error) {
+	if fi.IsDir() {
+		return "", webdav.ErrNotImplemented
+	}
+	f, err := fi.s.open(ctx, fi.name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return fi.s.contentType(fi.name, f), nil
+}
@@ -0,0 +1,144 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebDAV(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(root, ".secret"), []byte("hidden"), 0o644)
+	mw := New(Root(root), WebDAV(true), Cache(1<<20, 0, 0))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	do := func(method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler))
+		return rec
+	}
+
+	// Listing
+	rec := do(route.PROPFIND, "/", "", map[string]string{"Depth": "1"})
+	assert.Equal(http.StatusMultiStatus, rec.Code)
+	assert.Contains(rec.Body.String(), "<D:href>/a.txt</D:href>")
+	assert.NotContains(rec.Body.String(), ".secret")
+
+	// Writing
+	assert.Equal("a", do(http.MethodGet, "/a.txt", "", nil).Body.String())
+	assert.Equal(http.StatusCreated, do("MKCOL", "/dir", "", nil).Code)
+	assert.Equal(http.StatusCreated, do(http.MethodPut, "/a.txt", "b", nil).Code)
+	assert.Equal("b", do(http.MethodGet, "/a.txt", "", nil).Body.String())
+	assert.Equal(http.StatusCreated, do("MOVE", "/a.txt", "", map[string]string{"Destination": "/dir/a.txt"}).Code)
+	b, err := os.ReadFile(filepath.Join(root, "dir", "a.txt"))
+	assert.NoError(err)
+	assert.Equal("b", string(b))
+	assert.Equal(http.StatusNotFound, do(http.MethodDelete, "/.secret", "", nil).Code)
+	assert.Equal(http.StatusNoContent, do(http.MethodDelete, "/dir", "", nil).Code)
+	_, err = os.Stat(filepath.Join(root, "dir"))
+	assert.True(os.IsNotExist(err))
+}
+
+func TestWebDAVWindowsNames(t *testing.T) {
+	defer func(w bool) { windows = w }(windows)
+	windows = true
+
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644)
+	mw := New(Root(root), WebDAV(true))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	do := func(method, target string, header map[string]string) int {
+		req := httptest.NewRequest(method, target, strings.NewReader("b"))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		if err := mw(mux.NewContext(req, rec), route.NotFoundHandler); err != nil {
+			return err.(*route.HTTPError).Code
+		}
+		return rec.Code
+	}
+
+	for _, dst := range []string{"/CON", "/nul.txt", "/b.txt::$DATA", "/b.txt.", "/b.txt%20"} {
+		assert.Equal(http.StatusBadRequest, do("MOVE", "/a.txt", map[string]string{"Destination": dst}), dst)
+		assert.Equal(http.StatusBadRequest, do("COPY", "/a.txt", map[string]string{"Destination": dst}), dst)
+	}
+	assert.Equal(http.StatusNotFound, do(http.MethodPut, "/aux.txt", nil))
+	s, err := newHandle([]Option{Root(root), WebDAV(true)})
+	if assert.NoError(err) {
+		_, err = davFS{s}.writable("/dir/con.txt")
+		assert.Equal(os.ErrNotExist, err)
+	}
+	assert.Equal(http.StatusCreated, do("MOVE", "/a.txt", map[string]string{"Destination": "/b.txt"}))
+
+	entries, _ := os.ReadDir(root)
+	if assert.Len(entries, 1) {
+		assert.Equal("b.txt", entries[0].Name())
+	}
+}
+
+func TestWebDAVReadOnly(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/a.md": {Data: []byte("# A")},
+	}
+	mw := New(Filesystem(fsys), WebDAVReadOnly(true))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+
+	req := httptest.NewRequest(http.MethodOptions, "/docs/", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("OPTIONS, GET, HEAD, PROPFIND", rec.Header().Get(route.HeaderAllow))
+		assert.Equal("1", rec.Header().Get("DAV"))
+	}
+
+	req = httptest.NewRequest(route.PROPFIND, "/docs/a.md", nil)
+	req.Header.Set("Depth", "0")
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusMultiStatus, rec.Code)
+		assert.Contains(rec.Body.String(), "<D:getcontentlength>3</D:getcontentlength>")
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/docs/b.md", strings.NewReader("b"))
+	rec = httptest.NewRecorder()
+	err := mw(mux.NewContext(req, rec), route.NotFoundHandler)
+	assert.Equal(route.ErrMethodNotAllowed, err)
+	assert.Equal("OPTIONS, GET, HEAD, PROPFIND", rec.Header().Get(route.HeaderAllow))
+}
+
+func TestWebDAVGroup(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": {Data: []byte("a")},
+	}
+	mw := New(Filesystem(fsys), WebDAVReadOnly(true))
+	mux := route.NewServeMux()
+	mux.Add(route.PROPFIND, "/dav*", func(c route.Context) error {
+		return nil
+	}, mw)
+	req := httptest.NewRequest(route.PROPFIND, "/dav/a.txt", nil)
+	req.Header.Set("Depth", "0")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<D:href>/dav/a.txt</D:href>")
+}
+
+func TestWebDAVWritesRequireRoot(t *testing.T) {
+	assert.Panics(t, func() {
+		New(Filesystem(fstest.MapFS{}), WebDAV(true))
+	})
+}
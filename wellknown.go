@@ -0,0 +1,84 @@
+package static
+
+import (
+	"bytes"
+	"io/fs"
+	"time"
+
+	"github.com/goroute/route"
+)
+
+// faviconCacheControl is the Cache-Control header of favicons, which rarely
+// change.
+const faviconCacheControl = "public, max-age=86400"
+
+// Robots returns a Static middleware sending the content as robots.txt for
+// every request, e.g.
+// `mux.GET("/robots.txt", route.NotFoundHandler, static.Robots("User-agent: *\nDisallow: /admin/\n"))`.
+// Options apply as for any file, e.g. Headers.
+func Robots(content string, options ...Option) route.MiddlewareFunc {
+	fsys := &contentFS{name: "robots.txt", data: []byte(content), modTime: time.Now()}
+	return New(append(options, Filesystem(fsys), File(fsys.name))...)
+}
+
+// Favicon returns a Static middleware sending the file at the path, from Root
+// or Filesystem, for every request, e.g.
+// `mux.GET("/favicon.ico", route.NotFoundHandler, static.Favicon("assets/favicon.ico"))`.
+// It is cached by clients for a day unless CacheControl rules match it.
+func Favicon(path string, options ...Option) route.MiddlewareFunc {
+	return New(append(options, File(path), CacheControl(CacheRule{Patterns: []string{"**"}, Value: faviconCacheControl}))...)
+}
+
+type (
+	// contentFS is a file system of a single file in memory.
+	contentFS struct {
+		name    string
+		data    []byte
+		modTime time.Time
+	}
+
+	// contentFile is the open file of a contentFS.
+	contentFile struct {
+		*bytes.Reader
+		fs *contentFS
+	}
+)
+
+func (f *contentFS) Open(name string) (fs.File, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &contentFile{bytes.NewReader(f.data), f}, nil
+}
+
+func (f *contentFile) Stat() (fs.FileInfo, error) {
+	return f, nil
+}
+
+func (f *contentFile) Close() error {
+	return nil
+}
+
+func (f *contentFile) Name() string {
+	return f.fs.name
+}
+
+func (f *contentFile) Size() int64 {
+	return int64(len(f.fs.data))
+}
+
+func (f *contentFile) Mode() fs.FileMode {
+	return 0444
+}
+
+func (f *contentFile) ModTime() time.Time {
+	return f.fs.modTime
+}
+
+func (f *contentFile) IsDir() bool {
+	return false
+}
+
+func (f *contentFile) Sys() interface{} {
+	return nil
+}
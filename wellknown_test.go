@@ -0,0 +1,54 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goroute/route"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRobots(t *testing.T) {
+	mw := Robots("User-agent: *\nDisallow: /admin/\n", Headers(HeaderRule{Patterns: []string{"robots.txt"}, Values: map[string]string{"X-Robots": "1"}}))
+	assert := assert.New(t)
+	mux := route.NewServeMux()
+	req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal("User-agent: *\nDisallow: /admin/\n", rec.Body.String())
+		assert.Equal("text/plain; charset=utf-8", rec.Header().Get(route.HeaderContentType))
+		assert.Equal("1", rec.Header().Get("X-Robots"))
+		assert.NotEmpty(rec.Header().Get(route.HeaderLastModified))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+	req.Header.Set(route.HeaderIfModifiedSince, rec.Header().Get(route.HeaderLastModified))
+	rec = httptest.NewRecorder()
+	if assert.NoError(mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+		assert.Equal(http.StatusNotModified, rec.Code)
+	}
+}
+
+func TestFavicon(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/favicon.ico": {Data: []byte("icon")},
+	}
+	assert := assert.New(t)
+	for _, test := range []struct {
+		mw route.MiddlewareFunc
+		cc string
+	}{
+		{Favicon("assets/favicon.ico", Filesystem(fsys)), faviconCacheControl},
+		{Favicon("assets/favicon.ico", Filesystem(fsys), CacheControl(CacheRule{Patterns: []string{"*.ico"}, Value: "no-cache"})), "no-cache"},
+	} {
+		mux := route.NewServeMux()
+		req := httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)
+		rec := httptest.NewRecorder()
+		if assert.NoError(test.mw(mux.NewContext(req, rec), route.NotFoundHandler)) {
+			assert.Equal("icon", rec.Body.String())
+			assert.Equal(test.cc, rec.Header().Get(headerCacheControl))
+		}
+	}
+}